@@ -0,0 +1,168 @@
+// Package buildinfo describes the build of the running program, logging it at startup and
+// exporting it, together with the program's uptime, as prometheus metrics.
+package buildinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iand/pontium/envconf"
+)
+
+// Version is the version of the program. It may be set at build time using
+// -ldflags "-X github.com/iand/pontium/buildinfo.Version=v1.2.3". If empty, the version of the
+// main module recorded by the go command is used.
+var Version string
+
+// startTime approximates the time the program started.
+var startTime = time.Now()
+
+// Info describes the build of the running program.
+type Info struct {
+	Path       string    // import path of the main package
+	Version    string    // version of the program
+	Commit     string    // revision of the version control system the program was built from
+	CommitTime time.Time // time of the commit
+	Modified   bool      // whether the source had uncommitted changes
+	GoVersion  string    // version of Go used to build the program
+	GOOS       string
+	GOARCH     string
+}
+
+// Read returns the build information of the running program. Fields are left empty if the program
+// was built without the corresponding information, such as when built without module support or
+// outside a repository.
+func Read() Info {
+	info := Info{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Path = bi.Path
+	if info.Version == "" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.CommitTime, _ = time.Parse(time.RFC3339, s.Value)
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// LogValue implements slog.LogValuer.
+func (i Info) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("path", i.Path),
+		slog.String("version", i.Version),
+		slog.String("commit", i.Commit),
+	}
+	if !i.CommitTime.IsZero() {
+		attrs = append(attrs, slog.Time("commit_time", i.CommitTime))
+	}
+	if i.Modified {
+		attrs = append(attrs, slog.Bool("modified", true))
+	}
+	attrs = append(attrs,
+		slog.String("go_version", i.GoVersion),
+		slog.String("os", i.GOOS),
+		slog.String("arch", i.GOARCH),
+	)
+	return slog.GroupValue(attrs...)
+}
+
+// Uptime returns the time since the program started.
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
+
+// ConfigHash returns a short hash of the configuration held by the struct pointed to by cfg, as
+// described by envconf.Attrs, so that differences in configuration between instances or restarts
+// can be spotted without logging the full configuration. Secret fields contribute only whether
+// they are set, so the hash cannot be used to recover them.
+func ConfigHash(cfg any) (string, error) {
+	attrs, err := envconf.Attrs(cfg)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, a := range attrs {
+		fmt.Fprintf(h, "%s=%s\n", a.Key, a.Value.Resolve())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// LogStartup logs a record describing the build of the program and, if cfg is not nil, the hash
+// of its configuration as returned by ConfigHash. It is intended to be called once, early in
+// main.
+func LogStartup(ctx context.Context, cfg any) {
+	args := []any{slog.Any("build", Read())}
+	if cfg != nil {
+		hash, err := ConfigHash(cfg)
+		if err != nil {
+			hash = "error: " + err.Error()
+		}
+		args = append(args, slog.String("config_hash", hash))
+	}
+	slog.InfoContext(ctx, "starting", args...)
+}
+
+// NewCollector returns a prometheus.Collector exporting app_build_info, which has the value 1 and
+// labels describing the build of the program, and app_uptime_seconds, the time since the program
+// started.
+func NewCollector() prometheus.Collector {
+	info := Read()
+	return &collector{
+		info: prometheus.MustNewConstMetric(
+			prometheus.NewDesc("app_build_info", "Build information about the program.", []string{"version", "commit", "goversion"}, nil),
+			prometheus.GaugeValue, 1,
+			info.Version, shortCommit(info.Commit), info.GoVersion,
+		),
+		uptime: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "app_uptime_seconds",
+			Help: "Time since the program started.",
+		}, func() float64 { return Uptime().Seconds() }),
+	}
+}
+
+type collector struct {
+	info   prometheus.Metric
+	uptime prometheus.GaugeFunc
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info.Desc()
+	c.uptime.Describe(ch)
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ch <- c.info
+	c.uptime.Collect(ch)
+}
+
+// shortCommit abbreviates a commit hash in the manner of git.
+func shortCommit(commit string) string {
+	if len(commit) > 12 && !strings.ContainsAny(commit, " ") {
+		return commit[:12]
+	}
+	return commit
+}
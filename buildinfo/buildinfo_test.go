@@ -0,0 +1,49 @@
+package buildinfo
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestConfigHash(t *testing.T) {
+	type config struct {
+		Addr     string `env:"ADDR"`
+		Password string `env:"PASSWORD" secret:"true"`
+	}
+
+	h1, err := ConfigHash(&config{Addr: ":80", Password: "a"})
+	if err != nil {
+		t.Fatalf("ConfigHash: %v", err)
+	}
+	h2, _ := ConfigHash(&config{Addr: ":80", Password: "b"})
+	h3, _ := ConfigHash(&config{Addr: ":81", Password: "a"})
+
+	if h1 != h2 {
+		t.Errorf("hash depends on secret value")
+	}
+	if h1 == h3 {
+		t.Errorf("hash does not depend on non-secret value")
+	}
+}
+
+func TestCollector(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector())
+
+	n, err := testutil.GatherAndCount(reg, "app_build_info", "app_uptime_seconds")
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("got %d series, wanted 2", n)
+	}
+
+	mfs, _ := reg.Gather()
+	for _, mf := range mfs {
+		if mf.GetName() == "app_uptime_seconds" && mf.GetMetric()[0].GetGauge().GetValue() <= 0 {
+			t.Errorf("got non-positive uptime")
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package cachex provides an in-memory cache of values loaded on demand, with expiry, protection
+// against concurrent loads of the same key and prometheus metrics.
+package cachex
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iand/pontium/wait"
+)
+
+// A Loader loads the value for a key when it is not in the cache.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// Options configures a Cache.
+type Options struct {
+	// Name identifies the cache in metrics using the label "cache".
+	Name string
+
+	// TTL is the time that a loaded value remains in the cache. Zero means values do not expire.
+	TTL time.Duration
+
+	// Jitter extends the TTL of each value by a random fraction of the TTL, as described for
+	// wait.JitterDuration, so that values loaded together do not all expire together and cause a
+	// burst of loads.
+	Jitter float64
+
+	// NegativeTTL is the time that an error returned by the loader remains in the cache, so that
+	// repeated requests for a missing or failing key do not each cause a load. Zero disables
+	// caching of errors.
+	NegativeTTL time.Duration
+
+	// RefreshAhead is the time before a value expires at which a request for it starts a
+	// background load to replace it, while the current value continues to be returned. This hides
+	// the latency of loading frequently used values. Zero disables refreshing.
+	RefreshAhead time.Duration
+
+	// MaxEntries limits the number of entries in the cache, evicting the least recently used
+	// entry when the limit is exceeded. Zero means no limit.
+	MaxEntries int
+}
+
+// Cache holds values loaded by a Loader. Concurrent requests for a key that is not in the cache
+// share a single load. Cache is a prometheus.Collector exporting counts of hits, misses,
+// evictions and refreshes, and the number of entries.
+//
+// A Cache must be created with New. It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	load Loader[K, V]
+	opts Options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[K]*list.Element // values are *entry[K, V]
+	lru     *list.List          // most recently used at the front
+	loads   map[K]*call[V]
+
+	requests  *prometheus.CounterVec
+	evictions *prometheus.CounterVec
+	refreshes *prometheus.CounterVec
+	size      prometheus.GaugeFunc
+}
+
+type entry[K comparable, V any] struct {
+	key        K
+	val        V
+	err        error
+	expires    time.Time // zero if the entry does not expire
+	refreshing bool
+}
+
+// call is a load in progress.
+type call[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+var _ prometheus.Collector = (*Cache[string, int])(nil)
+
+// New returns a Cache that loads values using load.
+func New[K comparable, V any](load Loader[K, V], opts Options) *Cache[K, V] {
+	labels := prometheus.Labels{"cache": opts.Name}
+	c := &Cache[K, V]{
+		load:    load,
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[K]*list.Element),
+		lru:     list.New(),
+		loads:   make(map[K]*call[V]),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "cachex_requests_total",
+			Help:        "Number of requests for values, by whether they were a hit, a hit on a cached error or a miss.",
+			ConstLabels: labels,
+		}, []string{"result"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "cachex_evictions_total",
+			Help:        "Number of entries removed from the cache, by whether they expired or were evicted to make space.",
+			ConstLabels: labels,
+		}, []string{"reason"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "cachex_refreshes_total",
+			Help:        "Number of background refreshes of values, by whether they succeeded, failed or were discarded because the value was invalidated or replaced.",
+			ConstLabels: labels,
+		}, []string{"result"}),
+	}
+	c.size = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "cachex_entries",
+		Help:        "Number of entries in the cache.",
+		ConstLabels: labels,
+	}, func() float64 { return float64(c.Len()) })
+	return c
+}
+
+// Get returns the value for key, loading it if it is not in the cache or has expired. Errors
+// returned by the loader are returned to each request sharing the load and, if NegativeTTL is
+// set, to requests made until the error expires. A request whose context is cancelled while
+// waiting for a load returns the context's error without cancelling the load.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		now := c.now()
+		if e.expires.IsZero() || now.Before(e.expires) {
+			c.lru.MoveToFront(el)
+			if e.err != nil {
+				c.requests.WithLabelValues("negative_hit").Inc()
+			} else {
+				c.requests.WithLabelValues("hit").Inc()
+				c.maybeRefresh(e, now)
+			}
+			c.mu.Unlock()
+			return e.val, e.err
+		}
+		c.remove(el, "expired")
+	}
+	c.requests.WithLabelValues("miss").Inc()
+
+	cl, ok := c.loads[key]
+	if !ok {
+		cl = &call[V]{done: make(chan struct{})}
+		c.loads[key] = cl
+		go c.run(key, cl)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.val, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// run loads the value for key, stores it and completes the call.
+func (c *Cache[K, V]) run(key K, cl *call[V]) {
+	// The load is shared so must not be cancelled by any one request
+	cl.val, cl.err = c.load(context.Background(), key)
+
+	c.mu.Lock()
+	delete(c.loads, key)
+	c.store(key, cl.val, cl.err)
+	c.mu.Unlock()
+	close(cl.done)
+}
+
+// maybeRefresh starts a background load of e if it is due to expire within the refresh period.
+// The result is discarded if e has been removed or replaced by the time the load returns. The
+// caller must hold c.mu.
+func (c *Cache[K, V]) maybeRefresh(e *entry[K, V], now time.Time) {
+	if c.opts.RefreshAhead <= 0 || e.expires.IsZero() || e.refreshing || now.Before(e.expires.Add(-c.opts.RefreshAhead)) {
+		return
+	}
+	e.refreshing = true
+	go func() {
+		val, err := c.load(context.Background(), e.key)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		e.refreshing = false
+		if el, ok := c.entries[e.key]; !ok || el.Value != e {
+			// The entry was invalidated or replaced while refreshing, so the result is stale
+			c.refreshes.WithLabelValues("discarded").Inc()
+			return
+		}
+		if err != nil {
+			// Keep serving the current value until it expires
+			c.refreshes.WithLabelValues("error").Inc()
+			return
+		}
+		c.refreshes.WithLabelValues("ok").Inc()
+		c.store(e.key, val, nil)
+	}()
+}
+
+// store adds or replaces the entry for key. The caller must hold c.mu.
+func (c *Cache[K, V]) store(key K, val V, err error) {
+	ttl := wait.JitterDuration(c.opts.TTL, c.opts.Jitter)
+	if err != nil {
+		if c.opts.NegativeTTL <= 0 {
+			return
+		}
+		ttl = c.opts.NegativeTTL
+	}
+	e := &entry[K, V]{key: key, val: val, err: err}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	if c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back(), "capacity")
+	}
+}
+
+// remove removes the entry held by el. The caller must hold c.mu.
+func (c *Cache[K, V]) remove(el *list.Element, reason string) {
+	e := c.lru.Remove(el).(*entry[K, V])
+	delete(c.entries, e.key)
+	c.evictions.WithLabelValues(reason).Inc()
+}
+
+// Invalidate removes the value for key from the cache, if present. A load of the key that is in
+// progress is not affected.
+func (c *Cache[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of entries in the cache, including expired entries that have not yet
+// been removed.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Describe implements prometheus.Collector.
+func (c *Cache[K, V]) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.evictions.Describe(ch)
+	c.refreshes.Describe(ch)
+	c.size.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Cache[K, V]) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.evictions.Collect(ch)
+	c.refreshes.Collect(ch)
+	c.size.Collect(ch)
+}
@@ -0,0 +1,121 @@
+package cachex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/iand/pontium/test"
+)
+
+func TestCacheSharedLoad(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	c := New(func(ctx context.Context, key string) (int, error) {
+		loads.Add(1)
+		<-release
+		return len(key), nil
+	}, Options{TTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.Get(context.Background(), "abc"); v != 3 || err != nil {
+				t.Errorf("got %d, %v, wanted 3, nil", v, err)
+			}
+		}()
+	}
+	test.EventuallyEqual(t, 10.0, func() any { return testutil.ToFloat64(c.requests.WithLabelValues("miss")) }, time.Second)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("got %d loads, wanted 1", n)
+	}
+	if _, err := c.Get(context.Background(), "abc"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if hits := testutil.ToFloat64(c.requests.WithLabelValues("hit")); hits != 1 {
+		t.Errorf("got %v hits, wanted 1", hits)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Now()
+	var loads atomic.Int32
+	errMissing := errors.New("missing")
+	c := New(func(ctx context.Context, key string) (int, error) {
+		n := loads.Add(1)
+		if key == "missing" {
+			return 0, errMissing
+		}
+		return int(n), nil
+	}, Options{TTL: time.Minute, NegativeTTL: time.Second, RefreshAhead: 10 * time.Second, MaxEntries: 2})
+	c.now = func() time.Time { return now }
+
+	get := func(key string) (int, error) {
+		t.Helper()
+		return c.Get(context.Background(), key)
+	}
+
+	if _, err := get("missing"); err != errMissing {
+		t.Fatalf("got error %v, wanted %v", err, errMissing)
+	}
+	if _, err := get("missing"); err != errMissing || loads.Load() != 1 {
+		t.Errorf("error was not cached")
+	}
+
+	// Refreshed in the background shortly before expiry, serving the current value meanwhile
+	v, _ := get("a")
+	now = now.Add(55 * time.Second)
+	if got, _ := get("a"); got != v {
+		t.Errorf("got %d during refresh, wanted current value %d", got, v)
+	}
+	test.EventuallyEqual(t, 1.0, func() any { return testutil.ToFloat64(c.refreshes.WithLabelValues("ok")) }, time.Second)
+	if got, _ := get("a"); got == v {
+		t.Errorf("got stale value %d after refresh", got)
+	}
+
+	// The cached error has expired, so adding another key evicts the least recently used entry
+	get("b")
+	if got := testutil.ToFloat64(c.evictions.WithLabelValues("capacity")); got != 1 {
+		t.Errorf("got %v capacity evictions, wanted 1", got)
+	}
+}
+
+func TestCacheRefreshInvalidated(t *testing.T) {
+	now := time.Now()
+	var loads atomic.Int32
+	release := make(chan struct{})
+	c := New(func(ctx context.Context, key string) (int, error) {
+		if loads.Add(1) > 1 {
+			<-release
+		}
+		return 1, nil
+	}, Options{TTL: time.Minute, RefreshAhead: 10 * time.Second})
+	c.now = func() time.Time { return now }
+
+	if _, err := c.Get(context.Background(), "a"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	now = now.Add(55 * time.Second)
+	if _, err := c.Get(context.Background(), "a"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	test.EventuallyEqual(t, int32(2), func() any { return loads.Load() }, time.Second)
+
+	// Invalidating during the refresh is not undone when the refresh returns
+	c.Invalidate("a")
+	close(release)
+	test.EventuallyEqual(t, 1.0, func() any { return testutil.ToFloat64(c.refreshes.WithLabelValues("discarded")) }, time.Second)
+	if n := c.Len(); n != 0 {
+		t.Errorf("got %d entries, wanted the invalidated entry to stay removed", n)
+	}
+}
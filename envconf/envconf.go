@@ -0,0 +1,308 @@
+// Package envconf loads configuration into structs from defaults, environment variables and
+// command line flags described by struct tags.
+//
+// Each exported field of a configuration struct may be tagged with:
+//
+//	env:"NAME"       the environment variable that sets the field
+//	flag:"name"      the command line flag that sets the field
+//	default:"value"  the value of the field when it is not otherwise set
+//	usage:"text"     a description of the field, used as the flag's usage text
+//	secret:"true"    the field's value must never be logged
+//	reload:"true"    the field may be changed while running by a Reloader
+//
+// Fields of struct type that are not themselves decodable are searched for tagged fields.
+// Supported field types are strings, booleans, integers, floats, time.Duration, slices of strings
+// (separated by commas) and any type implementing encoding.TextUnmarshaler.
+package envconf
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iand/pontium/hlog"
+)
+
+// field is a configurable field of a configuration struct.
+type field struct {
+	name   string // dotted path of the field within the struct
+	v      reflect.Value
+	tag    reflect.StructTag
+	secret bool
+	reload bool
+}
+
+// fields returns the configurable fields of the struct pointed to by cfg.
+func fields(cfg any) ([]field, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a non-nil pointer to a struct, got %T", cfg)
+	}
+	var fs []field
+	collect(v.Elem(), "", &fs)
+	return fs, nil
+}
+
+func collect(v reflect.Value, prefix string, fs *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := prefix + sf.Name
+		if fv.Kind() == reflect.Struct && !decodable(fv) {
+			collect(fv, name+".", fs)
+			continue
+		}
+		*fs = append(*fs, field{
+			name:   name,
+			v:      fv,
+			tag:    sf.Tag,
+			secret: sf.Tag.Get("secret") == "true",
+			reload: sf.Tag.Get("reload") == "true",
+		})
+	}
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decodable reports whether v is decoded as a whole rather than searched for fields.
+func decodable(v reflect.Value) bool {
+	return v.Addr().Type().Implements(textUnmarshalerType)
+}
+
+// Load sets the fields of the struct pointed to by cfg, first to their default values and then
+// to the values of their environment variables, where set. It returns an error naming each field
+// that could not be set.
+func Load(cfg any) error {
+	return load(cfg, nil)
+}
+
+// LoadFile is like Load but also reads variables from the env file at path, as described for
+// ReadEnvFile. Variables in the file take precedence over those in the environment.
+func LoadFile(cfg any, path string) error {
+	vars, err := ReadEnvFile(path)
+	if err != nil {
+		return err
+	}
+	return load(cfg, vars)
+}
+
+func load(cfg any, vars map[string]string) error {
+	fs, err := fields(cfg)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range fs {
+		if err := f.resolve(f.v, vars); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolve sets v, which has the type of the field, to the field's default value and then to the
+// value of its variable in vars or, failing that, the environment, where set.
+func (f field) resolve(v reflect.Value, vars map[string]string) error {
+	var errs []error
+	if def, ok := f.tag.Lookup("default"); ok {
+		if err := set(v, def); err != nil {
+			errs = append(errs, fmt.Errorf("%s: default: %w", f.name, err))
+		}
+	}
+	if env := f.tag.Get("env"); env != "" {
+		val, ok := vars[env]
+		if !ok {
+			val, ok = os.LookupEnv(env)
+		}
+		if ok {
+			if err := set(v, val); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", f.name, env, f.redact(err)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// redact replaces an error caused by a secret field's value, which may contain the value.
+func (f field) redact(err error) error {
+	if f.secret {
+		return errors.New("invalid value")
+	}
+	return err
+}
+
+// Flags defines a flag in fs for each field of the struct pointed to by cfg that has a flag tag.
+// The current value of each field, such as that set by Load, is used as the flag's default, and
+// parsing the flags sets the fields. The defaults of secret fields are shown as redacted in usage
+// text.
+func Flags(fs *flag.FlagSet, cfg any) error {
+	fields, err := fields(cfg)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		name := f.tag.Get("flag")
+		if name == "" {
+			continue
+		}
+		fs.Var(&flagValue{f: f}, name, f.tag.Get("usage"))
+	}
+	return nil
+}
+
+// flagValue adapts a field to flag.Value.
+type flagValue struct {
+	f field
+}
+
+func (fv *flagValue) String() string {
+	if fv.f.v == (reflect.Value{}) {
+		// Called by the flag package on a zero value to check whether the default is empty
+		return ""
+	}
+	if fv.f.secret && !fv.f.v.IsZero() {
+		return hlog.Redacted
+	}
+	return format(fv.f.v)
+}
+
+func (fv *flagValue) Set(s string) error {
+	return fv.f.redact(set(fv.f.v, s))
+}
+
+// IsBoolFlag allows boolean flags to be given without a value.
+func (fv *flagValue) IsBoolFlag() bool {
+	return fv.f.v != (reflect.Value{}) && fv.f.v.Kind() == reflect.Bool
+}
+
+// set parses s into v.
+func set(v reflect.Value, s string) error {
+	if v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		sv := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			sv.Index(i).SetString(strings.TrimSpace(p))
+		}
+		v.Set(sv)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// format returns the textual form of v, as accepted by set.
+func format(v reflect.Value) string {
+	if tm, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = v.Index(i).String()
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Attrs returns an attribute for each field of the struct pointed to by cfg, keyed by the field's
+// path within the struct, so the resolved configuration can be logged. The values of secret
+// fields are hlog.Secret values, which are redacted by any slog handler; secret fields that are
+// not set are logged as empty so it remains visible whether they were configured.
+func Attrs(cfg any) ([]slog.Attr, error) {
+	fs, err := fields(cfg)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make([]slog.Attr, 0, len(fs))
+	for _, f := range fs {
+		attrs = append(attrs, slog.Any(f.name, f.logValue(f.v)))
+	}
+	return attrs, nil
+}
+
+// logValue returns v, which has the type of the field, in a form that is safe to log.
+func (f field) logValue(v reflect.Value) any {
+	switch {
+	case f.secret && !v.IsZero():
+		return hlog.Secret(format(v))
+	case f.secret:
+		return ""
+	default:
+		return v.Interface()
+	}
+}
+
+// LogValue returns a group value holding the attributes returned by Attrs for cfg, for use with
+// slog, as in slog.Info("loaded config", "config", envconf.LogValue(&cfg)). If cfg is not a pointer
+// to a struct the value holds the error.
+func LogValue(cfg any) slog.Value {
+	attrs, err := Attrs(cfg)
+	if err != nil {
+		return slog.StringValue(err.Error())
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,184 @@
+package envconf
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/iand/pontium/hlog"
+	"github.com/iand/pontium/test"
+)
+
+type testConfig struct {
+	Addr     string        `env:"TEST_ADDR" flag:"addr" default:":8080"`
+	Timeout  time.Duration `env:"TEST_TIMEOUT" flag:"timeout" default:"5s"`
+	Tags     []string      `env:"TEST_TAGS"`
+	Password string        `env:"TEST_PASSWORD" flag:"password" secret:"true"`
+	DB       struct {
+		MaxConns int `env:"TEST_DB_MAX_CONNS" default:"10"`
+	}
+}
+
+func TestLoad(t *testing.T) {
+	test.SetEnv(t, "TEST_TIMEOUT", "1m")
+	test.SetEnv(t, "TEST_TAGS", "a, b")
+	test.SetEnv(t, "TEST_PASSWORD", "hunter2")
+
+	var cfg testConfig
+	if err := Load(&cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := Flags(fs, &cfg); err != nil {
+		t.Fatalf("Flags: %v", err)
+	}
+	if err := fs.Parse([]string{"-addr", ":9000"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if cfg.Addr != ":9000" || cfg.Timeout != time.Minute || strings.Join(cfg.Tags, "|") != "a|b" || cfg.Password != "hunter2" || cfg.DB.MaxConns != 10 {
+		t.Errorf("got config %+v", cfg)
+	}
+}
+
+func TestLoadInvalidSecret(t *testing.T) {
+	test.SetEnv(t, "TEST_TIMEOUT", "hunter2")
+	type config struct {
+		Timeout time.Duration `env:"TEST_TIMEOUT" secret:"true"`
+	}
+	var cfg config
+	err := Load(&cfg)
+	if err == nil {
+		t.Fatalf("got no error for invalid value")
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error %q reveals secret value", err)
+	}
+}
+
+func TestLogValueRedactsSecrets(t *testing.T) {
+	cfg := testConfig{Addr: ":8080", Password: "hunter2"}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info("loaded config", "config", LogValue(&cfg))
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("log output reveals secret: %s", out)
+	}
+	if !strings.Contains(out, "config.Addr=:8080") || !strings.Contains(out, "config.Password=[redacted]") {
+		t.Errorf("log output missing config: %s", out)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	_ = Flags(fs, &cfg)
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	if strings.Contains(usage.String(), "hunter2") {
+		t.Errorf("usage reveals secret: %s", usage.String())
+	}
+}
+
+func TestReloader(t *testing.T) {
+	type config struct {
+		Level    string `env:"TEST_LEVEL" default:"info" reload:"true"`
+		Addr     string `env:"TEST_ADDR" default:":8080"`
+		Password string `env:"TEST_PASSWORD" reload:"true" secret:"true"`
+	}
+
+	path := filepath.Join(t.TempDir(), "test.env")
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write env file: %v", err)
+		}
+	}
+	writeFile("# settings\nTEST_LEVEL=debug\n")
+
+	var cfg config
+	if err := LoadFile(&cfg, path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.Level != "debug" {
+		t.Fatalf("got level %q, wanted debug", cfg.Level)
+	}
+
+	r, err := NewReloader(&cfg, path)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	var changes []Change
+	r.OnChange(func(c Change) { changes = append(changes, c) })
+
+	writeFile("TEST_LEVEL=warn\nTEST_ADDR=:9000\nexport TEST_PASSWORD=\"hunter2\"\n")
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if cfg.Level != "warn" || cfg.Password != "hunter2" {
+		t.Errorf("got config %+v, wanted reloadable fields changed", cfg)
+	}
+	if cfg.Addr != ":8080" {
+		t.Errorf("got addr %q, wanted field without reload tag unchanged", cfg.Addr)
+	}
+	want := []Change{
+		{Field: "Level", Old: "debug", New: "warn"},
+		{Field: "Password", Old: "", New: hlog.Secret("hunter2")},
+	}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReloaderKeepsFlags(t *testing.T) {
+	type config struct {
+		Level string `env:"TEST_LEVEL" flag:"level" default:"info" reload:"true"`
+		Addr  string `env:"TEST_ADDR" flag:"addr" default:":8080" reload:"true"`
+	}
+
+	path := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(path, []byte("TEST_LEVEL=debug\n"), 0o600); err != nil {
+		t.Fatalf("write env file: %v", err)
+	}
+
+	var cfg config
+	if err := LoadFile(&cfg, path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := Flags(fs, &cfg); err != nil {
+		t.Fatalf("Flags: %v", err)
+	}
+	if err := fs.Parse([]string{"-level", "error"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	r, err := NewReloader(&cfg, path)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	r.KeepFlags(fs)
+
+	if err := os.WriteFile(path, []byte("TEST_LEVEL=warn\nTEST_ADDR=:9000\n"), 0o600); err != nil {
+		t.Fatalf("write env file: %v", err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if cfg.Level != "error" {
+		t.Errorf("got level %q, wanted value set on the command line kept", cfg.Level)
+	}
+	if cfg.Addr != ":9000" {
+		t.Errorf("got addr %q, wanted field not set on the command line reloaded", cfg.Addr)
+	}
+}
@@ -0,0 +1,52 @@
+package envconf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadEnvFile reads variables from the env file at path. Each line of the file holds a NAME=value
+// pair, optionally preceded by "export". Blank lines and lines starting with # are ignored. Values
+// may be enclosed in double quotes, in which case they are unquoted as Go strings, or in single
+// quotes, which are removed.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open env file: %w", err)
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	s := bufio.NewScanner(f)
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: missing =", path, lineno)
+		}
+		name, val = strings.TrimSpace(name), strings.TrimSpace(val)
+		switch {
+		case len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"':
+			uq, err := strconv.Unquote(val)
+			if err != nil {
+				// Do not include the value, which may be secret
+				return nil, fmt.Errorf("%s:%d: invalid quoted value for %s", path, lineno, name)
+			}
+			val = uq
+		case len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'':
+			val = val[1 : len(val)-1]
+		}
+		vars[name] = val
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read env file: %w", err)
+	}
+	return vars, nil
+}
@@ -0,0 +1,202 @@
+package envconf
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// pollInterval is how often a Reloader checks its env file for changes.
+const pollInterval = 2 * time.Second
+
+// Change describes a change to the value of a reloadable field.
+type Change struct {
+	Field string // path of the field within the config struct, such as "DB.MaxConns"
+	Old   any    // previous value of the field, an hlog.Secret if the field is secret
+	New   any    // new value of the field, an hlog.Secret if the field is secret
+}
+
+// Reloader changes the reloadable fields of a configuration while a program is running. Fields are
+// reloadable when tagged with reload:"true". On each reload the value of each reloadable field is
+// resolved again from its default, the environment and the Reloader's env file, in the same manner
+// as LoadFile, except that fields set on the command line keep their values when the Reloader is
+// told about the flag set using KeepFlags. Changed fields are updated together, each change is
+// logged using slog and the functions registered with OnChange are called.
+//
+// Reloaded fields are written while holding the Reloader's lock. Code that reads reloadable
+// fields concurrently with a reload must do so within View, or instead react to changes using
+// OnChange.
+type Reloader struct {
+	mu        sync.RWMutex
+	fields    []field // reloadable fields
+	path      string
+	flags     *flag.FlagSet // flags set on the command line take precedence, if not nil
+	callbacks []func(Change)
+}
+
+// NewReloader returns a Reloader for the reloadable fields of the struct pointed to by cfg, which
+// should already have been loaded. If path is not empty, variables are read from the env file at
+// path on each reload.
+func NewReloader(cfg any, path string) (*Reloader, error) {
+	fs, err := fields(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reloader{path: path}
+	for _, f := range fs {
+		if f.reload {
+			r.fields = append(r.fields, f)
+		}
+	}
+	return r, nil
+}
+
+// OnChange registers fn to be called for each change to a reloadable field, after all the fields
+// changed by a reload have been updated. An example is passing a new log level to
+// hlog.Control.SetLevel.
+func (r *Reloader) OnChange(fn func(Change)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callbacks = append(r.callbacks, fn)
+}
+
+// KeepFlags causes reloads to leave unchanged the reloadable fields whose flags, registered using
+// Flags, were set when fs was parsed. The command line takes precedence over the environment and
+// env file, so a value given on the command line is not replaced by a reload.
+func (r *Reloader) KeepFlags(fs *flag.FlagSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flags = fs
+}
+
+// View calls fn while preventing reloads, so that fn may read reloadable fields safely.
+func (r *Reloader) View(fn func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn()
+}
+
+// Reload resolves the values of the reloadable fields and applies any changes. If any field
+// cannot be resolved no fields are changed and an error is returned.
+func (r *Reloader) Reload() error {
+	var vars map[string]string
+	if r.path != "" {
+		var err error
+		vars, err = ReadEnvFile(r.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.mu.RLock()
+	set := map[string]bool{}
+	if r.flags != nil {
+		r.flags.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	}
+	r.mu.RUnlock()
+
+	values := make([]reflect.Value, len(r.fields))
+	var errs []error
+	for i, f := range r.fields {
+		if name := f.tag.Get("flag"); name != "" && set[name] {
+			// Leave the value given on the command line in place
+			continue
+		}
+		values[i] = reflect.New(f.v.Type()).Elem()
+		if err := f.resolve(values[i], vars); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("reload config: %w", err)
+	}
+
+	r.mu.Lock()
+	var changes []Change
+	for i, f := range r.fields {
+		if !values[i].IsValid() || reflect.DeepEqual(f.v.Interface(), values[i].Interface()) {
+			continue
+		}
+		changes = append(changes, Change{Field: f.name, Old: f.logValue(f.v), New: f.logValue(values[i])})
+		f.v.Set(values[i])
+	}
+	callbacks := r.callbacks
+	r.mu.Unlock()
+
+	for _, c := range changes {
+		slog.Info("config field changed", "field", c.Field, "old", c.Old, "new", c.New)
+		for _, fn := range callbacks {
+			fn(c)
+		}
+	}
+	return nil
+}
+
+// Run reloads the configuration whenever the process receives SIGHUP or the env file is modified,
+// until the context is cancelled, when it returns the context's error. The env file is checked
+// for changes periodically and is only reloaded once it has stopped changing, so that a file
+// written in several steps is not read part way through. Failed reloads are logged and leave the
+// configuration unchanged.
+func (r *Reloader) Run(ctx context.Context) error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	loaded := r.stat()
+	pending := loaded
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+			r.reload(ctx, "signal")
+		case <-ticker.C:
+			st := r.stat()
+			if st == loaded {
+				pending = st
+				continue
+			}
+			if st != pending {
+				// Wait for the file to stop changing
+				pending = st
+				continue
+			}
+			loaded = st
+			r.reload(ctx, "file")
+		}
+	}
+}
+
+// fileState identifies a version of the env file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (r *Reloader) stat() fileState {
+	if r.path == "" {
+		return fileState{}
+	}
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: fi.ModTime(), size: fi.Size()}
+}
+
+func (r *Reloader) reload(ctx context.Context, trigger string) {
+	if err := r.Reload(); err != nil {
+		slog.ErrorContext(ctx, "failed to reload config", "trigger", trigger, "error", err)
+	}
+}
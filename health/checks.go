@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxCheckBody limits how much of a response body an HTTP check reads.
+const maxCheckBody = 64 << 10
+
+// WithTimeout returns a check that runs check with a context that times out after d.
+func WithTimeout(check Check, d time.Duration) Check {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return check(ctx)
+	}
+}
+
+// TCP returns a check that passes when a TCP connection can be established with addr.
+func TCP(addr string) Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPOptions configures a check created by HTTP.
+type HTTPOptions struct {
+	// Client is the client used to make the request. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Status is the expected status code of the response. If zero, any 2xx status is accepted.
+	Status int
+
+	// Body, if not empty, must be contained in the response body.
+	Body string
+}
+
+// HTTP returns a check that passes when a GET request for url receives the expected response.
+func HTTP(url string, opts HTTPOptions) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("new request: %w", err)
+		}
+		client := opts.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if opts.Status != 0 {
+			if resp.StatusCode != opts.Status {
+				return fmt.Errorf("got status %d, wanted %d", resp.StatusCode, opts.Status)
+			}
+		} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("got status %d", resp.StatusCode)
+		}
+
+		if opts.Body != "" {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBody))
+			if err != nil {
+				return fmt.Errorf("read body: %w", err)
+			}
+			if !strings.Contains(string(body), opts.Body) {
+				return fmt.Errorf("body does not contain %q", opts.Body)
+			}
+		}
+		return nil
+	}
+}
+
+// A Pinger checks a connection to a database. It is implemented by *sql.DB and *sql.Conn.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// SQL returns a check that passes when db can be pinged.
+func SQL(db Pinger) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// FileFresh returns a check that passes when the file at path exists and was modified no more than
+// maxAge ago. It is useful for checking that a periodic job, such as a backup, is still running.
+func FileFresh(path string, maxAge time.Duration) Check {
+	return func(ctx context.Context) error {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if age := time.Since(fi.ModTime()); age > maxAge {
+			return fmt.Errorf("%s was last modified %v ago, more than %v", path, age.Round(time.Second), maxAge)
+		}
+		return nil
+	}
+}
+
+// DiskSpace returns a check that passes when the filesystem containing path has at least minFree
+// bytes available to unprivileged users. It is not supported on all platforms, in which case the
+// check always fails.
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		free, err := diskFree(path)
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d bytes free on filesystem containing %s, less than %d", free, path, minFree)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHTTPCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("status: ok"))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	if err := HTTP(srv.URL, HTTPOptions{Body: "ok"})(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := HTTP(srv.URL, HTTPOptions{Status: http.StatusNoContent})(ctx); err == nil {
+		t.Errorf("expected an error for unexpected status")
+	}
+	if err := HTTP(srv.URL, HTTPOptions{Body: "healthy"})(ctx); err == nil {
+		t.Errorf("expected an error for unexpected body")
+	}
+}
+
+func TestFileFreshCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "marker")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := FileFresh(path, time.Hour)(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if err := FileFresh(path, time.Hour)(ctx); err == nil {
+		t.Errorf("expected an error for stale file")
+	}
+}
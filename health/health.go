@@ -0,0 +1,358 @@
+// Package health tracks the readiness of the components of a service and the results of checks
+// on its dependencies, reporting them over HTTP and as prometheus metrics.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// checkTimeout limits the time taken by each check when reporting readiness over HTTP.
+const checkTimeout = 5 * time.Second
+
+// A Check reports whether a dependency of the service is healthy, returning nil if it is. A check
+// that finds the dependency usable but impaired, for example responding slowly, may return an error
+// wrapped with Degraded.
+type Check func(context.Context) error
+
+// Status is the result of a check.
+type Status int
+
+const (
+	StatusOK       Status = iota // the check passed
+	StatusDegraded               // the check returned an error wrapped with Degraded
+	StatusFailing                // the check returned any other error
+)
+
+// String returns the name of the status as used in reports and metrics.
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusDegraded:
+		return "degraded"
+	default:
+		return "failing"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Severity determines whether a check affects the readiness of the service.
+type Severity int
+
+const (
+	// SeverityCritical checks make the service not ready while they are failing. This is the
+	// severity of checks added with AddCheck.
+	SeverityCritical Severity = iota
+
+	// SeverityInformational checks are reported but never affect readiness.
+	SeverityInformational
+)
+
+// String returns the name of the severity as used in reports and metrics.
+func (s Severity) String() string {
+	if s == SeverityInformational {
+		return "informational"
+	}
+	return "critical"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+type degradedError struct {
+	err error
+}
+
+func (e *degradedError) Error() string { return e.err.Error() }
+func (e *degradedError) Unwrap() error { return e.err }
+
+// Degraded wraps err to indicate that a check found its dependency impaired but still usable. A
+// degraded check is reported but does not make the service not ready, whatever its severity.
+// Degraded returns nil if err is nil.
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &degradedError{err: err}
+}
+
+// StatusOf returns the status of a check that returned err.
+func StatusOf(err error) Status {
+	if err == nil {
+		return StatusOK
+	}
+	var de *degradedError
+	if errors.As(err, &de) {
+		return StatusDegraded
+	}
+	return StatusFailing
+}
+
+// Health tracks the readiness of the components of a service, such as the services run by a
+// supervisor, and a set of checks on its dependencies. The service is ready when all critical
+// components are ready and no critical checks are failing. Changes in readiness and in the status
+// of checks are logged using slog. Health is a prometheus.Collector exporting the readiness of the
+// service and of each component, and the most recent status of each check.
+//
+// A Health must be created with New. It is safe for concurrent use.
+type Health struct {
+	mu         sync.Mutex
+	components map[string]*component
+	checks     map[string]*check
+	ready      bool // aggregate readiness of components
+}
+
+type component struct {
+	critical bool
+	ready    bool
+}
+
+type check struct {
+	fn       Check
+	severity Severity
+	status   Status
+	run      bool // whether status holds the result of a run
+}
+
+var _ prometheus.Collector = (*Health)(nil)
+
+// New returns a Health with no components or checks, which is ready.
+func New() *Health {
+	return &Health{
+		components: make(map[string]*component),
+		checks:     make(map[string]*check),
+		ready:      true,
+	}
+}
+
+// Register adds a component that is initially not ready. Only critical components affect the
+// readiness of the service. Registering a component again changes whether it is critical and
+// leaves its readiness unchanged.
+func (h *Health) Register(name string, critical bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c, ok := h.components[name]; ok {
+		c.critical = critical
+	} else {
+		h.components[name] = &component{critical: critical}
+	}
+	h.update()
+}
+
+// SetReady records whether the named component is ready, registering it as a critical component
+// if it has not been registered.
+func (h *Health) SetReady(name string, ready bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c, ok := h.components[name]
+	if !ok {
+		c = &component{critical: true}
+		h.components[name] = c
+	}
+	if c.ready != ready {
+		c.ready = ready
+		slog.Info("health component readiness changed", "component", name, "ready", ready, "critical", c.critical)
+	}
+	h.update()
+}
+
+// AddCheck adds a critical check that must not be failing for the service to be ready. Checks are
+// run each time readiness is reported over HTTP.
+func (h *Health) AddCheck(name string, fn Check) {
+	h.AddCheckSeverity(name, fn, SeverityCritical)
+}
+
+// AddCheckSeverity adds a check with the given severity, replacing any existing check with the
+// same name.
+func (h *Health) AddCheckSeverity(name string, fn Check, severity Severity) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = &check{fn: fn, severity: severity}
+}
+
+// Ready reports whether all critical components are ready. It does not run checks.
+func (h *Health) Ready() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ready
+}
+
+// update recomputes the aggregate readiness, logging any change. The caller must hold h.mu.
+func (h *Health) update() {
+	ready := true
+	for _, c := range h.components {
+		if c.critical && !c.ready {
+			ready = false
+			break
+		}
+	}
+	if ready != h.ready {
+		h.ready = ready
+		slog.Info("health readiness changed", "ready", ready)
+	}
+}
+
+// Report describes the health of a service. Status is failing when the service is not ready and
+// degraded when any check is not ok but the service is still ready.
+type Report struct {
+	Ready      bool                       `json:"ready"`
+	Status     Status                     `json:"status"`
+	Components map[string]ComponentReport `json:"components,omitempty"`
+	Checks     map[string]CheckReport     `json:"checks,omitempty"`
+}
+
+// ComponentReport describes the readiness of a component.
+type ComponentReport struct {
+	Ready    bool `json:"ready"`
+	Critical bool `json:"critical"`
+}
+
+// CheckReport describes the result of a check.
+type CheckReport struct {
+	Status   Status   `json:"status"`
+	Severity Severity `json:"severity"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Report runs the checks concurrently and returns a report of the health of the service.
+func (h *Health) Report(ctx context.Context) Report {
+	h.mu.Lock()
+	r := Report{
+		Ready:      h.ready,
+		Components: make(map[string]ComponentReport, len(h.components)),
+		Checks:     make(map[string]CheckReport, len(h.checks)),
+	}
+	for name, c := range h.components {
+		r.Components[name] = ComponentReport{Ready: c.ready, Critical: c.critical}
+	}
+	checks := make(map[string]*check, len(h.checks))
+	for name, c := range h.checks {
+		checks[name] = c
+	}
+	h.mu.Unlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, c := range checks {
+		wg.Add(1)
+		go func(name string, c *check) {
+			defer wg.Done()
+			cr := CheckReport{Severity: c.severity}
+			if err := c.fn(ctx); err != nil {
+				cr.Status = StatusOf(err)
+				cr.Error = err.Error()
+			}
+			mu.Lock()
+			r.Checks[name] = cr
+			mu.Unlock()
+		}(name, c)
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	for name, cr := range r.Checks {
+		// The check may have been replaced while running
+		if c := h.checks[name]; c == checks[name] {
+			if !c.run || c.status != cr.Status {
+				slog.Info("health check status changed", "check", name, "status", cr.Status, "severity", cr.Severity, "error", cr.Error)
+			}
+			c.status = cr.Status
+			c.run = true
+		}
+	}
+	h.mu.Unlock()
+
+	for _, cr := range r.Checks {
+		if cr.Severity == SeverityCritical && cr.Status == StatusFailing {
+			r.Ready = false
+		}
+		if cr.Status != StatusOK {
+			r.Status = StatusDegraded
+		}
+	}
+	if !r.Ready {
+		r.Status = StatusFailing
+	}
+	return r
+}
+
+// ServeHTTP reports the health of the service as JSON, with the status 200 OK when the service is
+// ready and 503 Service Unavailable otherwise.
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	rep := h.Report(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	if !rep.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(rep)
+}
+
+var (
+	readyDesc          = prometheus.NewDesc("health_ready", "Whether all critical components of the service are ready.", nil, nil)
+	componentReadyDesc = prometheus.NewDesc("health_component_ready", "Whether a component of the service is ready.", []string{"component", "critical"}, nil)
+	checkStatusDesc    = prometheus.NewDesc("health_check_status", "Whether a check had the given status when it was last run.", []string{"check", "severity", "status"}, nil)
+)
+
+var statuses = []Status{StatusOK, StatusDegraded, StatusFailing}
+
+// Describe implements prometheus.Collector.
+func (h *Health) Describe(ch chan<- *prometheus.Desc) {
+	ch <- readyDesc
+	ch <- componentReadyDesc
+	ch <- checkStatusDesc
+}
+
+// Collect implements prometheus.Collector.
+func (h *Health) Collect(ch chan<- prometheus.Metric) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(readyDesc, prometheus.GaugeValue, boolValue(h.ready))
+	names := make([]string, 0, len(h.components))
+	for name := range h.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := h.components[name]
+		critical := "false"
+		if c.critical {
+			critical = "true"
+		}
+		ch <- prometheus.MustNewConstMetric(componentReadyDesc, prometheus.GaugeValue, boolValue(c.ready), name, critical)
+	}
+
+	for name, c := range h.checks {
+		if !c.run {
+			continue
+		}
+		for _, st := range statuses {
+			ch <- prometheus.MustNewConstMetric(checkStatusDesc, prometheus.GaugeValue, boolValue(c.status == st), name, c.severity.String(), st.String())
+		}
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
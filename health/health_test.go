@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthReadiness(t *testing.T) {
+	h := New()
+	h.Register("db", true)
+	h.Register("cache", false)
+	if h.Ready() {
+		t.Errorf("ready before critical component was ready")
+	}
+
+	h.SetReady("db", true)
+	if !h.Ready() {
+		t.Errorf("not ready after all critical components were ready")
+	}
+
+	h.AddCheck("upstream", func(context.Context) error { return errors.New("unreachable") })
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d with failing check, wanted %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHealthCheckSeverity(t *testing.T) {
+	h := New()
+	h.AddCheck("db", func(context.Context) error { return Degraded(errors.New("slow")) })
+	h.AddCheckSeverity("cdn", func(context.Context) error { return errors.New("unreachable") }, SeverityInformational)
+
+	rep := h.Report(context.Background())
+	if !rep.Ready {
+		t.Errorf("not ready with only degraded and informational checks failing")
+	}
+	if rep.Status != StatusDegraded {
+		t.Errorf("got status %v, wanted %v", rep.Status, StatusDegraded)
+	}
+	if got := rep.Checks["db"].Status; got != StatusDegraded {
+		t.Errorf("got db status %v, wanted %v", got, StatusDegraded)
+	}
+	if got := rep.Checks["cdn"]; got.Status != StatusFailing || got.Severity != SeverityInformational {
+		t.Errorf("got cdn report %+v, wanted failing informational", got)
+	}
+
+	h.AddCheck("db", func(context.Context) error { return errors.New("down") })
+	rep = h.Report(context.Background())
+	if rep.Ready || rep.Status != StatusFailing {
+		t.Errorf("got ready=%v status=%v with failing critical check, wanted not ready and failing", rep.Ready, rep.Status)
+	}
+}
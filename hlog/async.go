@@ -0,0 +1,158 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// DropReasonQueueFull is the reason recorded when an asynchronous Handler drops a record because its
+// queue is full.
+const DropReasonQueueFull = "queue_full"
+
+// AsyncPolicy determines what an asynchronous Handler does with a record when its queue is full.
+type AsyncPolicy int
+
+const (
+	// AsyncBlock waits for space in the queue, slowing the caller to the speed of the writer. This
+	// is the default.
+	AsyncBlock AsyncPolicy = iota
+
+	// AsyncDrop discards the record, counting it as dropped with the reason DropReasonQueueFull
+	// when drop statistics are enabled using WithDropStats.
+	AsyncDrop
+)
+
+// pendingWrite is output waiting to be written to a writer.
+type pendingWrite struct {
+	w  io.Writer
+	mu *sync.Mutex // serializes writes to w
+	p  []byte
+}
+
+// asyncEntry is an element of an asyncQueue: either the output of a record or a request to be
+// notified once everything before it has been written.
+type asyncEntry struct {
+	writes  []pendingWrite
+	level   slog.Level     // level of the record
+	drops   *recordCounter // optional counts of emitted and dropped records
+	onError func(error)    // optional function called when writing the record fails
+	flushed chan struct{}
+}
+
+// asyncQueue writes the output of records in a background goroutine. It is shared by all handlers
+// derived from the handler that created it.
+type asyncQueue struct {
+	policy AsyncPolicy
+	ch     chan asyncEntry
+	done   chan struct{} // closed when the background goroutine exits
+
+	mu     sync.RWMutex // held for writing to close ch, and for reading to send on it
+	closed bool
+}
+
+func newAsyncQueue(size int, policy AsyncPolicy) *asyncQueue {
+	q := &asyncQueue{
+		policy: policy,
+		ch:     make(chan asyncEntry, size),
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// run writes queued output until the queue is closed and drained.
+func (q *asyncQueue) run() {
+	defer close(q.done)
+	for e := range q.ch {
+		if e.flushed != nil {
+			close(e.flushed)
+			continue
+		}
+		var errs []error
+		for _, pw := range e.writes {
+			if err := writeLocked(pw.w, pw.mu, pw.p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		err := errors.Join(errs...)
+		if err != nil && e.onError != nil {
+			e.onError(err)
+		}
+		e.drops.written(e.level, err)
+	}
+}
+
+// enqueue adds the output of a record to the queue, reporting whether it was accepted. Output is
+// not accepted once the queue is closed, or while it is full if the policy is AsyncDrop.
+func (q *asyncQueue) enqueue(e asyncEntry) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	if q.policy == AsyncDrop {
+		select {
+		case q.ch <- e:
+			return true
+		default:
+			return false
+		}
+	}
+	q.ch <- e
+	return true
+}
+
+// Flush waits until all output queued before it was called has been written.
+func (q *asyncQueue) Flush() error {
+	flushed := make(chan struct{})
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		return nil
+	}
+	q.ch <- asyncEntry{flushed: flushed}
+	q.mu.RUnlock()
+	<-flushed
+	return nil
+}
+
+// Close stops accepting output and waits until all queued output has been written.
+func (q *asyncQueue) Close() error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.mu.Unlock()
+	<-q.done
+	return nil
+}
+
+// WithAsync returns a new Handler that formats records in the calling goroutine but writes them in
+// a background goroutine, so that bursts of logging do not wait for a slow writer. Up to size
+// records are queued, after which policy determines whether callers wait or records are dropped.
+// The queue is shared by all Handlers derived from the new Handler. Flush waits for queued records
+// to be written, and Close must be called to write any remaining records and stop the background
+// goroutine. Since Close closes resources in the reverse of the order they were added, WithCloser
+// should be called before WithAsync so that queued records are written before the writer is
+// closed. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithAsync(size int, policy AsyncPolicy) *Handler {
+	q := newAsyncQueue(size, policy)
+	h2 := h.WithCloser(q)
+	h2.async = q
+	return h2
+}
+
+// Flush waits until all records queued by an asynchronous Handler have been written. It returns
+// immediately if the Handler is not asynchronous.
+func (h *Handler) Flush() error {
+	if h.async == nil {
+		return nil
+	}
+	return h.async.Flush()
+}
@@ -0,0 +1,102 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gatedWriter blocks writes until its gate is opened.
+type gatedWriter struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestWithAsync(t *testing.T) {
+	w := &gatedWriter{gate: make(chan struct{})}
+	h := new(Handler).WithoutColor().WithWriter(w).WithDropStats().WithAsync(1, AsyncDrop)
+	logger := slog.New(h)
+
+	// The first record is taken by the background goroutine, which blocks writing it, the second
+	// fills the queue and the third is dropped
+	logger.Info("one")
+	for len(h.async.ch) != 0 {
+		runtime.Gosched()
+	}
+	logger.Info("two")
+	logger.Info("three")
+	close(w.gate)
+
+	if err := h.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	got := w.String()
+	if !strings.Contains(got, "one") || !strings.Contains(got, "two") || strings.Contains(got, "three") {
+		t.Errorf("got output %q, wanted the first two records", got)
+	}
+	if got := h.Stats()[DropReasonQueueFull]; got != 1 {
+		t.Errorf("got %d records dropped, wanted 1", got)
+	}
+
+	logger.Info("four")
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !strings.Contains(w.String(), "four") {
+		t.Errorf("got output %q, wanted queued record written on close", w.String())
+	}
+}
+
+func TestWithAsyncOnError(t *testing.T) {
+	errClosed := errors.New("pipe closed")
+	var mu sync.Mutex
+	var reported []error
+	h := new(Handler).WithoutColor().WithWriters(errWriter{err: errClosed}, shortWriter{}).WithDropStats().WithOnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	}).WithAsync(10, AsyncBlock)
+	defer h.Close()
+
+	logger := slog.New(h)
+	logger.Info("one")
+	logger.Info("two")
+	if err := h.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// As for a synchronous handler, errors are reported once per record and joined across writers
+	if len(reported) != 2 || !errors.Is(reported[0], errClosed) || !errors.Is(reported[0], io.ErrShortWrite) {
+		t.Errorf("got reported errors %v, wanted two wrapping %v and %v", reported, errClosed, io.ErrShortWrite)
+	}
+	if got := h.Stats()[DropReasonWriteFailed]; got != 2 {
+		t.Errorf("got %d records dropped, wanted 2", got)
+	}
+	if got := len(h.Emitted()); got != 0 {
+		t.Errorf("got emitted records %v, wanted none", h.Emitted())
+	}
+}
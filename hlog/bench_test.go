@@ -0,0 +1,127 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// newBenchHandler returns a handler that has inherited attrs attributes through repeated calls
+// to WithAttrs, as happens when loggers are derived in layers of an application.
+func newBenchHandler(attrs int) slog.Handler {
+	var h slog.Handler = new(Handler).WithoutColor().WithWriter(io.Discard).WithAttrLevel(slog.String("pkg", "bench"), slog.LevelDebug)
+	for i := 0; i < attrs; i++ {
+		h = h.WithAttrs([]slog.Attr{slog.Int(fmt.Sprintf("k%d", i), i)})
+	}
+	return h
+}
+
+func BenchmarkWithAttrs(b *testing.B) {
+	for _, n := range []int{0, 10, 100} {
+		b.Run(fmt.Sprintf("inherited=%d", n), func(b *testing.B) {
+			h := newBenchHandler(n)
+			attrs := []slog.Attr{slog.String("request", "abc"), slog.Int("attempt", 1)}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = h.WithAttrs(attrs)
+			}
+		})
+	}
+}
+
+func BenchmarkHandle(b *testing.B) {
+	for _, n := range []int{0, 10} {
+		b.Run(fmt.Sprintf("inherited=%d", n), func(b *testing.B) {
+			h := newBenchHandler(n)
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, "benchmark message", 0)
+			r.AddAttrs(slog.String("path", "/a/b/c"), slog.Int("status", 200), slog.Duration("elapsed", 1234*time.Microsecond))
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := h.Handle(ctx, r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHandleColor(b *testing.B) {
+	h := new(Handler).WithColor(ColorAlways).WithWriter(io.Discard).WithAttrLevel(slog.String("pkg", "bench"), slog.LevelDebug)
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "benchmark message", 0)
+	r.AddAttrs(slog.String("path", "/a/b/c"), slog.Int("status", 200), slog.Bool("cached", true))
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := h.Handle(ctx, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkHandleColorAuto measures the default ColorAuto mode writing to a writer that is not a
+// terminal, which is the common case for services.
+func BenchmarkHandleColorAuto(b *testing.B) {
+	h := new(Handler).WithWriter(io.Discard).WithAttrLevel(slog.String("pkg", "bench"), slog.LevelDebug)
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "benchmark message", 0)
+	r.AddAttrs(slog.String("path", "/a/b/c"), slog.Int("status", 200), slog.Bool("cached", true))
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := h.Handle(ctx, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHandleGroups(b *testing.B) {
+	h := new(Handler).WithoutColor().WithWriter(io.Discard).WithAttrLevel(slog.String("pkg", "bench"), slog.LevelDebug).WithGroup("req")
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "benchmark message", 0)
+	r.AddAttrs(slog.Group("user", slog.String("name", "some user"), slog.Int("id", 42)), slog.Float64("ratio", 0.25))
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := h.Handle(ctx, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestHandleAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not reliable under the race detector")
+	}
+	testCases := []struct {
+		name string
+		h    slog.Handler
+	}{
+		{name: "no color", h: newBenchHandler(10)},
+		{name: "color auto", h: new(Handler).WithWriter(io.Discard).WithAttrs([]slog.Attr{slog.Int("k", 1)})},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, "benchmark message", 0)
+			r.AddAttrs(slog.String("path", "/a/b/c"), slog.Int("status", 200), slog.Duration("elapsed", 1234*time.Microsecond))
+			ctx := context.Background()
+
+			allocs := testing.AllocsPerRun(100, func() {
+				_ = tc.h.Handle(ctx, r)
+			})
+			if allocs > 0 {
+				t.Errorf("got %v allocations per record, wanted none", allocs)
+			}
+		})
+	}
+}
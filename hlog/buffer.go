@@ -0,0 +1,77 @@
+package hlog
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// maxPooledBuffer is the capacity above which a buffer is not returned to the pool, so that an
+// occasional very long record does not keep a large allocation alive.
+const maxPooledBuffer = 64 << 10
+
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make(buffer, 0, 1024)
+		return &b
+	},
+}
+
+// buffer is a byte slice used to format records. Buffers are reused through a pool so that
+// formatting a record does not usually allocate.
+type buffer []byte
+
+// newBuffer returns an empty buffer from the pool.
+func newBuffer() *buffer {
+	return bufPool.Get().(*buffer)
+}
+
+// free returns the buffer to the pool. The buffer must not be used afterwards.
+func (b *buffer) free() {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+func (b *buffer) WriteString(s string) (int, error) {
+	*b = append(*b, s...)
+	return len(s), nil
+}
+
+func (b *buffer) WriteByte(c byte) error {
+	*b = append(*b, c)
+	return nil
+}
+
+func (b *buffer) Len() int {
+	return len(*b)
+}
+
+func (b *buffer) String() string {
+	return string(*b)
+}
+
+// padRight appends s followed by enough spaces to make up width characters.
+func (b *buffer) padRight(s string, width int) {
+	*b = append(*b, s...)
+	b.spaces(width - utf8.RuneCountInString(s))
+}
+
+// padLeft appends enough spaces to make up width characters followed by s.
+func (b *buffer) padLeft(s []byte, width int) {
+	b.spaces(width - utf8.RuneCount(s))
+	*b = append(*b, s...)
+}
+
+// spaces appends n spaces.
+func (b *buffer) spaces(n int) {
+	for ; n > 0; n-- {
+		*b = append(*b, ' ')
+	}
+}
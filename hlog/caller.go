@@ -0,0 +1,69 @@
+package hlog
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// maxCallerDepth limits how far up the stack the caller of a record is searched for.
+const maxCallerDepth = 64
+
+var (
+	helpers    sync.Map // names of functions marked as logging helpers
+	hasHelpers atomic.Bool
+)
+
+// Helper marks the calling function as a logging helper, in the manner of testing.T.Helper.
+// When a Handler attributes a record to its source location, frames belonging to logging helpers
+// are skipped so that the record is attributed to the code that called the helper rather than to
+// the helper itself. Helper is typically called at the start of thin wrapper functions that
+// forward to a slog.Logger.
+func Helper() {
+	var pcs [1]uintptr
+	if runtime.Callers(2, pcs[:]) == 0 {
+		return
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	if _, loaded := helpers.LoadOrStore(frame.Function, struct{}{}); !loaded {
+		hasHelpers.Store(true)
+	}
+}
+
+// callerPC returns the program counter of the call site that a record with program counter pc
+// should be attributed to, after skipping a further skip frames and any frames belonging to
+// functions marked with Helper. It returns pc unchanged if no adjustment is needed or pc cannot be
+// found on the current stack, which is the case when the record is handled on a different
+// goroutine from the one that logged it.
+func callerPC(pc uintptr, skip int) uintptr {
+	if pc == 0 || (skip <= 0 && !hasHelpers.Load()) {
+		return pc
+	}
+
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+
+	// Find the frame the record was logged from
+	i := 0
+	for i < n && pcs[i] != pc {
+		i++
+	}
+	if i == n {
+		return pc
+	}
+
+	for ; skip > 0 && i+1 < n; skip-- {
+		i++
+	}
+	for i+1 < n && isHelper(pcs[i]) {
+		i++
+	}
+	return pcs[i]
+}
+
+// isHelper reports whether the program counter pc belongs to a function marked with Helper.
+func isHelper(pc uintptr) bool {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	_, ok := helpers.Load(frame.Function)
+	return ok
+}
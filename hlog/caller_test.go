@@ -0,0 +1,53 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"testing"
+)
+
+// recordingHandler captures the program counter of the last record after adjustment by Handler.
+type recordingHandler struct {
+	*Handler
+	pc uintptr
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.pc = callerPC(r.PC, h.callerSkip)
+	return nil
+}
+
+func logViaHelper(l *slog.Logger) {
+	Helper()
+	l.Info("via helper")
+}
+
+func logViaWrapper(l *slog.Logger) {
+	l.Info("via wrapper")
+}
+
+func callerFunction(pc uintptr) string {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame.Function
+}
+
+func TestCallerAttribution(t *testing.T) {
+	const self = "github.com/iand/pontium/hlog.TestCallerAttribution"
+
+	h := &recordingHandler{Handler: new(Handler).WithWriter(io.Discard)}
+	logViaHelper(slog.New(h))
+	if got := callerFunction(h.pc); got != self {
+		t.Errorf("helper: got caller %q, wanted %q", got, self)
+	}
+
+	h = &recordingHandler{Handler: new(Handler).WithWriter(io.Discard).WithCallerSkip(1)}
+	logViaWrapper(slog.New(h))
+	if got := callerFunction(h.pc); got != self {
+		t.Errorf("skip: got caller %q, wanted %q", got, self)
+	}
+}
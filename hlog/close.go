@@ -0,0 +1,100 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned by Handle once the Handler has been closed.
+var ErrClosed = errors.New("handler closed")
+
+// owned holds the resources owned by a Handler and those derived from it. It is shared by every
+// Handler in the chain, so closing any of them closes each resource once.
+type owned struct {
+	mu       sync.Mutex
+	flushers []func() // write output held back by the handler, such as sampling notices
+	closers  []io.Closer
+	once     sync.Once
+	closed   chan struct{}
+	err      error
+}
+
+// WithCloser returns a new Handler that owns c, such as the file it writes to, closing it when
+// Close is called. The new Handler shares ownership of c with the receiver and with Handlers
+// derived from either, for example using WithAttrs, so closing any of them closes c and all other
+// resources they own. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithCloser(c io.Closer) *Handler {
+	return h.own(nil, c)
+}
+
+// own returns a new Handler that shares the resources owned by the receiver, adding c and a call
+// to flush when closed, before closing any resources. Either of flush and c may be nil.
+func (h *Handler) own(flush func(), c io.Closer) *Handler {
+	h2 := h.clone()
+	if h2.owned == nil {
+		h2.owned = &owned{closed: make(chan struct{})}
+	}
+	o := h2.owned
+	o.mu.Lock()
+	if flush != nil {
+		o.flushers = append(o.flushers, flush)
+	}
+	if c != nil {
+		o.closers = append(o.closers, c)
+	}
+	o.mu.Unlock()
+	return h2
+}
+
+// Close writes any output held back by the Handler, such as notices of records suppressed by
+// WithSampling, then flushes and closes the resources owned by the Handler, in the reverse of the
+// order they were added using WithCloser. Resources with a Flush() error method are flushed before
+// they are closed. Once closed, the Handler and all Handlers derived from it return ErrClosed from
+// Handle. Close may be called more than once, returning the result of the first call, so a program
+// can defer it in main to ensure all records are written before exiting.
+func (h *Handler) Close() error {
+	o := h.owned
+	if o == nil {
+		return nil
+	}
+	o.once.Do(func() {
+		close(o.closed)
+		o.mu.Lock()
+		flushers, closers := o.flushers, o.closers
+		o.mu.Unlock()
+		for _, flush := range flushers {
+			flush()
+		}
+		var errs []error
+		for i := len(closers) - 1; i >= 0; i-- {
+			c := closers[i]
+			if f, ok := c.(interface{ Flush() error }); ok {
+				if err := f.Flush(); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		o.err = errors.Join(errs...)
+	})
+	return o.err
+}
+
+// isClosed reports whether the Handler has been closed.
+func (h *Handler) isClosed() bool {
+	if h.owned == nil {
+		return false
+	}
+	select {
+	case <-h.owned.closed:
+		return true
+	default:
+		return false
+	}
+}
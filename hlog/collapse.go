@@ -0,0 +1,109 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DropReasonRepeated is the reason recorded when a Handler drops a record because it repeats the
+// previous record, as configured using WithCollapse.
+const DropReasonRepeated = "repeated"
+
+// collapser suppresses consecutive records that are identical apart from their time. It is shared
+// by all handlers derived from the handler that enabled it.
+type collapser struct {
+	timeout time.Duration
+
+	mu      sync.Mutex // held while a record is checked and written so that output stays in order
+	last    []byte     // identity of the last record written
+	count   int        // number of repeats of the last record suppressed since the last summary
+	level   slog.Level // level of the last record
+	at      time.Time  // time of the last suppressed repeat
+	summary *Handler   // handler used to write the summary of repeats
+	timer   *time.Timer
+}
+
+// repeated reports whether the record with the given identity repeats the last record written, in
+// which case it is counted rather than written. Otherwise any summary of repeats of the last
+// record is written and the record becomes the last record. h is the handler handling the record.
+// The caller must hold c.mu.
+func (c *collapser) repeated(h *Handler, id []byte, r slog.Record) bool {
+	if c.last != nil && bytes.Equal(c.last, id) {
+		c.count++
+		c.at = r.Time
+		if c.timer == nil {
+			c.timer = time.AfterFunc(c.timeout, c.expire)
+		}
+		return true
+	}
+
+	c.summarize()
+	c.last = append(c.last[:0], id...)
+	c.level = r.Level
+
+	summary := h.clone()
+	summary.attrs = nil
+	summary.goroutine = false
+	summary.collapse = nil
+	summary.sampler = nil
+	summary.sidecar = nil
+	summary.owned = nil // so that the summary can be written while the handler is closed
+	c.summary = summary
+	return false
+}
+
+// summarize writes a record reporting the number of suppressed repeats of the last record, if
+// any. The caller must hold c.mu.
+func (c *collapser) summarize() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.count == 0 {
+		return
+	}
+	noun := "times"
+	if c.count == 1 {
+		noun = "time"
+	}
+	r := slog.NewRecord(c.at, c.level, fmt.Sprintf("… repeated %d %s", c.count, noun), 0)
+	c.count = 0
+	_ = c.summary.Handle(context.Background(), r)
+}
+
+// expire writes the summary of repeats when no different record has arrived within the timeout.
+// Later repeats of the same record continue to be suppressed.
+func (c *collapser) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summarize()
+}
+
+// close writes any summary of repeats that is pending and stops the timer that would write it.
+func (c *collapser) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summarize()
+}
+
+// WithCollapse returns a new Handler that collapses consecutive records that are identical apart
+// from their time, in the manner of syslog. The first record is written and its repeats are
+// counted, with the count written as a single "… repeated N times" record when a different record
+// is handled or when timeout has passed since the first uncounted repeat, whichever is sooner,
+// and by Close. Records are identical if they have the same level, message, attributes and source
+// location. Repeats are counted as dropped with the reason DropReasonRepeated when drop statistics
+// are enabled using WithDropStats, and are still passed to any sidecar handler. The state is
+// shared by all Handlers derived from the new Handler, which write records one at a time. The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithCollapse(timeout time.Duration) *Handler {
+	h2 := h.clone()
+	h2.collapse = &collapser{timeout: timeout}
+	return h2.own(h2.collapse.close, nil)
+}
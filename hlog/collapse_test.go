@@ -0,0 +1,113 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the lines written so far with runs of spaces collapsed.
+func (b *lockedBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return lines
+}
+
+func TestWithCollapse(t *testing.T) {
+	var buf lockedBuffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithTimeFormat("").WithDropStats().WithCollapse(time.Hour)
+	logger := slog.New(h).With("pkg", "db")
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("connection lost", "host", "a")
+	}
+	logger.Warn("connection lost", "host", "b")
+	logger.Warn("connection lost", "host", "b")
+	logger.Info("reconnected")
+
+	want := []string{
+		"warn | connection lost pkg=db host=a",
+		"warn | … repeated 2 times",
+		"warn | connection lost pkg=db host=b",
+		"warn | … repeated 1 time",
+		"info | reconnected pkg=db",
+	}
+	if got := buf.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got lines:\n%s\nwanted:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := h.Stats()[DropReasonRepeated]; got != 3 {
+		t.Errorf("got %d records dropped, wanted 3", got)
+	}
+}
+
+func TestWithCollapseTimeout(t *testing.T) {
+	var buf lockedBuffer
+	logger := slog.New(new(Handler).WithoutColor().WithWriter(&buf).WithTimeFormat("").WithCollapse(10 * time.Millisecond))
+
+	for i := 0; i < 4; i++ {
+		logger.Info("tick")
+	}
+
+	want := []string{"info | tick", "info | … repeated 3 times"}
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Join(buf.lines(), "\n") != strings.Join(want, "\n") {
+		if time.Now().After(deadline) {
+			t.Fatalf("got lines:\n%s\nwanted:\n%s", strings.Join(buf.lines(), "\n"), strings.Join(want, "\n"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Repeats after the summary are still collapsed
+	logger.Info("tick")
+	logger.Info("tock")
+	want = append(want, "info | … repeated 1 time", "info | tock")
+	if got := buf.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got lines:\n%s\nwanted:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWithCollapseClose(t *testing.T) {
+	var buf lockedBuffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithTimeFormat("").WithCollapse(time.Hour)
+	logger := slog.New(h)
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("connection lost")
+	}
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := []string{
+		"warn | connection lost",
+		"warn | … repeated 2 times",
+	}
+	if got := buf.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got lines:\n%s\nwanted:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if h.collapse.timer != nil {
+		t.Errorf("got timer still pending after Close")
+	}
+}
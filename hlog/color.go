@@ -0,0 +1,57 @@
+package hlog
+
+import (
+	"io"
+	"os"
+	"regexp"
+)
+
+// ColorMode determines when a Handler uses ANSI color directives.
+type ColorMode int
+
+const (
+	// ColorAuto uses color only when writing to a terminal. This is the default. Following common
+	// convention, color is never used while the NO_COLOR environment variable is set to a
+	// non-empty value, and is always used while CLICOLOR_FORCE is set to a value other than "0",
+	// so deployments can control color without code changes. NO_COLOR takes precedence. The
+	// environment and the writers are examined when the writers or color mode of a Handler are
+	// set, not for each record.
+	ColorAuto ColorMode = iota
+
+	// ColorAlways uses color regardless of the destination of the output.
+	ColorAlways
+
+	// ColorNever never uses color.
+	ColorNever
+)
+
+// autoColor reports whether color should be used when writing to w in the ColorAuto mode.
+func autoColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v := os.Getenv("CLICOLOR_FORCE"); v != "" && v != "0" {
+		return true
+	}
+	return isTerminal(w)
+}
+
+// ansiEscape matches the ANSI SGR escape sequences used for color.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// stripColor returns p with any ANSI color directives removed.
+func stripColor(p []byte) []byte {
+	return ansiEscape.ReplaceAll(p, nil)
+}
+
+// isTerminal reports whether w is a terminal. Only files that are character devices are
+// considered to be terminals and, on Windows, only consoles that support ANSI escape sequences,
+// which are enabled as a side effect.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0 && EnableWindowsANSI(f) == nil
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package hlog
+
+import "os"
+
+// EnableWindowsANSI enables the processing of ANSI escape sequences by the Windows console that f
+// writes to. On other platforms it does nothing.
+func EnableWindowsANSI(f *os.File) error {
+	return nil
+}
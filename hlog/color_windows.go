@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package hlog
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// EnableWindowsANSI enables the processing of ANSI escape sequences by the Windows console that f
+// writes to, so that colored output is rendered by cmd.exe and PowerShell rather than shown as
+// raw escape codes. It returns an error if f is not a console or the console does not support
+// escape sequences, as with versions of Windows before Windows 10. On other platforms it does
+// nothing. Handlers using ColorAuto call EnableWindowsANSI automatically; it only needs to be
+// called explicitly when color is forced using ColorAlways.
+func EnableWindowsANSI(f *os.File) error {
+	h := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return fmt.Errorf("get console mode: %w", err)
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return nil
+	}
+	if err := windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		return fmt.Errorf("set console mode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxAttrsKey struct{}
+
+// ContextWithAttrs returns a copy of ctx carrying the given attributes in addition to any already
+// carried by ctx. A Handler includes the attributes carried by the context passed to Handle in
+// each record it emits, after its own attributes and before those of the record. This allows
+// request-scoped values to be attached to every record logged while handling a request without
+// passing a logger around, provided the context-aware logging methods such as
+// slog.Logger.InfoContext are used.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	existing := AttrsFromContext(ctx)
+	combined := make([]slog.Attr, 0, len(existing)+len(attrs))
+	combined = append(combined, existing...)
+	combined = append(combined, attrs...)
+	return context.WithValue(ctx, ctxAttrsKey{}, combined)
+}
+
+// AttrsFromContext returns the attributes carried by ctx. The returned slice must not be modified.
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxAttrsKey{}).([]slog.Attr)
+	return attrs
+}
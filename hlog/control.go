@@ -0,0 +1,223 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Control holds a minimum log level and a set of attribute levels that may be changed while a
+// program is running, either directly, over HTTP or in response to operating system signals.
+// Every change is recorded by emitting an audit record describing the source of the change and
+// the old and new values, so that debugging sessions leave a trace in the log itself.
+//
+// A Control is attached to a Handler using Handler.WithControl. It is safe for concurrent use.
+type Control struct {
+	level slog.LevelVar
+
+	mu     sync.Mutex // serializes changes
+	rules  atomic.Pointer[map[string][]attrValueLevel]
+	logger atomic.Pointer[slog.Logger]
+}
+
+// NewControl returns a new Control with the given initial minimum level.
+func NewControl(level slog.Level) *Control {
+	c := &Control{}
+	c.level.Set(level)
+	return c
+}
+
+// SetAuditLogger sets the logger used to emit audit records. By default the slog default logger
+// is used.
+func (c *Control) SetAuditLogger(l *slog.Logger) {
+	c.logger.Store(l)
+}
+
+// Level returns the current minimum level. It implements slog.Leveler.
+func (c *Control) Level() slog.Level {
+	return c.level.Level()
+}
+
+// SetLevel changes the minimum level. source describes who or what made the change and is
+// included in the audit record.
+func (c *Control) SetLevel(level slog.Level, source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.level.Level()
+	if old == level {
+		return
+	}
+
+	// Emit the audit record while the lower of the two levels is in force, and at a level no
+	// lower than it, so that it is not suppressed by the change it describes.
+	args := []any{slog.String("source", source), slog.Any("old", old), slog.Any("new", level)}
+	if level > old {
+		c.audit(old, "log level changed", args...)
+		c.level.Set(level)
+	} else {
+		c.level.Set(level)
+		c.audit(level, "log level changed", args...)
+	}
+}
+
+// SetAttrLevel associates a log level with an attribute key and value in the same manner as
+// Handler.WithAttrLevel, replacing any level previously set for the same key and value. source
+// describes who or what made the change and is included in the audit record.
+func (c *Control) SetAttrLevel(a slog.Attr, level slog.Level, source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rules := c.copyRules()
+	old := "none"
+	vs := rules[a.Key]
+	for i, v := range vs {
+		if v.value.Equal(a.Value) {
+			old = v.level.String()
+			vs = append(vs[:i:i], vs[i+1:]...)
+			break
+		}
+	}
+	rules[a.Key] = append(vs, attrValueLevel{value: a.Value, level: level})
+	c.rules.Store(&rules)
+
+	c.audit(c.level.Level(), "attribute log level changed", slog.String("source", source), slog.String("attr", a.String()), slog.String("old", old), slog.Any("new", level))
+}
+
+// ClearAttrLevels removes all attribute levels set using SetAttrLevel. source describes who or
+// what made the change and is included in the audit record.
+func (c *Control) ClearAttrLevels(source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.describeRules()
+	if len(old) == 0 {
+		return
+	}
+	c.rules.Store(nil)
+	c.audit(c.level.Level(), "attribute log levels cleared", slog.String("source", source), slog.String("old", strings.Join(old, " ")))
+}
+
+// attrLevels returns the current attribute levels, which must not be modified.
+func (c *Control) attrLevels() map[string][]attrValueLevel {
+	if m := c.rules.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+func (c *Control) hasAttrLevels() bool {
+	return len(c.attrLevels()) > 0
+}
+
+// copyRules returns a copy of the current attribute levels that may be modified.
+func (c *Control) copyRules() map[string][]attrValueLevel {
+	rules := make(map[string][]attrValueLevel)
+	for k, v := range c.attrLevels() {
+		rules[k] = append([]attrValueLevel(nil), v...)
+	}
+	return rules
+}
+
+// describeRules returns a textual description of each of the current attribute levels in a stable
+// order. Values are quoted so that each description is a single unambiguous word.
+func (c *Control) describeRules() []string {
+	var rs []string
+	for k, vs := range c.attrLevels() {
+		for _, v := range vs {
+			rs = append(rs, fmt.Sprintf("%s=%q:%s", k, v.value.String(), v.level))
+		}
+	}
+	sort.Strings(rs)
+	return rs
+}
+
+// audit emits an audit record at the info level, or at level if that is higher, so that the
+// record is not filtered out by a minimum level of level.
+func (c *Control) audit(level slog.Level, msg string, args ...any) {
+	l := c.logger.Load()
+	if l == nil {
+		l = slog.Default()
+	}
+	l.Log(context.Background(), max(level, slog.LevelInfo), msg, args...)
+}
+
+// ServeHTTP allows the levels held by the Control to be inspected and changed over HTTP.
+//
+// A GET request responds with the current minimum level followed by any attribute levels, one per
+// line, with their values quoted. A POST or PUT request changes a level using the form values "level", which is parsed
+// using slog.Level.UnmarshalText, and "attr" which, if present, is of the form key=value and names
+// the attribute whose level is to be set. Only string valued attributes can be matched by levels
+// set in this way. A DELETE request clears all attribute levels.
+func (c *Control) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	source := fmt.Sprintf("http %s from %s", r.Method, r.RemoteAddr)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost, http.MethodPut:
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(r.FormValue("level"))); err != nil {
+			http.Error(w, fmt.Sprintf("invalid level: %v", err), http.StatusBadRequest)
+			return
+		}
+		if attr := r.FormValue("attr"); attr != "" {
+			k, v, ok := strings.Cut(attr, "=")
+			if !ok || k == "" {
+				http.Error(w, "invalid attr: expected the form key=value", http.StatusBadRequest)
+				return
+			}
+			c.SetAttrLevel(slog.String(k, v), level, source)
+		} else {
+			c.SetLevel(level, source)
+		}
+	case http.MethodDelete:
+		c.ClearAttrLevels(source)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "level=%s\n", c.Level())
+	for _, rule := range c.describeRules() {
+		fmt.Fprintf(w, "attr %s\n", rule)
+	}
+}
+
+// WatchSignals changes the minimum level in response to operating system signals until the
+// context is cancelled, when it returns the context's error. Receipt of the more signal lowers
+// the minimum level by one step, making logging more verbose, and receipt of the less signal
+// raises it by one step. The steps are the levels defined by slog: debug, info, warn and error.
+// Typical signals are syscall.SIGUSR1 and syscall.SIGUSR2, which are not defined on all platforms.
+func (c *Control) WatchSignals(ctx context.Context, more, less os.Signal) error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, more, less)
+	defer signal.Stop(ch)
+
+	const step = slog.LevelInfo - slog.LevelDebug
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sig := <-ch:
+			level := c.Level()
+			if sig == more {
+				level = max(level-step, slog.LevelDebug)
+			} else {
+				level = min(level+step, slog.LevelError)
+			}
+			c.SetLevel(level, "signal "+sig.String())
+		}
+	}
+}
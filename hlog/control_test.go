@@ -0,0 +1,115 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestControl(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewControl(slog.LevelWarn)
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithControl(c)
+	logger := slog.New(h)
+	c.SetAuditLogger(logger)
+
+	logger.Info("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	c.SetLevel(slog.LevelDebug, "test")
+	logger.Debug("visible")
+
+	out := buf.String()
+	if !strings.Contains(out, "log level changed") || !strings.Contains(out, "old=WARN") || !strings.Contains(out, "new=DEBUG") {
+		t.Errorf("missing audit record: %q", out)
+	}
+	if !strings.Contains(out, "visible") {
+		t.Errorf("missing debug record: %q", out)
+	}
+
+	// Raising the level should still emit the audit record
+	buf.Reset()
+	c.SetLevel(slog.LevelError, "test")
+	if !strings.Contains(buf.String(), "new=ERROR") {
+		t.Errorf("missing audit record: %q", buf.String())
+	}
+
+	// Lowering the level from error to warn should emit the audit record at a level that passes
+	buf.Reset()
+	c.SetLevel(slog.LevelWarn, "test")
+	if out := buf.String(); !strings.Contains(out, "warn") || !strings.Contains(out, "old=ERROR") || !strings.Contains(out, "new=WARN") {
+		t.Errorf("missing audit record: %q", out)
+	}
+
+	// Attribute level changes are audited at a level that passes the current minimum
+	buf.Reset()
+	c.SetLevel(slog.LevelError, "test")
+	buf.Reset()
+	c.SetAttrLevel(slog.String("pkg", "db"), slog.LevelDebug, "test")
+	if out := buf.String(); !strings.Contains(out, "attribute log level changed") {
+		t.Errorf("missing audit record: %q", out)
+	}
+}
+
+func TestControlHTTP(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewControl(slog.LevelInfo)
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithControl(c)
+	logger := slog.New(h)
+	c.SetAuditLogger(logger)
+
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	resp, err := http.PostForm(srv.URL, url.Values{"level": {"debug"}, "attr": {"pkg=db"}})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, wanted %d", resp.StatusCode, http.StatusOK)
+	}
+
+	logger.Debug("from db", "pkg", "db")
+	logger.Debug("from web", "pkg", "web")
+
+	out := buf.String()
+	if !strings.Contains(out, "from db") {
+		t.Errorf("missing record matching attribute level: %q", out)
+	}
+	if strings.Contains(out, "from web") {
+		t.Errorf("unexpected record not matching attribute level: %q", out)
+	}
+	if !strings.Contains(out, "attribute log level changed") {
+		t.Errorf("missing audit record: %q", out)
+	}
+
+	resp, err = http.PostForm(srv.URL, url.Values{"level": {"warn"}, "attr": {"user=John Smith"}})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	want := "level=INFO\nattr pkg=\"db\":DEBUG\nattr user=\"John Smith\":WARN\n"
+	if string(body) != want {
+		t.Errorf("got body %q, wanted %q", body, want)
+	}
+}
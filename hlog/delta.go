@@ -0,0 +1,54 @@
+package hlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// deltaWidth is the width of the column showing the time since the previous record.
+const deltaWidth = 10
+
+// recordDelta tracks the time of the most recent record to determine the time elapsed between
+// records. It is shared by all handlers derived from the handler that enabled it so that the
+// deltas follow the combined stream of records.
+type recordDelta struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// text records t as the time of the latest record and returns the time elapsed since the previous
+// record formatted for display, as in "Δ 35ms". It returns an empty string for the first record
+// and for records without a time.
+func (d *recordDelta) text(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d.mu.Lock()
+	last := d.last
+	if t.After(d.last) {
+		d.last = t
+	}
+	d.mu.Unlock()
+
+	if last.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("Δ %v", roundDelta(t.Sub(last)))
+}
+
+// roundDelta rounds d to a precision that keeps it short while remaining useful: to the
+// microsecond below a millisecond, to a tenth of a millisecond below a second and to the
+// millisecond otherwise. Negative durations are shown as zero.
+func roundDelta(d time.Duration) time.Duration {
+	switch {
+	case d < 0:
+		return 0
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond)
+	case d < time.Second:
+		return d.Round(100 * time.Microsecond)
+	default:
+		return d.Round(time.Millisecond)
+	}
+}
@@ -0,0 +1,139 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reasons for which a Handler may drop records, used as keys of the counts returned by
+// Handler.Stats.
+const (
+	DropReasonLevel     = "level"      // the record was below the handler's minimum level
+	DropReasonAttrLevel = "attr_level" // the record was below the minimum level and not permitted by an attribute level
+)
+
+// recordCounter counts records emitted by a handler, keyed by level, and records dropped by a
+// handler, keyed by reason. It is shared by all handlers derived from the handler that enabled it.
+type recordCounter struct {
+	emitted sync.Map // slog.Level to *atomic.Uint64
+	dropped sync.Map // reason to *atomic.Uint64
+}
+
+// increment adds one to the counter held in m under key.
+func increment(m *sync.Map, key any) {
+	c, ok := m.Load(key)
+	if !ok {
+		c, _ = m.LoadOrStore(key, new(atomic.Uint64))
+	}
+	c.(*atomic.Uint64).Add(1)
+}
+
+// snapshot returns the values of the counters held in m.
+func snapshot[K comparable](m *sync.Map) map[K]uint64 {
+	s := make(map[K]uint64)
+	m.Range(func(k, v any) bool {
+		s[k.(K)] = v.(*atomic.Uint64).Load()
+		return true
+	})
+	return s
+}
+
+// WithDropStats returns a new Handler that counts the records it drops, by reason, so that it is
+// possible to tell that the handler is filtering records rather than the application being silent.
+// It also counts the records it emits, by level. The counts are shared by all handlers derived
+// from the new Handler and may be retrieved using Stats and Emitted or the drops logged
+// periodically using LogDropSummary. Records are only counted as dropped when they reach Handle,
+// since Enabled may be called any number of times for each record. slog.Logger and TeeHandler do
+// not pass on records rejected by Enabled, so records below the handler's level are normally not
+// counted at all, only those passed to Handle by code that does not check Enabled first. Records
+// dropped by attribute levels are always counted. The new Handler is otherwise identical to the
+// receiver.
+func (h *Handler) WithDropStats() *Handler {
+	h2 := h.clone()
+	h2.drops = &recordCounter{}
+	return h2
+}
+
+// Stats returns the number of records dropped by the handler and those sharing its counts, keyed
+// by reason. It returns nil if drop statistics were not enabled using WithDropStats.
+func (h *Handler) Stats() map[string]uint64 {
+	if h.drops == nil {
+		return nil
+	}
+	return snapshot[string](&h.drops.dropped)
+}
+
+// Emitted returns the number of records emitted by the handler and those sharing its counts, keyed
+// by level. It returns nil if statistics were not enabled using WithDropStats.
+func (h *Handler) Emitted() map[slog.Level]uint64 {
+	if h.drops == nil {
+		return nil
+	}
+	return snapshot[slog.Level](&h.drops.emitted)
+}
+
+// dropped records that a record was dropped for the given reason.
+func (h *Handler) dropped(reason string) {
+	if h.drops != nil {
+		increment(&h.drops.dropped, reason)
+	}
+}
+
+// written records the outcome of writing a record at the given level: emitted if err is nil and
+// otherwise dropped with the reason DropReasonWriteFailed. It does nothing if c is nil.
+func (c *recordCounter) written(level slog.Level, err error) {
+	switch {
+	case c == nil:
+	case err != nil:
+		increment(&c.dropped, DropReasonWriteFailed)
+	default:
+		increment(&c.emitted, level)
+	}
+}
+
+// LogDropSummary logs an info record summarising the records dropped by the handler every
+// interval until the context is cancelled. Summaries are only logged for intervals in which
+// records were dropped. It returns the context's error. LogDropSummary does nothing until the
+// context is cancelled if drop statistics were not enabled using WithDropStats.
+func (h *Handler) LogDropSummary(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := map[string]uint64{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		current := h.Stats()
+		var reasons []string
+		for reason, n := range current {
+			if n > last[reason] {
+				reasons = append(reasons, reason)
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		sort.Strings(reasons)
+
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "hlog dropped records", 0)
+		for _, reason := range reasons {
+			r.AddAttrs(slog.Uint64(reason, current[reason]-last[reason]))
+		}
+		r.AddAttrs(slog.Duration("interval", interval))
+		if err := h.Handle(ctx, r); err != nil {
+			return err
+		}
+		last = current
+	}
+}
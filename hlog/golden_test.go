@@ -0,0 +1,111 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// goldenTime is the time of every record in the golden corpus, so output is reproducible.
+var goldenTime = time.Date(2024, 3, 1, 14, 30, 15, 123456000, time.UTC)
+
+// goldenCorpus is a representative set of records rendered by each golden configuration.
+var goldenCorpus = []struct {
+	level slog.Level
+	msg   string
+	attrs []slog.Attr
+}{
+	{level: slog.LevelDebug, msg: "cache warmed", attrs: []slog.Attr{slog.Int("entries", 1024), slog.Duration("took", 1500*time.Millisecond)}},
+	{level: slog.LevelInfo, msg: "request handled", attrs: []slog.Attr{
+		slog.Group("req", slog.String("method", "GET"), slog.String("path", "/api/v1/users")),
+		slog.Int("status", 200),
+	}},
+	{level: slog.LevelWarn, msg: "slow query", attrs: []slog.Attr{slog.Float64("seconds", 2.75), slog.String("query", "SELECT id, name, email FROM users WHERE created_at > $1 ORDER BY created_at")}},
+	{level: slog.LevelError, msg: "upstream failed", attrs: []slog.Attr{slog.Any("error", errors.New("dial tcp 10.0.0.1:443: connection refused")), slog.Bool("retrying", true)}},
+	{level: slog.LevelInfo, msg: "usuário criado ✓", attrs: []slog.Attr{slog.String("nome", "José Müller"), slog.String("emoji", "🚀")}},
+	{level: slog.LevelInfo + 2, msg: "custom level", attrs: []slog.Attr{slog.Time("at", goldenTime.Add(time.Hour))}},
+	{level: slog.LevelInfo, msg: "a message that is considerably longer than the default width of the message column", attrs: []slog.Attr{slog.String("k", "v")}},
+	{level: slog.LevelInfo, msg: "empty and inline groups", attrs: []slog.Attr{slog.Group("empty"), slog.Group("", slog.String("inlined", "yes")), slog.Group("meta", slog.Group("tags", slog.String("env", "prod")))}},
+}
+
+func TestGolden(t *testing.T) {
+	testCases := []struct {
+		name string
+		h    func(*Handler) *Handler
+		step time.Duration // time between consecutive records of the corpus
+	}{
+		{name: "plain", h: func(h *Handler) *Handler { return h.WithoutColor() }},
+		{name: "color", h: func(h *Handler) *Handler { return h.WithColor(ColorAlways) }},
+		{name: "theme-light", h: func(h *Handler) *Handler { return h.WithColor(ColorAlways).WithTheme(LightTheme()) }},
+		{name: "autowidth", h: func(h *Handler) *Handler { return h.WithoutColor().WithAutoWidth(20) }},
+		{name: "truncated", h: func(h *Handler) *Handler { return h.WithoutColor().WithTruncation(12, 8) }},
+		{name: "json-groups", h: func(h *Handler) *Handler { return h.WithoutColor().WithJSONGroup("req").WithJSONGroup("meta") }},
+		{name: "zone", h: func(h *Handler) *Handler {
+			return h.WithoutColor().WithTimezone(time.FixedZone("EST", -5*60*60)).WithZoneName()
+		}},
+		{name: "grouped-logger", h: func(h *Handler) *Handler {
+			return h.WithoutColor().WithAttrs([]slog.Attr{slog.String("service", "api")}).WithGroup("call").(*Handler)
+		}},
+		{name: "elapsed", step: 35 * time.Millisecond, h: func(h *Handler) *Handler {
+			h = h.WithoutColor().WithElapsedTime()
+			h.epoch = goldenTime.Add(-90 * time.Second)
+			return h
+		}},
+		{name: "delta", step: 35 * time.Millisecond, h: func(h *Handler) *Handler { return h.WithoutColor().WithDelta() }},
+		{name: "layout", h: func(h *Handler) *Handler {
+			return h.WithoutColor().WithLayout(Layout{Columns: []Column{ColumnLevel, ColumnMessage, ColumnAttrs}, MessageWidth: 24})
+		}},
+		{name: "static-bracket", h: func(h *Handler) *Handler {
+			return h.WithoutColor().WithStaticAttrs(StaticAttrsBracket).WithAttrs([]slog.Attr{slog.String("service", "api")}).(*Handler)
+		}},
+		{name: "static-dim", h: func(h *Handler) *Handler {
+			return h.WithColor(ColorAlways).WithStaticAttrs(StaticAttrsDim).WithAttrs([]slog.Attr{slog.String("service", "api")}).(*Handler)
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := tc.h(new(Handler).WithLevel(slog.LevelDebug).WithWriter(&buf))
+			for i, c := range goldenCorpus {
+				r := slog.NewRecord(goldenTime.Add(time.Duration(i)*tc.step), c.level, c.msg, 0)
+				r.AddAttrs(c.attrs...)
+				if err := h.Handle(context.Background(), r); err != nil {
+					t.Fatalf("Handle: %v", err)
+				}
+			}
+
+			path := filepath.Join("testdata", "golden", tc.name+".txt")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("create golden dir: %v", err)
+				}
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatalf("write golden file: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create): %v", err)
+			}
+			if diff := cmp.Diff(strings.Split(string(want), "\n"), strings.Split(buf.String(), "\n")); diff != "" {
+				t.Errorf("output differs from %s (-want +got):\n%s", path, diff)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ package hlog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,67 +13,179 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-const (
-	colorReset  = "\x1b[0m"
-	colorRed    = "\x1b[1;31m"
-	colorGreen  = "\x1b[1;32m"
-	colorYellow = "\x1b[1;33m"
-	colorBlue   = "\x1b[1;34m"
-)
+const colorReset = "\x1b[0m"
 
 var _ slog.Handler = (*Handler)(nil)
 
 // Handler is a slog logging handler that provides human friendly log output. It's not intended to be used in high
 // throughput situations, but is more suited to logs that a human might want to watch, such during as the development
 // phase of a service.
+//
+// A Handler is safe for concurrent use. Each record is written using a single call to Write, and
+// writes to the same writer by any Handler in the process are serialized so that lines are never
+// interleaved, even when the writer does not synchronize writes itself.
 type Handler struct {
 	minLevel   slog.Level
 	nocolor    bool
-	group      string
-	attrs      []slog.Attr
+	color      ColorMode
+	attrs      *attrNode
+	groups     []string // groups opened by WithGroup, which qualify the keys of record attributes
 	writer     io.Writer
+	writers    []io.Writer   // optional writers receiving the same output, in place of writer
+	writeMu    []*sync.Mutex // serializes writes to writer, or to each of writers
+	colorOut   []bool        // whether output to writer, or to each of writers, uses color
 	prefixName *string
 	attrLevels map[string][]attrValueLevel // associates an attribute key with a value and a log level
+	goroutine  bool                        // whether to annotate records with the emitting goroutine
+	control    *Control                    // optional runtime control of levels
+	jsonGroups []string                    // names of groups to render as trailing JSON objects
+	callerSkip int                         // number of additional stack frames to skip when attributing records
+	location   *time.Location              // optional time zone used to render timestamps
+	showZone   bool                        // whether to include the time zone abbreviation in timestamps
+	timeLayout *string                     // optional layout of timestamps, omitting them when empty
+	epoch      time.Time                   // if not zero, timestamps are shown as the time elapsed since epoch
+	delta      *recordDelta                // optional time elapsed between records
+	layout     *Layout                     // optional columns used in place of the default layout
+	locale     *TimeLocale                 // optional names of days and months in timestamps
+	isoWeek    bool                        // whether to precede timestamps with the ISO week number
+	static     StaticAttrs                 // how attributes added using WithAttrs are distinguished
+	lint       *messageLinter              // optional check for messages containing formatted values
+	msgWidth   *messageWidth               // optional automatic width of the message column
+	drops      *recordCounter              // optional counts of emitted and dropped records
+	truncate   *truncation                 // optional truncation of long values
+	keyTrunc   map[string]truncation       // truncation of long values by attribute key
+	sidecar    slog.Handler                // optional handler receiving full fidelity copies of records
+	leveler    slog.Leveler                // optional dynamic minimum level, replacing minLevel
+	addSource  bool                        // whether to include the source location of records
+	srcStyle   SourceStyle                 // how the source location is shown
+	replace    replaceFunc                 // optional rewriting of attributes
+	owned      *owned                      // optional resources closed by Close
+	async      *asyncQueue                 // optional queue of output written in the background
+	theme      *Theme                      // optional colors used in place of the default theme
+	onError    func(error)                 // optional function called when writing a record fails
+	sampler    *sampler                    // optional limit on repetitive records
+	collapse   *collapser                  // optional suppression of consecutive identical records
+	attrSep    string                      // text written before each attribute, if not a space
+	kvDelim    string                      // text written between keys and values, if not "="
+	profile    ColorProfile                // colors supported by the terminal
 }
 
+// replaceFunc rewrites an attribute in the manner of slog.HandlerOptions.ReplaceAttr.
+type replaceFunc func(groups []string, a slog.Attr) slog.Attr
+
+// clone returns a shallow copy of the handler. Handlers are immutable once created so the copy
+// shares the receiver's attributes, attribute levels and other reference types. Methods that
+// modify a reference type in the copy must replace it rather than mutating it in place, so
+// deriving a handler costs the same regardless of how many attributes it has inherited.
 func (h *Handler) clone() *Handler {
-	h2 := &Handler{
-		minLevel:   h.minLevel,
-		nocolor:    h.nocolor,
-		group:      h.group,
-		prefixName: h.prefixName,
-		attrLevels: make(map[string][]attrValueLevel),
-		writer:     h.writer,
-	}
-	h2.attrs = append(h2.attrs, h.attrs...)
-	for k, v := range h.attrLevels {
-		h2.attrLevels[k] = append(h2.attrLevels[k], v...)
-	}
+	h2 := *h
+	return &h2
+}
 
-	return h2
+// attrNode is an element of an immutable chain of attributes and groups added to a handler by
+// WithAttrs and WithGroup. Each node holds the attributes added by a single call to WithAttrs, or
+// the name of a group opened by WithGroup, and points to the node holding whatever was added
+// before.
+type attrNode struct {
+	parent *attrNode
+	attrs  []slog.Attr
+	group  string
+}
+
+// each calls fn for each attribute in the chain ending at n, in the order they were added, with the
+// groups opened before the attribute was added. It returns the groups open after n.
+func (n *attrNode) each(fn func(groups []string, a slog.Attr)) []string {
+	if n == nil {
+		return nil
+	}
+	groups := n.parent.each(fn)
+	if n.group != "" {
+		return append(groups[:len(groups):len(groups)], n.group)
+	}
+	for _, a := range n.attrs {
+		fn(groups, a)
+	}
+	return groups
 }
 
 type attrValueLevel struct {
 	value slog.Value
+	match func(slog.Value) bool // if non-nil, used in place of comparing with value
 	level slog.Level
 }
 
+// matches reports whether v satisfies the attribute level.
+func (v attrValueLevel) matches(val slog.Value) bool {
+	if v.match != nil {
+		return v.match(val.Resolve())
+	}
+	return v.value.Equal(val)
+}
+
 // WithLevel returns a new Handler with a minimum log level set to level. The new
 // Handler is otherwise identical to the receiver.
 func (h *Handler) WithLevel(level slog.Level) *Handler {
 	h2 := h.clone()
 	h2.minLevel = level
+	h2.leveler = nil
+	return h2
+}
+
+// WithLeveler returns a new Handler whose minimum log level is reported by l, which is consulted
+// for each record. A shared slog.LevelVar may be used to change the verbosity of every logger
+// derived from the Handler while a program is running. The new Handler is otherwise identical to
+// the receiver.
+func (h *Handler) WithLeveler(l slog.Leveler) *Handler {
+	h2 := h.clone()
+	h2.leveler = l
+	return h2
+}
+
+// WithSource returns a new Handler that includes the file name and line number of the code that
+// emitted each record, shown in the given style. The file name is shortened to its final directory
+// and base name, as in "hlog/handler.go:42", unless rewritten using WithReplaceAttr. The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithSource(style SourceStyle) *Handler {
+	h2 := h.clone()
+	h2.addSource = true
+	h2.srcStyle = style
+	return h2
+}
+
+// WithReplaceAttr returns a new Handler that calls fn to rewrite, rename or drop each attribute
+// before it is written, in the same manner as slog.HandlerOptions.ReplaceAttr. fn is called with
+// the names of the groups containing the attribute, and is not called for group attributes, only
+// for their members. The level, time and message of each record are also passed to fn, using the
+// keys slog.LevelKey, slog.TimeKey and slog.MessageKey with no groups, so that they may be
+// reformatted or dropped, although they cannot be renamed. When the source location of records is
+// included it is passed to fn as a *slog.Source using slog.SourceKey, allowing file paths to be
+// shortened. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithReplaceAttr(fn func(groups []string, a slog.Attr) slog.Attr) *Handler {
+	h2 := h.clone()
+	h2.replace = fn
 	return h2
 }
 
 // WithoutColor returns a new Handler that is configured to emit logs without using ANSI
-// color directives. The new Handler is otherwise identical to the receiver.
+// color directives. It is equivalent to WithColor(ColorNever). The new Handler is otherwise
+// identical to the receiver.
 func (h *Handler) WithoutColor() *Handler {
+	return h.WithColor(ColorNever)
+}
+
+// WithColor returns a new Handler that uses ANSI color directives according to mode. By default
+// color is only used when writing to a terminal, subject to the NO_COLOR and CLICOLOR_FORCE
+// environment variables as described for ColorAuto. The new Handler is otherwise identical to the
+// receiver.
+func (h *Handler) WithColor(mode ColorMode) *Handler {
 	h2 := h.clone()
-	h2.nocolor = true
+	h2.color = mode
+	h2.nocolor = mode == ColorNever
+	h2.resolveColor()
 	return h2
 }
 
@@ -88,9 +201,187 @@ func (h *Handler) WithPrefix(name string) *Handler {
 
 // WithWriter returns a new Handler that writes output to w. The new Handler is
 // otherwise identical to the receiver.
+//
+// Handlers derived from one another never interleave their writes to the same writer, but
+// Handlers created separately do not coordinate. To share w between them, derive them from a
+// single Handler or wrap w in a writer that serializes its own writes.
 func (h *Handler) WithWriter(w io.Writer) *Handler {
 	h2 := h.clone()
 	h2.writer = w
+	h2.writers = nil
+	h2.writeMu = []*sync.Mutex{h.writerLock(w)}
+	h2.resolveColor()
+	return h2
+}
+
+// WithWriters returns a new Handler that writes the same output to each of ws, such as a terminal
+// and a file. Whether color is used is decided separately for each writer, so with the default
+// ColorAuto mode the terminal receives colored output while the file does not. A writer that fails
+// does not prevent the output from being written to the others. The new Handler is otherwise
+// identical to the receiver.
+func (h *Handler) WithWriters(ws ...io.Writer) *Handler {
+	h2 := h.clone()
+	h2.writer = nil
+	h2.writers = append([]io.Writer(nil), ws...)
+	h2.writeMu = make([]*sync.Mutex, len(ws))
+	for i, w := range ws {
+		h2.writeMu[i] = h.writerLock(w)
+	}
+	h2.resolveColor()
+	return h2
+}
+
+// WithTimezone returns a new Handler that renders timestamps in the time zone loc, regardless of
+// the local time zone of the process. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithTimezone(loc *time.Location) *Handler {
+	h2 := h.clone()
+	h2.location = loc
+	return h2
+}
+
+// WithTimeFormat returns a new Handler that formats timestamps using layout, as understood by
+// time.Time.Format, instead of the default "15:04:05.000000". Layouts that include the date, such
+// as time.RFC3339 or "2006-01-02 15:04:05.000", suit long running services. An empty layout omits
+// the timestamp column entirely. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithTimeFormat(layout string) *Handler {
+	h2 := h.clone()
+	h2.timeLayout = &layout
+	h2.epoch = time.Time{}
+	return h2
+}
+
+// WithElapsedTime returns a new Handler that shows the time of each record as the time elapsed
+// since WithElapsedTime was called, as in "+12.345s", instead of the time of day. This is easier to
+// follow when reading startup sequences and benchmarks. Handlers derived from the new Handler
+// measure from the same moment. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithElapsedTime() *Handler {
+	h2 := h.clone()
+	h2.epoch = time.Now()
+	h2.timeLayout = nil
+	return h2
+}
+
+// WithDelta returns a new Handler that shows the time elapsed since the previous record in a column
+// after the timestamp, as in "Δ 35ms", making slow steps easy to spot while watching the log.
+// The delta is part of ColumnTime, so it is not shown by a layout set using WithLayout that omits
+// the time column. Handlers derived from the new Handler share the same previous record. The new Handler is
+// otherwise identical to the receiver.
+func (h *Handler) WithDelta() *Handler {
+	h2 := h.clone()
+	h2.delta = new(recordDelta)
+	return h2
+}
+
+// WithZoneName returns a new Handler that includes the abbreviated name of the time zone, such as
+// UTC or CET, after each timestamp. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithZoneName() *Handler {
+	h2 := h.clone()
+	h2.showZone = true
+	return h2
+}
+
+// WithAttrSeparator returns a new Handler that writes sep before each attribute in place of a
+// space, such as "\t" to make the attributes tab separated fields for tools like cut and awk.
+// String values containing sep are quoted. An empty sep restores the default. The new Handler is
+// otherwise identical to the receiver.
+func (h *Handler) WithAttrSeparator(sep string) *Handler {
+	h2 := h.clone()
+	h2.attrSep = sep
+	return h2
+}
+
+// WithKeyValueDelimiter returns a new Handler that writes delim between the key and value of each
+// attribute in place of "=", such as ": " to produce key: value. An empty delim restores the
+// default. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithKeyValueDelimiter(delim string) *Handler {
+	h2 := h.clone()
+	h2.kvDelim = delim
+	return h2
+}
+
+// separator returns the text written before each attribute.
+func (h *Handler) separator() string {
+	if h.attrSep == "" {
+		return " "
+	}
+	return h.attrSep
+}
+
+// delimiter returns the text written between the key and value of each attribute.
+func (h *Handler) delimiter() string {
+	if h.kvDelim == "" {
+		return "="
+	}
+	return h.kvDelim
+}
+
+// WithAutoWidth returns a new Handler that pads messages to the length of the longest of the
+// recently logged messages, up to limit characters, instead of the fixed width of 40 characters.
+// This keeps attributes aligned without wasting space when messages are short. Handlers derived
+// from the new Handler share the same column width. The new Handler is otherwise identical to the
+// receiver.
+func (h *Handler) WithAutoWidth(limit int) *Handler {
+	h2 := h.clone()
+	h2.msgWidth = &messageWidth{limit: limit}
+	return h2
+}
+
+// WithTruncation returns a new Handler that shortens attribute values longer than head+tail+1
+// characters by keeping the first head and last tail characters, separated by an ellipsis. A value
+// of exactly head+tail+1 characters is left as it is, since the ellipsis would not shorten it.
+// Keeping both ends is useful because identifiers and paths often differ only at the end. The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithTruncation(head, tail int) *Handler {
+	h2 := h.clone()
+	h2.truncate = &truncation{head: head, tail: tail}
+	return h2
+}
+
+// WithKeyTruncation returns a new Handler that shortens values of attributes with the given key as
+// described for WithTruncation, using the given head and tail lengths instead of those configured
+// for other keys. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithKeyTruncation(key string, head, tail int) *Handler {
+	h2 := h.clone()
+	h2.keyTrunc = make(map[string]truncation, len(h.keyTrunc)+1)
+	for k, v := range h.keyTrunc {
+		h2.keyTrunc[k] = v
+	}
+	h2.keyTrunc[key] = truncation{head: head, tail: tail}
+	return h2
+}
+
+// WithGoroutineID returns a new Handler that annotates each record with the id of the goroutine
+// that emitted it, using the attribute key "goroutine". This makes it easier to untangle the
+// interleaved output of concurrent code. The id is only accurate when the Handler is called
+// synchronously by the logging goroutine, which is the case unless it is wrapped by a handler
+// that defers processing. Goroutine ids are reused by the runtime, so where a stable identity is
+// needed prefer attaching a label to the logger, such as logger.With("worker", name). The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithGoroutineID() *Handler {
+	h2 := h.clone()
+	h2.goroutine = true
+	return h2
+}
+
+// WithJSONGroup returns a new Handler that renders attributes belonging to the named group as a
+// single compact JSON object at the end of the line, rather than as individual key=value pairs.
+// This keeps detailed structured data greppable without cluttering the rest of the line. If
+// several groups with the name are attached to a record their attributes are merged into one
+// object. WithJSONGroup may be called more than once to render several groups as JSON. The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithJSONGroup(name string) *Handler {
+	h2 := h.clone()
+	h2.jsonGroups = append(h.jsonGroups[:len(h.jsonGroups):len(h.jsonGroups)], name)
+	return h2
+}
+
+// WithCallerSkip returns a new Handler that attributes each record to the call site n stack
+// frames above the one that logged it. This is useful when all logging goes through a fixed
+// number of wrapper functions. Where wrappers vary, mark each one with Helper instead. The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithCallerSkip(n int) *Handler {
+	h2 := h.clone()
+	h2.callerSkip = n
 	return h2
 }
 
@@ -102,31 +393,73 @@ func (h *Handler) WithWriter(w io.Writer) *Handler {
 // receiver.
 func (h *Handler) WithAttrLevel(a slog.Attr, level slog.Level) *Handler {
 	h2 := h.clone()
-	if h2.attrLevels == nil {
-		h2.attrLevels = make(map[string][]attrValueLevel)
+	h2.attrLevels = make(map[string][]attrValueLevel, len(h.attrLevels)+1)
+	for k, v := range h.attrLevels {
+		h2.attrLevels[k] = v
 	}
 	// TODO: make sure unique?
-	h2.attrLevels[a.Key] = append(h2.attrLevels[a.Key], attrValueLevel{value: a.Value, level: level})
+	vs := h.attrLevels[a.Key]
+	h2.attrLevels[a.Key] = append(vs[:len(vs):len(vs)], attrValueLevel{value: a.Value, level: level})
 	return h2
 }
 
-// nabled reports whether the handler handles records at the given level.
-func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
-	if len(h.attrLevels) == 0 {
-		return level >= h.minLevel
+// WithAttrLevelFunc returns a new Handler that associates a log level with an attribute key and
+// any value for which match returns true. It is like WithAttrLevel but allows matching a range of
+// values, for example using AtLeast to emit debug records only once a retry attempt counter
+// reaches some threshold. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithAttrLevelFunc(key string, match func(slog.Value) bool, level slog.Level) *Handler {
+	h2 := h.clone()
+	h2.attrLevels = make(map[string][]attrValueLevel, len(h.attrLevels)+1)
+	for k, v := range h.attrLevels {
+		h2.attrLevels[k] = v
 	}
-	return true
+	vs := h.attrLevels[key]
+	h2.attrLevels[key] = append(vs[:len(vs):len(vs)], attrValueLevel{match: match, level: level})
+	return h2
+}
+
+// WithControl returns a new Handler whose minimum log level and attribute levels may be changed
+// at runtime using c. The level held by c replaces the minimum level of the Handler and the
+// attribute levels held by c are consulted in addition to any configured using WithAttrLevel.
+// The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithControl(c *Control) *Handler {
+	h2 := h.clone()
+	h2.control = c
+	return h2
+}
+
+// level returns the minimum level of records that will be emitted, disregarding attribute levels.
+func (h *Handler) level() slog.Level {
+	if h.control != nil {
+		return h.control.Level()
+	}
+	if h.leveler != nil {
+		return h.leveler.Level()
+	}
+	return h.minLevel
+}
+
+// hasAttrLevels reports whether any attribute levels apply to the handler.
+func (h *Handler) hasAttrLevels() bool {
+	return len(h.attrLevels) > 0 || (h.control != nil && h.control.hasAttrLevels())
+}
+
+// Enabled reports whether the handler handles records at the given level. It has no side effects,
+// so it may be called any number of times for each record.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.hasAttrLevels() || level >= h.level()
 }
 
 func (h *Handler) enabledForRecord(_ context.Context, r slog.Record) bool {
-	if r.Level >= h.minLevel {
+	if r.Level >= h.level() {
 		return true
 	}
 	enabled := false
-	for _, a := range h.attrs {
-		if h.attrHasMinLevel(a, r.Level) {
-			return true
-		}
+	h.attrs.each(func(_ []string, a slog.Attr) {
+		enabled = enabled || h.attrHasMinLevel(a, r.Level)
+	})
+	if enabled {
+		return true
 	}
 	r.Attrs(func(a slog.Attr) bool {
 		if enabled {
@@ -139,9 +472,20 @@ func (h *Handler) enabledForRecord(_ context.Context, r slog.Record) bool {
 }
 
 func (h *Handler) attrHasMinLevel(a slog.Attr, level slog.Level) bool {
-	if vs, ok := h.attrLevels[a.Key]; ok {
+	if matchAttrLevels(h.attrLevels, a, level) {
+		return true
+	}
+	if h.control != nil {
+		return matchAttrLevels(h.control.attrLevels(), a, level)
+	}
+	return false
+}
+
+// matchAttrLevels reports whether a matches an attribute level in m that permits records at level.
+func matchAttrLevels(m map[string][]attrValueLevel, a slog.Attr, level slog.Level) bool {
+	if vs, ok := m[a.Key]; ok {
 		for _, v := range vs {
-			if v.value.Equal(a.Value) {
+			if v.matches(a.Value) {
 				if level >= v.level {
 					return true
 				}
@@ -151,103 +495,390 @@ func (h *Handler) attrHasMinLevel(a slog.Attr, level slog.Level) bool {
 	return false
 }
 
+// Handle formats the record and writes it to the handler's writers. It returns any error from the
+// sidecar handler joined with any errors writing to the writers. See WithOnError for a way to
+// observe write errors when logging through a slog.Logger, which discards them.
 func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.isClosed() {
+		return ErrClosed
+	}
 	// Check whether we should log this record
-	if len(h.attrLevels) > 0 {
+	if h.hasAttrLevels() {
 		if !h.enabledForRecord(ctx, r) {
+			h.dropped(DropReasonAttrLevel)
 			return nil
 		}
+	} else if r.Level < h.level() {
+		h.dropped(DropReasonLevel)
+		return nil
 	}
+	r.PC = callerPC(r.PC, h.callerSkip)
 
-	kind := "???"
-	switch r.Level {
-	case slog.LevelError:
-		kind = "error"
-	case slog.LevelWarn:
-		kind = "warn"
-	case slog.LevelInfo:
-		kind = "info"
-	case slog.LevelDebug:
-		kind = "debug"
-	default:
-		kind = fmt.Sprintf("%02d", r.Level)
+	writers := h.writers
+	if writers == nil {
+		w := h.writer
+		if w == nil {
+			w = os.Stdout
+		}
+		single := [1]io.Writer{w}
+		writers = single[:]
+	}
+	if !h.nocolor && !h.colorForAny(len(writers)) {
+		// Format this record with a copy of the handler rather than checking the mode throughout
+		h = h.clone()
+		h.nocolor = true
 	}
 
-	if !h.nocolor {
-		if r.Level >= slog.LevelError {
-			kind = fmt.Sprintf("%s%-5s%s", colorRed, kind, colorReset)
-		} else if r.Level >= slog.LevelWarn {
-			kind = fmt.Sprintf("%s%-5s%s", colorYellow, kind, colorReset)
-		} else if r.Level >= slog.LevelInfo {
-			kind = fmt.Sprintf("%s%-5s%s", colorGreen, kind, colorReset)
+	var sidecarErr error
+	if h.sidecar != nil {
+		sr := r
+		if attrs := AttrsFromContext(ctx); len(attrs) > 0 {
+			sr = r.Clone()
+			sr.AddAttrs(attrs...)
 		}
-	} else {
-		kind = fmt.Sprintf("%-5s", kind)
+		sidecarErr = h.sidecar.Handle(ctx, sr)
+	}
+	if h.sampler != nil && !h.sampled(r) {
+		return sidecarErr
+	}
+
+	kind := levelText(r.Level)
+	var tsBuf [64]byte
+	ts := h.appendTime(tsBuf[:0], r.Time)
+	msg := r.Message
+	if h.replace != nil {
+		var text string
+		kind, text, msg = h.replaceBuiltins(r, kind, string(ts))
+		ts = append(tsBuf[:0], text...)
 	}
 
 	prefix := ""
+	var trailing []slog.Attr
 
-	var b strings.Builder
-	for _, a := range h.attrs {
+	b := newBuffer()
+	defer b.free()
+	if h.goroutine {
+		h.writeAttr(b, nil, slog.Uint64("goroutine", goroutineID()))
+	}
+	static, sw := b, h
+	if h.static != StaticAttrsPlain {
+		static, sw = newBuffer(), h.staticWriter()
+		defer static.free()
+	}
+	h.attrs.each(func(groups []string, a slog.Attr) {
 		// Ignore empty attrs
 		if a.Equal(slog.Attr{}) {
-			continue
+			return
 		}
 
-		if h.prefixName != nil && a.Key == *h.prefixName {
+		if h.prefixName != nil && len(groups) == 0 && a.Key == *h.prefixName {
 			prefix = a.Value.String()
 		}
-		h.writeAttr(&b, a)
+		if h.isJSONGroup(a) {
+			a.Key = qualify(groups, a.Key)
+			trailing = append(trailing, a)
+			return
+		}
+		sw.writeAttr(static, groups, a)
+	})
+	if static != b {
+		h.writeStatic(b, *static)
 	}
-	r.Attrs(func(a slog.Attr) bool {
+	addAttr := func(groups []string, a slog.Attr) {
 		// Ignore empty attrs
 		if a.Equal(slog.Attr{}) {
-			return true
+			return
 		}
-		if h.prefixName != nil && a.Key == *h.prefixName {
+		if h.prefixName != nil && len(groups) == 0 && a.Key == *h.prefixName {
 			prefix = a.Value.String()
-			return true
+			return
 		}
-		h.writeAttr(&b, a)
+		if h.isJSONGroup(a) {
+			a.Key = qualify(groups, a.Key)
+			trailing = append(trailing, a)
+			return
+		}
+		h.writeAttr(b, groups, a)
+	}
+	// Attributes from the context belong to the request rather than the logger so are not
+	// qualified by the handler's groups
+	for _, a := range AttrsFromContext(ctx) {
+		addAttr(nil, a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(h.groups, a)
 		return true
 	})
+	src := ""
+	if h.addSource && r.PC != 0 {
+		src = h.sourceText(r.PC)
+	}
+	h.writeJSONGroups(b, trailing)
 
-	flatattrs := b.String()
-	msg := r.Message
 	if prefix != "" {
 		msg = prefix + ": " + msg
 	}
 
-	w := h.writer
-	if w == nil {
-		w = os.Stdout
+	if h.collapse != nil {
+		id := newBuffer()
+		defer id.free()
+		id.WriteString(kind)
+		id.WriteByte(0)
+		id.WriteString(msg)
+		id.WriteByte(0)
+		id.Write(*b)
+		id.WriteByte(0)
+		id.WriteString(src)
+
+		h.collapse.mu.Lock()
+		defer h.collapse.mu.Unlock()
+		if h.collapse.repeated(h, *id, r) {
+			h.dropped(DropReasonRepeated)
+			return sidecarErr
+		}
+	}
+
+	line := newBuffer()
+	defer line.free()
+	if h.lint != nil {
+		h.lint.check(line, r, h.nocolor)
 	}
-	fmt.Fprintf(w, "%s | %15s | %-40s %s\n", kind, r.Time.Format("15:04:05.000000"), msg, flatattrs)
+	lay := h.columns()
+	width := lay.MessageWidth
+	if h.msgWidth != nil {
+		width = h.msgWidth.width(msg)
+	}
+	t := h.styles()
 
-	return nil
+	sep := ""
+	for _, col := range lay.Columns {
+		switch col {
+		case ColumnLevel:
+			line.WriteString(sep)
+			styled := h.openStyle(line, t.level(r.Level))
+			line.padRight(kind, lay.LevelWidth)
+			closeStyle(line, styled)
+		case ColumnTime:
+			if h.timeLayout == nil || *h.timeLayout != "" {
+				line.WriteString(sep)
+				styled := h.openStyle(line, t.Time)
+				line.padLeft(ts, lay.TimeWidth)
+				closeStyle(line, styled)
+				sep = " | "
+			}
+			if h.delta != nil {
+				line.WriteString(sep)
+				line.padRight(h.delta.text(r.Time), deltaWidth)
+			}
+		case ColumnMessage:
+			if src != "" && h.srcStyle == SourceColumn {
+				line.WriteString(sep)
+				line.padRight(src, sourceWidth)
+				sep = " | "
+			}
+			line.WriteString(sep)
+			styled := h.openStyle(line, t.Message)
+			line.padRight(msg, width)
+			closeStyle(line, styled)
+		case ColumnAttrs:
+			// Each attribute is preceded by a space
+			if sep != "" {
+				line.WriteString(" ")
+			}
+			line.Write(*b)
+			if src != "" && h.srcStyle != SourceColumn {
+				line.WriteString(h.separator())
+				styled := h.openStyle(line, t.Source)
+				line.WriteString(src)
+				closeStyle(line, styled)
+			}
+		}
+		sep = " | "
+	}
+	line.WriteString("\n")
+	accepted, err := h.write(writers, *line, r.Level)
+	if !accepted {
+		h.dropped(DropReasonQueueFull)
+		return sidecarErr
+	}
+	if err != nil {
+		return errors.Join(sidecarErr, err)
+	}
+	return sidecarErr
 }
 
-func (h *Handler) writeAttr(b *strings.Builder, a slog.Attr) {
-	b.WriteString(" ")
-	if !h.nocolor {
-		b.WriteString(colorBlue)
+// openStyle appends the style s, adapted to the handler's color profile, reporting whether it must
+// be followed by a call to closeStyle. Nothing is appended when color is not used.
+func (h *Handler) openStyle(b *buffer, s Style) bool {
+	if h.nocolor || s == "" {
+		return false
 	}
-	b.WriteString(a.Key)
-	if !h.nocolor {
+	b.WriteString(string(h.style(s)))
+	return true
+}
+
+// closeStyle appends a reset directive if a style was opened.
+func closeStyle(b *buffer, opened bool) {
+	if opened {
 		b.WriteString(colorReset)
 	}
-	b.WriteString("=")
+}
+
+// levelText returns the text used to show a level.
+func levelText(level slog.Level) string {
+	switch level {
+	case slog.LevelError:
+		return "error"
+	case slog.LevelWarn:
+		return "warn"
+	case slog.LevelInfo:
+		return "info"
+	case slog.LevelDebug:
+		return "debug"
+	default:
+		return fmt.Sprintf("%02d", level)
+	}
+}
+
+// replaceBuiltins passes the level, time and message of a record to the handler's ReplaceAttr
+// function using the keys slog.LevelKey, slog.TimeKey and slog.MessageKey, returning the text to
+// show for each. A built-in attribute that is dropped is shown as empty. The time is only passed
+// if it is not zero.
+func (h *Handler) replaceBuiltins(r slog.Record, kind, ts string) (string, string, string) {
+	if a := h.replace(nil, slog.Any(slog.LevelKey, r.Level)); a.Equal(slog.Attr{}) {
+		kind = ""
+	} else if l, ok := a.Value.Any().(slog.Level); ok {
+		kind = levelText(l)
+	} else {
+		kind = a.Value.Resolve().String()
+	}
+
+	if !r.Time.IsZero() {
+		a := h.replace(nil, slog.Time(slog.TimeKey, r.Time))
+		v := a.Value.Resolve()
+		switch {
+		case a.Equal(slog.Attr{}):
+			ts = ""
+		case v.Kind() == slog.KindTime:
+			ts = h.formatTime(v.Time())
+		default:
+			ts = v.String()
+		}
+	}
+
+	msg := ""
+	if a := h.replace(nil, slog.String(slog.MessageKey, r.Message)); !a.Equal(slog.Attr{}) {
+		msg = a.Value.Resolve().String()
+	}
+	return kind, ts, msg
+}
+
+// formatTime formats the timestamp of a record. A zero time is formatted as an empty string.
+func (h *Handler) formatTime(t time.Time) string {
+	return string(h.appendTime(nil, t))
+}
+
+// appendTime appends the formatted timestamp of a record to dst. Nothing is appended for a zero
+// time.
+func (h *Handler) appendTime(dst []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return dst
+	}
+	if !h.epoch.IsZero() {
+		d := t.Sub(h.epoch)
+		if d >= 0 {
+			dst = append(dst, '+')
+		}
+		dst = strconv.AppendFloat(dst, d.Seconds(), 'f', 3, 64)
+		return append(dst, 's')
+	}
+	if h.location != nil {
+		t = t.In(h.location)
+	}
+	layout := "15:04:05.000000"
+	if h.timeLayout != nil {
+		layout = *h.timeLayout
+	}
+	if h.showZone {
+		layout += " MST"
+	}
+	if h.isoWeek {
+		dst = append(dst, isoWeekText(t)...)
+		dst = append(dst, ' ')
+	}
+	if h.locale != nil {
+		return append(dst, h.locale.format(t, layout)...)
+	}
+	return t.AppendFormat(dst, layout)
+}
 
+// writeAttr writes a as key=value with its key qualified by groups, as in "req.method". The
+// attributes of a group are written individually, with their keys qualified by the group's key
+// unless it is empty. Attributes are first rewritten by the handler's ReplaceAttr function, if any.
+func (h *Handler) writeAttr(b *buffer, groups []string, a slog.Attr) {
 	rv := a.Value.Resolve()
+	if rv.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range rv.Group() {
+			if !ga.Equal(slog.Attr{}) {
+				h.writeAttr(b, groups, ga)
+			}
+		}
+		return
+	}
+	if h.replace != nil {
+		a.Value = rv
+		a = h.replace(groups, a)
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		rv = a.Value.Resolve()
+		if rv.Kind() == slog.KindGroup {
+			h.writeAttr(b, groups, a)
+			return
+		}
+	}
+	key := qualify(groups, a.Key)
+
+	b.WriteString(h.separator())
+	style, styleValue := h.styles().keyStyle(key)
+	styled := h.openStyle(b, style)
+	b.WriteString(key)
+	if !styleValue {
+		closeStyle(b, styled)
+	}
+	b.WriteString(h.delimiter())
+	if styleValue {
+		// The style also applies to the value
+		defer closeStyle(b, styled)
+	}
+
+	untruncated := h.truncate == nil && len(h.keyTrunc) == 0
 	switch rv.Kind() {
 	case slog.KindFloat64:
 		v := rv.Float64()
 		abs := math.Abs(v)
 		if abs == 0 || 1e-6 <= v && v < 1e21 {
-			b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+			*b = strconv.AppendFloat(*b, v, 'f', -1, 64)
+		} else {
+			*b = strconv.AppendFloat(*b, v, 'g', -1, 64)
+		}
+	case slog.KindInt64:
+		if untruncated {
+			*b = strconv.AppendInt(*b, rv.Int64(), 10)
 		} else {
-			b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+			b.WriteString(h.truncateValue(key, rv.String()))
 		}
+	case slog.KindUint64:
+		if untruncated {
+			*b = strconv.AppendUint(*b, rv.Uint64(), 10)
+		} else {
+			b.WriteString(h.truncateValue(key, rv.String()))
+		}
+	case slog.KindBool:
+		*b = strconv.AppendBool(*b, rv.Bool())
 	case slog.KindDuration:
 		v := rv.Duration()
 		s := v.String()
@@ -259,28 +890,60 @@ func (h *Handler) writeAttr(b *strings.Builder, a slog.Attr) {
 		}
 		b.WriteString(s)
 	case slog.KindTime:
-		v := rv.Time()
-		b.WriteString(v.Format(time.RFC3339Nano))
+		*b = rv.Time().AppendFormat(*b, time.RFC3339Nano)
 	default:
-		b.WriteString(quote(rv.String()))
+		s := h.truncateValue(key, rv.String())
+		if strings.Contains(s, " ") || (h.attrSep != "" && strings.Contains(s, h.attrSep)) {
+			*b = strconv.AppendQuote(*b, s)
+		} else {
+			b.WriteString(s)
+		}
+	}
+}
+
+// truncateValue shortens a value of the attribute with the given key if truncation is configured.
+func (h *Handler) truncateValue(key, s string) string {
+	if t, ok := h.keyTrunc[key]; ok {
+		return t.apply(s)
 	}
+	if h.truncate != nil {
+		return h.truncate.apply(s)
+	}
+	return s
 }
 
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
 	h2 := h.clone()
-	h2.attrs = append(h2.attrs, attrs...)
+	h2.attrs = &attrNode{parent: h.attrs, attrs: attrs}
+	if h.sidecar != nil {
+		h2.sidecar = h.sidecar.WithAttrs(attrs)
+	}
 	return h2
 }
 
+// WithGroup returns a new Handler that qualifies the keys of attributes added later, by WithAttrs
+// or when logging, with name, separated by a dot as in "req.method". Attributes from the context
+// are not qualified.
 func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
 	h2 := h.clone()
-	h2.group = name
+	h2.attrs = &attrNode{parent: h.attrs, group: name}
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	if h.sidecar != nil {
+		h2.sidecar = h.sidecar.WithGroup(name)
+	}
 	return h2
 }
 
-func quote(s string) string {
-	if strings.ContainsAny(s, " ") {
-		return fmt.Sprintf("%q", s)
+// qualify returns key qualified by the names of groups, separated by dots.
+func qualify(groups []string, key string) string {
+	if len(groups) == 0 {
+		return key
 	}
-	return s
+	return strings.Join(groups, ".") + "." + key
 }
@@ -5,16 +5,23 @@ package hlog
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/slogtest"
 	"time"
 )
 
 func TestHandler(t *testing.T) {
-	t.Skipf("Skipped while under development")
 	var buf bytes.Buffer
 	results := func() []map[string]any {
 		var ms []map[string]any
@@ -66,21 +73,876 @@ func parseLogLine(line string) (map[string]any, error) {
 	}
 
 	stime = strings.TrimSpace(stime)
-	ptime, err := time.Parse("15:04:05.000000", stime)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse time segment %s: %v", stime, err)
+	if stime != "" {
+		ptime, err := time.Parse("15:04:05.000000", stime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time segment %s: %v", stime, err)
+		}
+		now := time.Now()
+		m[slog.TimeKey] = ptime.AddDate(now.Year(), int(now.Month())-1, now.Day())
 	}
-	now := time.Now()
-	m[slog.TimeKey] = ptime.AddDate(now.Year(), int(now.Month())-1, now.Day())
-
-	var msg string
-	msg, sline, ok = strings.Cut(sline, "|")
 
+	// The message is padded and followed by the attributes, each preceded by a space
+	msg, attrs, _ := strings.Cut(strings.TrimPrefix(sline, " "), "  ")
 	m[slog.MessageKey] = strings.TrimSpace(msg)
 
-	_ = ptime
-	_ = sline
-	_ = ok
+	attrs = strings.TrimSpace(attrs)
+	for attrs != "" {
+		key, rest, ok := strings.Cut(attrs, "=")
+		if !ok {
+			return nil, fmt.Errorf("failed to find value of attribute in %q", attrs)
+		}
+		var val string
+		if strings.HasPrefix(rest, `"`) {
+			q, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse quoted value of attribute %s: %v", key, err)
+			}
+			val, _ = strconv.Unquote(q)
+			rest = rest[len(q):]
+		} else {
+			val, rest, _ = strings.Cut(rest, " ")
+		}
+		attrs = strings.TrimSpace(rest)
+
+		// Qualified keys are nested in a map for each group
+		group := m
+		names := strings.Split(key, ".")
+		for _, name := range names[:len(names)-1] {
+			sub, ok := group[name].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				group[name] = sub
+			}
+			group = sub
+		}
+		group[names[len(names)-1]] = val
+	}
 
 	return m, nil
 }
+
+func TestWithGroup(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf)
+	logger := slog.New(h).With("service", "api").WithGroup("req").With("method", "GET")
+	logger.Info("handled", "status", 200, slog.Group("user", "id", 7, slog.Group("")))
+
+	want := " service=api req.method=GET req.status=200 req.user.id=7\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+}
+
+func TestWithGoroutineID(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithGoroutineID()
+	slog.New(h).Info("hello")
+
+	want := fmt.Sprintf("goroutine=%d", goroutineID())
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q did not contain %q", buf.String(), want)
+	}
+}
+
+func TestWithJSONGroup(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithJSONGroup("meta")
+	logger := slog.New(h).With(slog.Group("meta", slog.String("region", "eu")))
+	logger.Info("hello", "a", 1, slog.Group("meta", slog.Int("size", 10), slog.String("path", "a<b>")), "b", 2)
+
+	out := strings.TrimSpace(buf.String())
+	want := `a=1 b=2 meta={"region":"eu","size":10,"path":"a<b>"}`
+	if !strings.HasSuffix(out, want) {
+		t.Errorf("got %q, wanted suffix %q", out, want)
+	}
+}
+
+func TestContextWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(new(Handler).WithoutColor().WithWriter(&buf))
+
+	ctx := ContextWithRequestID(context.Background(), "")
+	id, ok := RequestIDFromContext(ctx)
+	if !ok || len(id) != 20 {
+		t.Fatalf("got request id %q, wanted a 20 character id", id)
+	}
+
+	logger.InfoContext(ctx, "hello", "a", 1)
+	want := fmt.Sprintf("%s=%s a=1", RequestIDKey, id)
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("output %q did not contain %q", buf.String(), want)
+	}
+}
+
+func TestNewRequestIDSortable(t *testing.T) {
+	a := NewRequestID()
+	time.Sleep(2 * time.Millisecond)
+	b := NewRequestID()
+	if a >= b {
+		t.Errorf("request id %q generated before %q does not sort before it", a, b)
+	}
+}
+
+func TestSetRequestIDGenerator(t *testing.T) {
+	restore := SetRequestIDGenerator(func() string { return "fixed" })
+	if id := NewRequestID(); id != "fixed" {
+		t.Errorf("got request id %q, wanted the installed generator to be used", id)
+	}
+	restore()
+	if id := NewRequestID(); len(id) != 20 {
+		t.Errorf("got request id %q, wanted a 20 character id once restored", id)
+	}
+}
+
+func TestWithAttrLevelFunc(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithLevel(slog.LevelInfo).
+		WithAttrLevelFunc("attempt", AtLeast(3), slog.LevelDebug)
+	logger := slog.New(h)
+
+	for attempt := 1; attempt <= 4; attempt++ {
+		logger.Debug("retrying", "attempt", attempt)
+	}
+	logger.Debug("retrying", "attempt", "many")
+
+	got := strings.Count(buf.String(), "retrying")
+	if got != 2 {
+		t.Errorf("got %d records, wanted 2:\n%s", got, buf.String())
+	}
+}
+
+func TestWithTimezone(t *testing.T) {
+	loc := time.FixedZone("XYZ", 3*60*60)
+	h := new(Handler).WithTimezone(loc).WithZoneName()
+
+	ts := time.Date(2024, 1, 2, 10, 4, 5, 0, time.UTC)
+	want := "13:04:05.000000 XYZ"
+	if got := h.formatTime(ts); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestWithMessageLint(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(new(Handler).WithoutColor().WithWriter(&buf).WithMessageLint())
+
+	for i := 0; i < 2; i++ {
+		logger.Info(fmt.Sprintf("processed %d items", 1234))
+		logger.Info("processed items", "count", 1234)
+	}
+
+	if got := strings.Count(buf.String(), "lint "); got != 1 {
+		t.Errorf("got %d warnings, wanted 1:\n%s", got, buf.String())
+	}
+}
+
+func TestWithAutoWidth(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(new(Handler).WithoutColor().WithWriter(&buf).WithAutoWidth(10))
+
+	logger.Info("abcdef", "k", 1)
+	logger.Info("abc", "k", 2)
+	logger.Info("much longer than the limit", "k", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{"abcdef  k=1", "abc     k=2", "much longer than the limit  k=3"}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, "| "+want[i]) {
+			t.Errorf("line %d: got %q, wanted suffix %q", i, line, want[i])
+		}
+	}
+}
+
+func TestWithDropStats(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithLevel(slog.LevelInfo).WithDropStats()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		// Enabled has no side effects, so checking it does not count a drop
+		if h.Enabled(ctx, slog.LevelDebug) {
+			t.Fatalf("got enabled at debug level, wanted disabled")
+		}
+	}
+	if err := h.Handle(ctx, slog.NewRecord(time.Time{}, slog.LevelDebug, "dropped by level", 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	h2 := h.WithAttrLevel(slog.String("pkg", "verbose"), slog.LevelDebug)
+	slog.New(h2).Debug("dropped by attr level", "pkg", "other")
+
+	got := h.Stats()
+	if got[DropReasonLevel] != 1 || got[DropReasonAttrLevel] != 1 {
+		t.Errorf("got drop counts %v, wanted one of each reason", got)
+	}
+
+	slog.New(h).Warn("emitted")
+	if got := h.Emitted(); got[slog.LevelWarn] != 1 {
+		t.Errorf("got emitted counts %v, wanted one warning", got)
+	}
+
+	// Neither slog.Logger nor TeeHandler passes on records below the handler's level
+	slog.New(h).Debug("not counted")
+	slog.New(Tee(h, new(Handler).WithWriter(io.Discard).WithLevel(slog.LevelDebug))).Debug("not counted")
+	if got := h.Stats(); got[DropReasonLevel] != 1 {
+		t.Errorf("got %d level drops, wanted only the record passed to Handle directly", got[DropReasonLevel])
+	}
+}
+
+func TestWithTruncation(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithTruncation(4, 4).WithKeyTruncation("path", 0, 8)
+	slog.New(h).Info("hello", "id", "abcdefghijklmnopqrstuvwxyz", "short", "abcdefghi", "ten", "abcdefghij", "path", "/var/lib/data/file.txt")
+
+	for _, want := range []string{"id=abcd…wxyz", "short=abcdefghi", "ten=abcd…ghij", "path=…file.txt"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output %q did not contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestWithSidecar(t *testing.T) {
+	var console, sidecar bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&console).WithSidecar(&sidecar)
+	logger := slog.New(h).With("service", "api")
+	logger.InfoContext(ContextWithAttrs(context.Background(), slog.String("user", "u1")), "hello", "n", 1)
+
+	var m map[string]any
+	err := json.Unmarshal(sidecar.Bytes(), &m)
+	if err != nil {
+		t.Fatalf("parse sidecar output: %v", err)
+	}
+	for k, want := range map[string]any{"msg": "hello", "service": "api", "user": "u1", "n": 1.0} {
+		if m[k] != want {
+			t.Errorf("sidecar %s: got %v, wanted %v", k, m[k], want)
+		}
+	}
+	if _, ok := m[slog.SourceKey]; !ok {
+		t.Errorf("sidecar record has no source")
+	}
+	if !strings.Contains(console.String(), "hello") {
+		t.Errorf("console output %q does not contain the record", console.String())
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	var level slog.LevelVar
+	h := New(&buf, &HandlerOptions{
+		Level:     &level,
+		AddSource: true,
+		NoColor:   true,
+		Prefix:    "component",
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "password" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := slog.New(h)
+
+	logger.Debug("hidden")
+	level.Set(slog.LevelDebug)
+	logger.Debug("shown", "component", "db", "password", "hunter2")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("record below level was emitted: %q", out)
+	}
+	if !strings.Contains(out, "db: shown") {
+		t.Errorf("output missing prefixed message: %q", out)
+	}
+	if strings.Contains(out, "password") {
+		t.Errorf("attribute dropped by ReplaceAttr was emitted: %q", out)
+	}
+	if !strings.Contains(out, " hlog/handler_test.go:") {
+		t.Errorf("output missing source location: %q", out)
+	}
+}
+
+func TestWithLeveler(t *testing.T) {
+	var buf bytes.Buffer
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	logger := slog.New(new(Handler).WithoutColor().WithWriter(&buf).WithLeveler(&level)).With("component", "db")
+
+	logger.Info("first")
+	level.Set(slog.LevelInfo)
+	logger.Info("second")
+
+	out := buf.String()
+	if strings.Contains(out, "first") || !strings.Contains(out, "second") {
+		t.Errorf("level change not applied to derived logger: %q", out)
+	}
+}
+
+func TestWithReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithReplaceAttr(func(groups []string, a slog.Attr) slog.Attr {
+		switch {
+		case a.Key == slog.TimeKey:
+			return slog.Attr{}
+		case a.Key == slog.MessageKey:
+			return slog.String(a.Key, strings.ToUpper(a.Value.String()))
+		case a.Key == "err":
+			return slog.String("error", a.Value.String())
+		case len(groups) > 0 && a.Key == "secret":
+			return slog.Attr{}
+		}
+		return a
+	})
+	slog.New(h).WithGroup("req").Info("done", "err", "boom", "secret", "x")
+
+	want := "info  |                 | DONE                                      req.error=boom\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestWithTimeFormat(t *testing.T) {
+	testCases := []struct {
+		layout string
+		want   string
+	}{
+		{layout: time.RFC3339, want: "info  | 2024-03-05T14:07:09Z | hello"},
+		{layout: "2006-01-02 15:04:05.000", want: "info  | 2024-03-05 14:07:09.123 | hello"},
+		{layout: "", want: "info  | hello"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.layout, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := new(Handler).WithoutColor().WithWriter(&buf).WithTimezone(time.UTC).WithTimeFormat(tc.layout)
+			r := slog.NewRecord(time.Date(2024, 3, 5, 14, 7, 9, 123000000, time.UTC), slog.LevelInfo, "hello", 0)
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(buf.String(), tc.want) {
+				t.Errorf("got %q, wanted prefix %q", buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestWithTimeLocale(t *testing.T) {
+	german := TimeLocale{
+		Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mär"},
+	}
+
+	testCases := []struct {
+		name string
+		h    func(*Handler) *Handler
+		want string
+	}{
+		{name: "english", h: func(h *Handler) *Handler { return h }, want: "Tue 5 Mar 14:07"},
+		{name: "german", h: func(h *Handler) *Handler { return h.WithTimeLocale(german) }, want: "Di 5 Mär 14:07"},
+		{name: "iso week", h: func(h *Handler) *Handler { return h.WithISOWeek() }, want: "W10 Tue 5 Mar 14:07"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := tc.h(new(Handler).WithoutColor().WithWriter(&buf).WithTimezone(time.UTC).WithTimeFormat("Mon 2 Jan 15:04"))
+			r := slog.NewRecord(time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC), slog.LevelInfo, "hello", 0)
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(buf.String(), " "+tc.want+" |") {
+				t.Errorf("got %q, wanted timestamp %q", buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestWithElapsedTime(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithElapsedTime()
+	for _, d := range []time.Duration{12345 * time.Millisecond, -10 * time.Millisecond} {
+		r := slog.NewRecord(h.epoch.Add(d), slog.LevelInfo, "hello", 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	want := "info  |        +12.345s | hello"
+	if lines := strings.Split(buf.String(), "\n"); !strings.HasPrefix(lines[0], want) || !strings.HasPrefix(lines[1], "info  |         -0.010s | hello") {
+		t.Errorf("got %q, wanted elapsed times", buf.String())
+	}
+}
+
+func TestWithDelta(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithTimeFormat("").WithDelta()
+	start := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	for _, d := range []time.Duration{0, 35 * time.Millisecond, 1234567 * time.Microsecond} {
+		r := slog.NewRecord(start.Add(d), slog.LevelInfo, "hello", 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, want := range []string{
+		"info  |            | hello",
+		"info  | Δ 35ms     | hello",
+		"info  | Δ 1.2s     | hello",
+	} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("got line %q, wanted prefix %q", lines[i], want)
+		}
+	}
+}
+
+func TestWithLayout(t *testing.T) {
+	testCases := []struct {
+		name   string
+		layout Layout
+		want   string
+	}{
+		{
+			name:   "narrow",
+			layout: Layout{Columns: []Column{ColumnLevel, ColumnMessage, ColumnAttrs}, MessageWidth: 8},
+			want:   "info  | hello     k=v\n",
+		},
+		{
+			name:   "reordered",
+			layout: Layout{Columns: []Column{ColumnMessage, ColumnLevel, ColumnTime}, LevelWidth: 1, TimeWidth: 10, MessageWidth: 1},
+			want:   "hello | info |   14:07:09\n",
+		},
+		{
+			name:   "attrs first",
+			layout: Layout{Columns: []Column{ColumnAttrs, ColumnMessage}},
+			want:   " k=v | hello                                   \n",
+		},
+		{
+			name:   "default columns",
+			layout: Layout{MessageWidth: 8},
+			want:   "info  |        14:07:09 | hello     k=v\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := new(Handler).WithoutColor().WithWriter(&buf).WithTimeFormat("15:04:05").WithLayout(tc.layout)
+			r := slog.NewRecord(time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC), slog.LevelInfo, "hello", 0)
+			r.AddAttrs(slog.String("k", "v"))
+			if err := h.WithTimezone(time.UTC).Handle(context.Background(), r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tc.want {
+				t.Errorf("got %q, wanted %q", buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWithWriters(t *testing.T) {
+	var a, b bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriters(failingWriter{}, &a, &b)
+	slog.New(h).Info("hello", "k", "v")
+
+	if !strings.Contains(a.String(), "hello") {
+		t.Errorf("got %q, wanted record written after a failing writer", a.String())
+	}
+	if a.String() != b.String() {
+		t.Errorf("got %q and %q, wanted the same output for each writer", a.String(), b.String())
+	}
+}
+
+func TestStripColor(t *testing.T) {
+	var colored, plain bytes.Buffer
+	slog.New(new(Handler).WithColor(ColorAlways).WithWriter(&colored).WithTimeFormat("")).Warn("hello", "k", "v")
+	slog.New(new(Handler).WithoutColor().WithWriter(&plain).WithTimeFormat("")).Warn("hello", "k", "v")
+
+	if got := string(stripColor(colored.Bytes())); got != plain.String() {
+		t.Errorf("got %q, wanted %q", got, plain.String())
+	}
+}
+
+func TestWithStaticAttrs(t *testing.T) {
+	testCases := []struct {
+		name string
+		h    func(*Handler) *Handler
+		want string
+	}{
+		{name: "plain", h: func(h *Handler) *Handler { return h.WithoutColor() }, want: "  svc=api env=prod n=1\n"},
+		{name: "bracket", h: func(h *Handler) *Handler { return h.WithoutColor().WithStaticAttrs(StaticAttrsBracket) }, want: "  [svc=api env=prod] n=1\n"},
+		{name: "dim", h: func(h *Handler) *Handler {
+			return h.WithColor(ColorAlways).WithTheme(Theme{Static: Dim}).WithStaticAttrs(StaticAttrsDim)
+		}, want: "  \x1b[2msvc=api env=prod\x1b[0m n=1\n"},
+		{name: "dim without color", h: func(h *Handler) *Handler { return h.WithoutColor().WithStaticAttrs(StaticAttrsDim) }, want: "  svc=api env=prod n=1\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := tc.h(new(Handler).WithWriter(&buf))
+			slog.New(h).With("svc", "api", "env", "prod").Info("hello", "n", 1)
+			if !strings.HasSuffix(buf.String(), tc.want) {
+				t.Errorf("got %q, wanted suffix %q", buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestWithSource(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithSource(SourceColumn)
+	slog.New(h).Info("hello")
+
+	if !regexp.MustCompile(`^info  \| [0-9:.]+ \| hlog/handler_test.go:\d+ +\| hello `).MatchString(buf.String()) {
+		t.Errorf("got %q, wanted source column", buf.String())
+	}
+}
+
+func TestWithColor(t *testing.T) {
+	testCases := []struct {
+		name  string
+		env   map[string]string
+		h     func(*Handler) *Handler
+		color bool
+	}{
+		{name: "auto", env: map[string]string{"CLICOLOR_FORCE": ""}, h: func(h *Handler) *Handler { return h }, color: false},
+		{name: "always", h: func(h *Handler) *Handler { return h.WithColor(ColorAlways) }, color: true},
+		{name: "never", h: func(h *Handler) *Handler { return h.WithColor(ColorAlways).WithoutColor() }, color: false},
+		{name: "auto forced", env: map[string]string{"CLICOLOR_FORCE": "1"}, h: func(h *Handler) *Handler { return h }, color: true},
+		{name: "auto disabled", env: map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, h: func(h *Handler) *Handler { return h }, color: false},
+		{name: "always disabled", env: map[string]string{"NO_COLOR": "1"}, h: func(h *Handler) *Handler { return h.WithColor(ColorAlways) }, color: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			var buf bytes.Buffer
+			slog.New(tc.h(new(Handler).WithWriter(&buf))).Info("hello", "k", "v")
+			if got := strings.Contains(buf.String(), "\x1b["); got != tc.color {
+				t.Errorf("got color %v, wanted %v: %q", got, tc.color, buf.String())
+			}
+		})
+	}
+}
+
+func TestColorResolvedOnce(t *testing.T) {
+	t.Setenv("CLICOLOR_FORCE", "1")
+	var buf bytes.Buffer
+	h := new(Handler).WithWriter(&buf)
+
+	// The decision is made when the writer is set, not for each record
+	t.Setenv("NO_COLOR", "1")
+	slog.New(h).Info("hello", "k", "v")
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("got no color, wanted color decided when the writer was set: %q", buf.String())
+	}
+
+	buf.Reset()
+	slog.New(h.WithColor(ColorAuto)).Info("hello", "k", "v")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("got color, wanted color decided again when the mode was set: %q", buf.String())
+	}
+}
+
+func TestWithTheme(t *testing.T) {
+	theme := LightTheme()
+	theme.Message = Cyan
+	theme.Keys = map[string]Style{"err": Red.Bold()}
+
+	var buf bytes.Buffer
+	h := new(Handler).WithColor(ColorAlways).WithWriter(&buf).WithTheme(theme)
+	slog.New(h).Warn("failed", "err", "boom", "n", 1)
+
+	got := buf.String()
+	for _, want := range []string{
+		"\x1b[35mwarn \x1b[0m",
+		"\x1b[36mfailed",
+		"\x1b[1;31merr=boom\x1b[0m",
+		"\x1b[34mn\x1b[0m=1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got %q, wanted it to contain %q", got, want)
+		}
+	}
+}
+
+type closeRecorder struct {
+	bytes.Buffer
+	events []string
+}
+
+func (c *closeRecorder) Flush() error { c.events = append(c.events, "flush"); return nil }
+func (c *closeRecorder) Close() error { c.events = append(c.events, "close"); return nil }
+
+func TestClose(t *testing.T) {
+	var w closeRecorder
+	h := new(Handler).WithoutColor().WithWriter(&w).WithCloser(&w)
+	logger := slog.New(h).With("k", "v")
+	logger.Info("before")
+
+	for i := 0; i < 2; i++ {
+		if err := h.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+	if got := strings.Join(w.events, ","); got != "flush,close" {
+		t.Errorf("got events %q, wanted flush and close once", got)
+	}
+
+	logger.Info("after")
+	if strings.Contains(w.String(), "after") {
+		t.Errorf("derived handler wrote after close: %q", w.String())
+	}
+}
+
+func TestCloseChain(t *testing.T) {
+	for _, closeParentFirst := range []bool{false, true} {
+		var w, f1, f2 closeRecorder
+		h1 := new(Handler).WithoutColor().WithWriter(&w).WithCloser(&f1)
+		h2 := h1.WithCloser(&f2)
+
+		first, second := h2, h1
+		if closeParentFirst {
+			first, second = h1, h2
+		}
+		if err := first.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if err := second.Close(); err != nil {
+			t.Fatalf("second Close: %v", err)
+		}
+		for name, c := range map[string]*closeRecorder{"f1": &f1, "f2": &f2} {
+			if got := strings.Join(c.events, ","); got != "flush,close" {
+				t.Errorf("parent first %v: got %s events %q, wanted flush and close once", closeParentFirst, name, got)
+			}
+		}
+
+		slog.New(h1).Info("after")
+		slog.New(h2).Info("after")
+		if w.Len() != 0 {
+			t.Errorf("parent first %v: got output after close: %q", closeParentFirst, w.String())
+		}
+	}
+}
+
+func TestWithColorProfile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		env     map[string]string
+		profile ColorProfile
+		want    string
+	}{
+		{name: "truecolor", profile: ProfileTrueColor, want: "\x1b[1;38;2;255;135;0mk"},
+		{name: "256", profile: Profile256, want: "\x1b[1;38;5;208mk"},
+		{name: "basic", profile: ProfileBasic, want: "\x1b[1;33mk"},
+		{name: "auto truecolor", env: map[string]string{"COLORTERM": "truecolor"}, want: "\x1b[1;38;2;255;135;0mk"},
+		{name: "auto 256", env: map[string]string{"COLORTERM": "", "TERM": "xterm-256color"}, want: "\x1b[1;38;5;208mk"},
+		{name: "auto basic", env: map[string]string{"COLORTERM": "", "TERM": "xterm"}, want: "\x1b[1;33mk"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			theme := DefaultTheme()
+			theme.Key = RGB(255, 135, 0).Bold()
+
+			var buf bytes.Buffer
+			h := new(Handler).WithColor(ColorAlways).WithWriter(&buf).WithTheme(theme).WithColorProfile(tc.profile)
+			slog.New(h).Info("hello", "k", "v")
+			if !strings.Contains(buf.String(), tc.want) {
+				t.Errorf("got %q, wanted it to contain %q", buf.String(), tc.want)
+			}
+		})
+	}
+
+	// The profile is detected when the handler is configured rather than for each record
+	t.Setenv("COLORTERM", "truecolor")
+	var buf bytes.Buffer
+	theme := DefaultTheme()
+	theme.Key = RGB(255, 135, 0).Bold()
+	h := new(Handler).WithColor(ColorAlways).WithWriter(&buf).WithTheme(theme)
+	t.Setenv("COLORTERM", "")
+	t.Setenv("TERM", "xterm")
+	slog.New(h).Info("hello", "k", "v")
+	if want := "\x1b[1;38;2;255;135;0mk"; !strings.Contains(buf.String(), want) {
+		t.Errorf("got %q, wanted it to contain %q", buf.String(), want)
+	}
+}
+
+// overlapWriter is a writer that does not synchronize writes and records whether any overlapped.
+type overlapWriter struct {
+	buf     bytes.Buffer
+	writing atomic.Bool
+	overlap atomic.Bool
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	if !w.writing.CompareAndSwap(false, true) {
+		w.overlap.Store(true)
+		return len(p), nil
+	}
+	defer w.writing.Store(false)
+	time.Sleep(10 * time.Microsecond)
+	return w.buf.Write(p)
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	w := new(overlapWriter)
+	base := new(Handler).WithoutColor().WithWriter(w).WithTimeFormat("")
+	handlers := []*Handler{base, base.WithWriter(w), base.WithLevel(slog.LevelDebug).WithWriter(w)}
+
+	const records = 50
+	var wg sync.WaitGroup
+	for i, h := range handlers {
+		wg.Add(1)
+		go func(i int, logger *slog.Logger) {
+			defer wg.Done()
+			for n := 0; n < records; n++ {
+				logger.Info("concurrent", "handler", i, "n", n)
+			}
+		}(i, slog.New(h))
+	}
+	wg.Wait()
+
+	if w.overlap.Load() {
+		t.Errorf("writes overlapped")
+	}
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\n"), "\n")
+	if len(lines) != records*len(handlers) {
+		t.Fatalf("got %d lines, wanted %d", len(lines), records*len(handlers))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "info  | concurrent") {
+			t.Errorf("got malformed line %q", line)
+		}
+	}
+}
+
+// blockingWriter is a writer whose writes wait until release is closed.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	close(w.started)
+	<-w.release
+	return len(p), nil
+}
+
+func TestBlockedWriterDoesNotBlockOthers(t *testing.T) {
+	blocked := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(blocked.release)
+	go slog.New(new(Handler).WithWriter(blocked)).Info("stuck")
+	<-blocked.started
+
+	// Writes to other writers, alone or alongside others, must not wait for the blocked one
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			slog.New(new(Handler).WithWriter(new(bytes.Buffer))).Info("free")
+			slog.New(new(Handler).WithWriters(new(bytes.Buffer), io.Discard)).Info("free")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writes to other writers blocked")
+	}
+}
+
+// errWriter is a writer that always fails with err.
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+// shortWriter is a writer that accepts only part of each write.
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) {
+	return len(p) / 2, nil
+}
+
+func TestWithOnError(t *testing.T) {
+	errFull := errors.New("disk full")
+	var buf bytes.Buffer
+	var reported []error
+	h := new(Handler).WithoutColor().WithWriters(&buf, errWriter{err: errFull}, shortWriter{}).WithDropStats().WithOnError(func(err error) {
+		reported = append(reported, err)
+	})
+
+	err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hello", 0))
+	if !errors.Is(err, errFull) || !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("got error %v, wanted it to wrap %v and %v", err, errFull, io.ErrShortWrite)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], errFull) {
+		t.Errorf("got reported errors %v, wanted one wrapping %v", reported, errFull)
+	}
+	if !strings.Contains(buf.String(), "hello") {
+		t.Errorf("got output %q, wanted the record to be written to the working writer", buf.String())
+	}
+	if got := h.Stats()[DropReasonWriteFailed]; got != 1 || len(h.Emitted()) != 0 {
+		t.Errorf("got %d records dropped and emitted records %v, wanted one dropped and none emitted", got, h.Emitted())
+	}
+
+	reported = nil
+	if err := h.WithWriter(&buf).Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "hello", 0)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(reported) != 0 {
+		t.Errorf("got reported errors %v, wanted none", reported)
+	}
+}
+
+func TestWithAttrSeparator(t *testing.T) {
+	testCases := []struct {
+		name string
+		h    *Handler
+		want string
+	}{
+		{
+			name: "tab",
+			h:    new(Handler).WithAttrSeparator("\t"),
+			want: "\tpkg=db\tk=v\tnote=\"a\\tb\"",
+		},
+		{
+			name: "colon",
+			h:    new(Handler).WithKeyValueDelimiter(": "),
+			want: " pkg: db k: v note: a\tb",
+		},
+		{
+			name: "bracketed",
+			h:    new(Handler).WithAttrSeparator(", ").WithStaticAttrs(StaticAttrsBracket),
+			want: ", [pkg=db], k=v, note=a\tb",
+		},
+		{
+			name: "default",
+			h:    new(Handler).WithAttrSeparator("\t").WithAttrSeparator(""),
+			want: " pkg=db k=v note=a\tb",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(tc.h.WithoutColor().WithWriter(&buf).WithTimeFormat("")).With("pkg", "db").Info("hello", "k", "v", "note", "a\tb")
+			if got := strings.TrimSuffix(buf.String(), "\n"); !strings.HasSuffix(got, tc.want) {
+				t.Errorf("got %q, wanted it to end with %q", got, tc.want)
+			}
+		})
+	}
+}
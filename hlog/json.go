@@ -0,0 +1,116 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// isJSONGroup reports whether a is a group that should be rendered as a trailing JSON object.
+func (h *Handler) isJSONGroup(a slog.Attr) bool {
+	if len(h.jsonGroups) == 0 || a.Value.Kind() != slog.KindGroup {
+		return false
+	}
+	for _, name := range h.jsonGroups {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSONGroups writes each group in attrs as a key followed by a compact JSON object, merging
+// groups that share a key. Groups are written in the order that their keys first appear.
+func (h *Handler) writeJSONGroups(b *buffer, attrs []slog.Attr) {
+	if len(attrs) == 0 {
+		return
+	}
+
+	var keys []string
+	merged := map[string][]slog.Attr{}
+	for _, a := range attrs {
+		if _, ok := merged[a.Key]; !ok {
+			keys = append(keys, a.Key)
+		}
+		merged[a.Key] = append(merged[a.Key], a.Value.Group()...)
+	}
+
+	for _, k := range keys {
+		b.WriteString(h.separator())
+		styled := h.openStyle(b, h.styles().Key)
+		b.WriteString(k)
+		closeStyle(b, styled)
+		b.WriteString(h.delimiter())
+		*b = appendJSONObject(*b, merged[k])
+	}
+}
+
+// appendJSONObject appends attrs to buf as a JSON object. Empty attributes are omitted.
+func appendJSONObject(buf []byte, attrs []slog.Attr) []byte {
+	buf = append(buf, '{')
+	first := true
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) || (a.Value.Kind() == slog.KindGroup && len(a.Value.Group()) == 0) {
+			continue
+		}
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = appendJSONString(buf, a.Key)
+		buf = append(buf, ':')
+		buf = appendJSONValue(buf, a.Value)
+	}
+	return append(buf, '}')
+}
+
+// appendJSONValue appends the JSON representation of v to buf. Durations and times are written
+// as strings in their human readable forms.
+func appendJSONValue(buf []byte, v slog.Value) []byte {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return appendJSONString(buf, v.String())
+	case slog.KindInt64:
+		return strconv.AppendInt(buf, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		return appendJSONMarshal(buf, v.Float64())
+	case slog.KindBool:
+		return strconv.AppendBool(buf, v.Bool())
+	case slog.KindDuration:
+		return appendJSONString(buf, v.Duration().String())
+	case slog.KindTime:
+		return appendJSONString(buf, v.Time().Format(time.RFC3339Nano))
+	case slog.KindGroup:
+		return appendJSONObject(buf, v.Group())
+	default:
+		if err, ok := v.Any().(error); ok {
+			return appendJSONString(buf, err.Error())
+		}
+		return appendJSONMarshal(buf, v.Any())
+	}
+}
+
+// appendJSONMarshal appends the JSON encoding of v, falling back to a string if v cannot be
+// encoded. Unlike json.Marshal it does not escape HTML characters, which would hinder readability.
+func appendJSONMarshal(buf []byte, v any) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return appendJSONMarshal(buf, slog.AnyValue(v).String())
+	}
+	return append(buf, bytes.TrimSuffix(b.Bytes(), []byte{'\n'})...)
+}
+
+func appendJSONString(buf []byte, s string) []byte {
+	return appendJSONMarshal(buf, s)
+}
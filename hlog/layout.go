@@ -0,0 +1,86 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+// Column identifies a column of the output written by a Handler.
+type Column int
+
+const (
+	// ColumnLevel shows the level of each record.
+	ColumnLevel Column = iota
+
+	// ColumnTime shows the time of each record, followed by the time since the previous record
+	// when enabled using WithDelta.
+	ColumnTime
+
+	// ColumnMessage shows the message of each record, preceded by the source location of the
+	// record when shown as a column using WithSource.
+	ColumnMessage
+
+	// ColumnAttrs shows the attributes of each record, followed by the source location of the
+	// record when shown as a suffix using WithSource.
+	ColumnAttrs
+)
+
+// Layout determines which columns a Handler writes for each record, in what order and how wide
+// they are. Columns are padded to their widths but never truncated.
+type Layout struct {
+	// Columns lists the columns to write, in order. Columns are separated by a vertical bar,
+	// except that attributes are separated from the preceding column by a space. An empty list
+	// means the default columns.
+	Columns []Column
+
+	LevelWidth   int // width of the level column; zero means the default of 5
+	TimeWidth    int // width of the time column, which is aligned to the right; zero means the default of 15
+	MessageWidth int // width of the message column unless set using WithAutoWidth; zero means the default of 40
+}
+
+// DefaultLayout returns the layout used by a Handler unless changed using WithLayout.
+func DefaultLayout() Layout {
+	return Layout{
+		Columns:      []Column{ColumnLevel, ColumnTime, ColumnMessage, ColumnAttrs},
+		LevelWidth:   5,
+		TimeWidth:    15,
+		MessageWidth: 40,
+	}
+}
+
+var defaultLayout = DefaultLayout()
+
+// WithLayout returns a new Handler that writes the columns described by l. For example, on a
+// narrow terminal the time column might be dropped and the message column shortened:
+//
+//	h = h.WithLayout(hlog.Layout{
+//		Columns:      []hlog.Column{hlog.ColumnLevel, hlog.ColumnMessage, hlog.ColumnAttrs},
+//		MessageWidth: 24,
+//	})
+//
+// The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithLayout(l Layout) *Handler {
+	if len(l.Columns) == 0 {
+		l.Columns = defaultLayout.Columns
+	}
+	l.Columns = append([]Column(nil), l.Columns...)
+	if l.LevelWidth == 0 {
+		l.LevelWidth = defaultLayout.LevelWidth
+	}
+	if l.TimeWidth == 0 {
+		l.TimeWidth = defaultLayout.TimeWidth
+	}
+	if l.MessageWidth == 0 {
+		l.MessageWidth = defaultLayout.MessageWidth
+	}
+
+	h2 := h.clone()
+	h2.layout = &l
+	return h2
+}
+
+// columns returns the layout used by the handler.
+func (h *Handler) columns() *Layout {
+	if h.layout == nil {
+		return &defaultLayout
+	}
+	return h.layout
+}
@@ -0,0 +1,76 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// messageLinter warns about log messages that embed formatted values. It is shared by all
+// handlers derived from the handler that enabled it so that each call site is reported once.
+type messageLinter struct {
+	seen sync.Map // program counters of call sites already reported
+}
+
+// WithMessageLint returns a new Handler that warns when a record's message appears to contain
+// formatted values, such as numbers, identifiers or key=value pairs, which are better logged as
+// attributes so they can be searched and filtered. Each call site is warned about once, on the
+// line before the first record it logs. The check is heuristic and intended for use during
+// development only. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithMessageLint() *Handler {
+	h2 := h.clone()
+	h2.lint = &messageLinter{}
+	return h2
+}
+
+// check writes a warning to w if r's message contains formatted values and r's call site has not
+// been warned about before.
+func (l *messageLinter) check(w io.Writer, r slog.Record, nocolor bool) {
+	if !hasFormattedValues(r.Message) {
+		return
+	}
+	if _, loaded := l.seen.LoadOrStore(r.PC, struct{}{}); loaded {
+		return
+	}
+
+	source := "unknown"
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		source = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+	}
+
+	kind := "lint "
+	if !nocolor {
+		kind = Yellow.Bold().wrap(kind)
+	}
+	fmt.Fprintf(w, "%s | %15s | %-40s source=%s\n", kind, "", "message contains formatted values, use attributes instead", source)
+}
+
+// hasFormattedValues reports whether msg appears to contain values that were formatted into it,
+// such as numbers with more than one digit, key=value pairs or fmt formatting errors.
+func hasFormattedValues(msg string) bool {
+	if strings.Contains(msg, "%!") {
+		return true
+	}
+	for _, word := range strings.Fields(msg) {
+		if i := strings.IndexByte(word, '='); i > 0 && i < len(word)-1 {
+			return true
+		}
+		digits := 0
+		for _, c := range word {
+			if c >= '0' && c <= '9' {
+				digits++
+			}
+		}
+		if digits > 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import "log/slog"
+
+// AtLeast returns a function for use with WithAttrLevelFunc that matches numeric values greater
+// than or equal to n. Values that are not numeric never match.
+func AtLeast(n float64) func(slog.Value) bool {
+	return func(v slog.Value) bool {
+		f, ok := numericValue(v)
+		return ok && f >= n
+	}
+}
+
+// GreaterThan returns a function for use with WithAttrLevelFunc that matches numeric values
+// strictly greater than n. Values that are not numeric never match.
+func GreaterThan(n float64) func(slog.Value) bool {
+	return func(v slog.Value) bool {
+		f, ok := numericValue(v)
+		return ok && f > n
+	}
+}
+
+// Between returns a function for use with WithAttrLevelFunc that matches numeric values in the
+// inclusive range lo to hi. Values that are not numeric never match.
+func Between(lo, hi float64) func(slog.Value) bool {
+	return func(v slog.Value) bool {
+		f, ok := numericValue(v)
+		return ok && f >= lo && f <= hi
+	}
+}
+
+// numericValue returns v as a float64 if it holds an integer or floating point number.
+func numericValue(v slog.Value) (float64, bool) {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return float64(v.Int64()), true
+	case slog.KindUint64:
+		return float64(v.Uint64()), true
+	case slog.KindFloat64:
+		return v.Float64(), true
+	default:
+		return 0, false
+	}
+}
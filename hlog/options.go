@@ -0,0 +1,117 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"io"
+	"log/slog"
+	"path"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// HandlerOptions are options for a Handler created by New. A zero HandlerOptions consists entirely
+// of default values. The first three fields have the same meaning as those of slog.HandlerOptions.
+type HandlerOptions struct {
+	// Level is the minimum level of records that are emitted. If nil, records at slog.LevelInfo
+	// and above are emitted. A slog.LevelVar may be used to change the level while the Handler is
+	// in use.
+	Level slog.Leveler
+
+	// AddSource causes the Handler to include the file name and line number of the code that
+	// emitted each record, as for WithSource.
+	AddSource bool
+
+	// SourceStyle determines how the source location is shown when AddSource is set.
+	SourceStyle SourceStyle
+
+	// ReplaceAttr, if not nil, is called to rewrite each attribute before it is written, as
+	// described for WithReplaceAttr.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+	// NoColor disables the use of ANSI color directives, as for WithoutColor. It takes
+	// precedence over Color.
+	NoColor bool
+
+	// Color determines when ANSI color directives are used, as for WithColor.
+	Color ColorMode
+
+	// Prefix names an attribute to be written as a prefix to the log message, as for WithPrefix.
+	Prefix string
+}
+
+// New returns a Handler that writes to w, configured by opts, which may be nil. It is equivalent
+// to creating a Handler by chaining the corresponding With methods, without the cost of cloning
+// the Handler for each option.
+func New(w io.Writer, opts *HandlerOptions) *Handler {
+	h := &Handler{writer: w, writeMu: []*sync.Mutex{newWriterLock(w)}}
+	if opts == nil {
+		h.resolveColor()
+		return h
+	}
+	h.leveler = opts.Level
+	h.addSource = opts.AddSource
+	h.srcStyle = opts.SourceStyle
+	h.replace = opts.ReplaceAttr
+	h.color = opts.Color
+	if opts.NoColor {
+		h.color = ColorNever
+	}
+	h.nocolor = h.color == ColorNever
+	if opts.Prefix != "" {
+		prefix := opts.Prefix
+		h.prefixName = &prefix
+	}
+	h.resolveColor()
+	return h
+}
+
+// SourceStyle determines how a Handler shows the source location of records.
+type SourceStyle int
+
+const (
+	// SourceSuffix shows the source location at the end of the line, dimmed when color is used.
+	SourceSuffix SourceStyle = iota
+
+	// SourceColumn shows the source location in a column between the time and the message.
+	SourceColumn
+)
+
+// sourceWidth is the minimum width of the source column.
+const sourceWidth = 24
+
+// sourceText returns the text used to show the source location of the code at pc, after passing it
+// to the handler's ReplaceAttr function, if any.
+func (h *Handler) sourceText(pc uintptr) string {
+	src := source(pc)
+	if h.replace == nil {
+		return formatSource(src)
+	}
+	a := h.replace(nil, slog.Any(slog.SourceKey, src))
+	if a.Equal(slog.Attr{}) {
+		return ""
+	}
+	v := a.Value.Resolve()
+	if s, ok := v.Any().(*slog.Source); ok {
+		return formatSource(s)
+	}
+	return v.String()
+}
+
+// source returns the source location of the code at pc.
+func source(pc uintptr) *slog.Source {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+}
+
+// formatSource returns the file name and line number of a source location, with the file name
+// shortened to its final directory and base name, as in "hlog/handler.go:42".
+func formatSource(s *slog.Source) string {
+	if s.File == "" {
+		return ""
+	}
+	dir, file := path.Split(s.File)
+	return path.Join(path.Base(dir), file) + ":" + strconv.Itoa(s.Line)
+}
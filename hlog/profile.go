@@ -0,0 +1,194 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ColorProfile describes the range of colors supported by a terminal.
+type ColorProfile int
+
+const (
+	// ProfileAuto detects the profile of the terminal from the COLORTERM and TERM environment
+	// variables when the writers, color mode or profile of a Handler are set. This is the
+	// default. A COLORTERM of "truecolor" or "24bit" selects ProfileTrueColor, a TERM containing
+	// "256color" selects Profile256 and anything else selects ProfileBasic.
+	ProfileAuto ColorProfile = iota
+
+	// ProfileBasic supports the sixteen standard ANSI colors.
+	ProfileBasic
+
+	// Profile256 supports the 256 colors of the xterm palette.
+	Profile256
+
+	// ProfileTrueColor supports 24-bit colors.
+	ProfileTrueColor
+)
+
+// detectColorProfile returns the color profile of the terminal described by the environment.
+func detectColorProfile() ColorProfile {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ProfileTrueColor
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return Profile256
+	}
+	return ProfileBasic
+}
+
+// Color256 returns a style using color n of the 256 color xterm palette for the foreground. It is
+// shown using the nearest standard color on terminals that only support ProfileBasic.
+func Color256(n uint8) Style {
+	return Style(fmt.Sprintf("\x1b[38;5;%dm", n))
+}
+
+// RGB returns a style using a 24-bit color for the foreground. It is shown using the nearest
+// available color on terminals that do not support ProfileTrueColor.
+func RGB(r, g, b uint8) Style {
+	return Style(fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, b))
+}
+
+// WithColorProfile returns a new Handler that limits the colors of its theme to those supported by
+// the profile p, replacing each color that is not supported with the nearest one that is. The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithColorProfile(p ColorProfile) *Handler {
+	h2 := h.clone()
+	if p == ProfileAuto {
+		p = detectColorProfile()
+	}
+	h2.profile = p
+	return h2
+}
+
+// autoProfile is the color profile used by handlers whose profile was never detected.
+var autoProfile = sync.OnceValue(detectColorProfile)
+
+// style returns s adapted to the handler's color profile.
+func (h *Handler) style(s Style) Style {
+	p := h.profile
+	if p == ProfileAuto {
+		p = autoProfile()
+	}
+	return s.degrade(p)
+}
+
+// degrade returns the style with any colors that are not supported by the profile p replaced by the
+// nearest colors that are. Styles that are not SGR sequences are returned unchanged.
+func (s Style) degrade(p ColorProfile) Style {
+	if p == ProfileTrueColor || !strings.HasPrefix(string(s), "\x1b[") || !strings.HasSuffix(string(s), "m") {
+		return s
+	}
+	if !strings.Contains(string(s), "8;") {
+		// Only extended colors, introduced by 38 or 48, need adapting
+		return s
+	}
+	params := strings.Split(string(s[2:len(s)-1]), ";")
+	out := make([]string, 0, len(params))
+	changed := false
+	for i := 0; i < len(params); i++ {
+		if (params[i] != "38" && params[i] != "48") || i+1 >= len(params) {
+			out = append(out, params[i])
+			continue
+		}
+		base := 30
+		if params[i] == "48" {
+			base = 40
+		}
+
+		var r, g, b int
+		switch {
+		case params[i+1] == "2" && i+4 < len(params):
+			r, g, b = atoi(params[i+2]), atoi(params[i+3]), atoi(params[i+4])
+			i += 4
+			changed = true
+			if p == Profile256 {
+				out = append(out, params[i-4], "5", strconv.Itoa(nearest256(r, g, b)))
+				continue
+			}
+		case params[i+1] == "5" && i+2 < len(params) && p == ProfileBasic:
+			r, g, b = rgb256(atoi(params[i+2]))
+			i += 2
+			changed = true
+		default:
+			out = append(out, params[i])
+			continue
+		}
+
+		n := nearestBasic(r, g, b)
+		if n >= 8 {
+			base += 60 // bright colors
+			n -= 8
+		}
+		out = append(out, strconv.Itoa(base+n))
+	}
+	if !changed {
+		return s
+	}
+	return Style("\x1b[" + strings.Join(out, ";") + "m")
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// basicColors holds the xterm defaults for the sixteen standard colors.
+var basicColors = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels holds the intensities of the 6x6x6 color cube of the 256 color palette.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// rgb256 returns the components of color n of the 256 color palette.
+func rgb256(n int) (r, g, b int) {
+	switch {
+	case n < 16:
+		c := basicColors[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		v := 8 + 10*(n-232)
+		return v, v, v
+	}
+}
+
+// nearest256 returns the color of the 256 color palette, excluding the standard colors whose
+// appearance varies between terminals, that is nearest to the given components.
+func nearest256(r, g, b int) int {
+	best, bestDist := 16, -1
+	for n := 16; n < 256; n++ {
+		if d := distance(r, g, b, n); bestDist < 0 || d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// nearestBasic returns the index of the standard color nearest to the given components.
+func nearestBasic(r, g, b int) int {
+	best, bestDist := 0, -1
+	for n := 0; n < 16; n++ {
+		if d := distance(r, g, b, n); bestDist < 0 || d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// distance returns the squared distance between the given components and color n of the 256 color
+// palette.
+func distance(r, g, b, n int) int {
+	r2, g2, b2 := rgb256(n)
+	return (r-r2)*(r-r2) + (g-g2)*(g-g2) + (b-b2)*(b-b2)
+}
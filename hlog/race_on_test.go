@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package hlog
+
+// raceEnabled reports whether the race detector is on. It makes sync.Pool drop items at random,
+// so allocation counts are not reliable.
+const raceEnabled = true
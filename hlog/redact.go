@@ -0,0 +1,31 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import "log/slog"
+
+// Redacted is the text logged in place of the value of a Secret.
+const Redacted = "[redacted]"
+
+// Secret is a string that must never appear in logs. It implements slog.LogValuer and
+// fmt.Stringer, logging and formatting as Redacted, so secrets are redacted by any slog handler
+// and when formatted with the %v or %s verbs. Use Reveal to obtain the underlying value.
+type Secret string
+
+var _ slog.LogValuer = Secret("")
+
+// LogValue implements slog.LogValuer.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// String implements fmt.Stringer.
+func (s Secret) String() string {
+	return Redacted
+}
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
@@ -0,0 +1,64 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"log/slog"
+	prand "math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/iand/pontium/internal/crockford"
+)
+
+// RequestIDKey is the attribute key used for request ids installed by ContextWithRequestID.
+const RequestIDKey = "request_id"
+
+// requestIDGenerator holds the function installed by SetRequestIDGenerator, if any.
+var requestIDGenerator atomic.Pointer[func() string]
+
+// SetRequestIDGenerator replaces the function used by NewRequestID to generate ids with f. It
+// returns a function that restores the previous generator. It is intended for testing, to make
+// logs containing request ids deterministic, and affects all request ids generated in the process.
+func SetRequestIDGenerator(f func() string) (restore func()) {
+	prev := requestIDGenerator.Swap(&f)
+	return func() {
+		requestIDGenerator.Store(prev)
+	}
+}
+
+// NewRequestID returns a new randomly generated request id. Ids are 20 characters long, in the
+// style of a ULID: the first 10 characters encode the current time in milliseconds and the
+// remaining 10 encode 50 random bits, both in Crockford's base32. Ids generated in different
+// milliseconds therefore sort in the order they were generated. The generator may be replaced
+// for testing using SetRequestIDGenerator.
+func NewRequestID() string {
+	if f := requestIDGenerator.Load(); f != nil {
+		return (*f)()
+	}
+	var id [20]byte
+	crockford.Encode(id[:10], uint64(time.Now().UnixMilli()))
+	crockford.Encode(id[10:], prand.Uint64())
+	return string(id[:])
+}
+
+type ctxRequestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id. The id is also added to the
+// context's attributes using the key RequestIDKey so that it is included in every record logged
+// with the context. If id is empty a new id is generated using NewRequestID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = NewRequestID()
+	}
+	ctx = context.WithValue(ctx, ctxRequestIDKey{}, id)
+	return ContextWithAttrs(ctx, slog.String(RequestIDKey, id))
+}
+
+// RequestIDFromContext returns the request id carried by ctx, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxRequestIDKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,142 @@
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// RingBuffer is an io.Writer that retains the most recent lines written to it, discarding older
+// lines once its capacity is reached. It is intended to be used as the writer of a Handler in
+// services that normally log nowhere but need recent history when something goes wrong. The
+// retained lines can be dumped on demand by calling Dump, over HTTP or in response to a signal.
+//
+// A RingBuffer is safe for concurrent use.
+type RingBuffer struct {
+	mu      sync.Mutex
+	lines   [][]byte
+	next    int    // index of the slot that will hold the next line
+	full    bool   // whether every slot holds a line
+	partial []byte // data written since the last newline
+}
+
+var _ io.Writer = (*RingBuffer)(nil)
+
+// NewRingBuffer returns a RingBuffer that retains the last n lines written to it.
+func NewRingBuffer(n int) *RingBuffer {
+	if n < 1 {
+		n = 1
+	}
+	return &RingBuffer{
+		lines: make([][]byte, n),
+	}
+}
+
+// Write appends p to the buffer. Each complete line in p is retained as a separate entry, evicting
+// the oldest line if the buffer is full. A trailing incomplete line is held until it is completed
+// by a later write.
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i == -1 {
+			r.partial = append(r.partial, p...)
+			break
+		}
+		line := append(r.partial, p[:i+1]...)
+		r.partial = nil
+		r.add(line)
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (r *RingBuffer) add(line []byte) {
+	// Reuse the evicted line's storage where possible
+	r.lines[r.next] = append(r.lines[r.next][:0], line...)
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Lines returns the retained lines, oldest first, without their trailing newlines.
+func (r *RingBuffer) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lines []string
+	r.each(func(line []byte) {
+		lines = append(lines, string(bytes.TrimSuffix(line, []byte{'\n'})))
+	})
+	return lines
+}
+
+// Len returns the number of retained lines.
+func (r *RingBuffer) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full {
+		return len(r.lines)
+	}
+	return r.next
+}
+
+// Dump writes the retained lines to w, oldest first. The buffer is not modified.
+func (r *RingBuffer) Dump(w io.Writer) error {
+	r.mu.Lock()
+	var buf bytes.Buffer
+	r.each(func(line []byte) {
+		buf.Write(line)
+	})
+	r.mu.Unlock()
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// each calls fn for each retained line, oldest first. The caller must hold r.mu.
+func (r *RingBuffer) each(fn func([]byte)) {
+	if r.full {
+		for _, line := range r.lines[r.next:] {
+			fn(line)
+		}
+	}
+	for _, line := range r.lines[:r.next] {
+		fn(line)
+	}
+}
+
+// ServeHTTP responds with the retained lines as plain text.
+func (r *RingBuffer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = r.Dump(w)
+}
+
+// DumpOnSignal writes the retained lines to w each time one of the given operating system signals
+// is received, until the context is cancelled, when it returns the context's error. Any error
+// from writing to w is returned immediately.
+func (r *RingBuffer) DumpOnSignal(ctx context.Context, w io.Writer, sig ...os.Signal) error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+			if err := r.Dump(w); err != nil {
+				return err
+			}
+		}
+	}
+}
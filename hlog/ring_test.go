@@ -0,0 +1,31 @@
+package hlog
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRingBuffer(t *testing.T) {
+	r := NewRingBuffer(3)
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(r, "line %d\n", i)
+	}
+	// A line written in pieces is retained once complete
+	fmt.Fprint(r, "line ")
+	fmt.Fprint(r, "5\nline 6")
+
+	want := []string{"line 3", "line 4", "line 5"}
+	if got := r.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got lines %q, wanted %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Dump(&buf); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	if got, want := buf.String(), "line 3\nline 4\nline 5\n"; got != want {
+		t.Errorf("got dump %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DropReasonSampled is the reason recorded when a Handler drops a record because of sampling
+// configured using WithSampling.
+const DropReasonSampled = "sampled"
+
+// sampleKey identifies records that are sampled together.
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+// sampler limits the number of records with the same level and message written in each interval.
+// It is shared by all handlers derived from the handler that enabled it.
+type sampler struct {
+	first      uint64
+	thereafter uint64
+	interval   time.Duration
+	now        func() time.Time
+
+	mu         sync.Mutex
+	start      time.Time            // start of the current interval
+	counts     map[sampleKey]uint64 // records seen in the current interval
+	suppressed map[sampleKey]uint64 // records dropped in the current interval
+	notify     *Handler             // handler used to write notices of suppressed records
+	timer      *time.Timer          // writes notices at the end of an interval with suppressed records
+}
+
+// sample reports whether a record with the given level and message should be written. When a new
+// interval begins it first writes notices of the records suppressed during the previous one. h is
+// the handler handling the record.
+func (s *sampler) sample(h *Handler, level slog.Level, msg string) bool {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.start) >= s.interval {
+		s.flush(now)
+	}
+
+	k := sampleKey{level: level, msg: msg}
+	s.counts[k]++
+	n := s.counts[k]
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true
+	}
+	if len(s.suppressed) == 0 {
+		// Notices are written without the attributes of the record that caused them, are not
+		// sampled and, since the sidecar receives every record, are not sent to it
+		notify := h.clone()
+		notify.attrs = nil
+		notify.goroutine = false
+		notify.sampler = nil
+		notify.sidecar = nil
+		notify.owned = nil
+		s.notify = notify
+		s.timer = time.AfterFunc(s.start.Add(s.interval).Sub(now), s.expire)
+	}
+	s.suppressed[k]++
+	return false
+}
+
+// flush writes notices of the records suppressed in the current interval and begins a new
+// interval at now. The caller must hold s.mu.
+func (s *sampler) flush(now time.Time) {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	for _, r := range s.notices(now) {
+		_ = s.notify.Handle(context.Background(), r)
+	}
+	s.notify = nil
+	s.start = now
+	clear(s.counts)
+	clear(s.suppressed)
+}
+
+// expire writes notices of suppressed records at the end of an interval, when no record has been
+// handled to begin the next one.
+func (s *sampler) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush(s.now())
+}
+
+// close writes notices of the records suppressed in the current interval, so that they are not
+// lost when the handler is closed.
+func (s *sampler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.suppressed) > 0 {
+		s.flush(s.now())
+	}
+}
+
+// notices returns a record for each level and message that had records suppressed in the current
+// interval, ordered by level and then message.
+func (s *sampler) notices(now time.Time) []slog.Record {
+	if len(s.suppressed) == 0 {
+		return nil
+	}
+	keys := make([]sampleKey, 0, len(s.suppressed))
+	for k := range s.suppressed {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].msg < keys[j].msg
+	})
+
+	notices := make([]slog.Record, 0, len(keys))
+	for _, k := range keys {
+		r := slog.NewRecord(now, k.level, fmt.Sprintf("suppressed %d records", s.suppressed[k]), 0)
+		r.AddAttrs(slog.String("message", k.msg))
+		notices = append(notices, r)
+	}
+	return notices
+}
+
+// WithSampling returns a new Handler that limits how many records with the same level and message
+// it writes in each interval, so that a misbehaving loop cannot flood the output. In each interval
+// the first records are written, after which only every thereafter-th record is written, or none if
+// thereafter is zero. A notice, at the same level, of the number of records suppressed for each
+// message is written at the end of each interval in which records were suppressed, or before the
+// first record of the next interval if that is handled sooner, and by Close. Suppressed records are
+// counted as dropped with the reason DropReasonSampled when drop statistics are enabled using
+// WithDropStats, and are still passed to any sidecar handler. The sampling state is shared by all
+// Handlers derived from the new Handler. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithSampling(first, thereafter int, interval time.Duration) *Handler {
+	if first < 0 {
+		first = 0
+	}
+	if thereafter < 0 {
+		thereafter = 0
+	}
+	h2 := h.clone()
+	h2.sampler = &sampler{
+		first:      uint64(first),
+		thereafter: uint64(thereafter),
+		interval:   interval,
+		now:        time.Now,
+		counts:     make(map[sampleKey]uint64),
+		suppressed: make(map[sampleKey]uint64),
+	}
+	return h2.own(h2.sampler.close, nil)
+}
+
+// sampled reports whether the record should be written according to the handler's sampling,
+// first writing any notices of suppressed records that are due.
+func (h *Handler) sampled(r slog.Record) bool {
+	keep := h.sampler.sample(h, r.Level, r.Message)
+	if !keep {
+		h.dropped(DropReasonSampled)
+	}
+	return keep
+}
@@ -0,0 +1,98 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSampling(t *testing.T) {
+	var buf, side bytes.Buffer
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithTimeFormat("").WithDropStats().WithSampling(2, 3, time.Second).WithSidecar(&side)
+	h.sampler.now = func() time.Time { return now }
+	logger := slog.New(h)
+
+	for i := 0; i < 10; i++ {
+		logger.Info("busy", "i", i)
+	}
+	logger.Warn("busy")
+	logger.Info("other")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var got []string
+	for _, line := range lines {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"info | busy i=0",
+		"info | busy i=1",
+		"info | busy i=4",
+		"info | busy i=7",
+		"warn | busy",
+		"info | other",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got lines:\n%s\nwanted:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := h.Stats()[DropReasonSampled]; got != 6 {
+		t.Errorf("got %d records dropped, wanted 6", got)
+	}
+	if got := strings.Count(side.String(), "\n"); got != 12 {
+		t.Errorf("got %d records sent to the sidecar, wanted 12", got)
+	}
+
+	// The first record of the next interval is preceded by a notice of suppressed records
+	buf.Reset()
+	now = now.Add(time.Second)
+	logger.Info("busy")
+	if want := "info  | suppressed 6 records"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("got %q, wanted it to start with %q", buf.String(), want)
+	}
+	if want := "message=busy"; !strings.Contains(buf.String(), want) {
+		t.Errorf("got %q, wanted it to contain %q", buf.String(), want)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Errorf("got %d lines, wanted the notice followed by the record", got)
+	}
+	if strings.Contains(side.String(), "suppressed") {
+		t.Errorf("got notice in sidecar output, wanted none")
+	}
+
+	// Sampling state is shared by derived handlers
+	buf.Reset()
+	derived := slog.New(h.WithAttrs([]slog.Attr{slog.String("k", "v")}))
+	derived.Info("busy")
+	derived.Info("busy")
+	if got := strings.Count(buf.String(), "\n"); got != 1 {
+		t.Errorf("got %d lines, wanted 1", got)
+	}
+}
+
+func TestWithSamplingClose(t *testing.T) {
+	var buf bytes.Buffer
+	h := new(Handler).WithoutColor().WithWriter(&buf).WithTimeFormat("").WithGoroutineID().WithSampling(1, 0, time.Hour)
+	logger := slog.New(h).With("k", "v")
+
+	for i := 0; i < 4; i++ {
+		logger.Info("busy")
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 1 {
+		t.Fatalf("got %d lines, wanted 1", got)
+	}
+
+	// Notices pending at the end of the interval are written by Close
+	buf.Reset()
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got := strings.Join(strings.Fields(buf.String()), " ")
+	if want := "info | suppressed 3 records message=busy"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
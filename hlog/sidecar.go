@@ -0,0 +1,61 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithSidecar returns a new Handler that, in addition to writing human friendly output, writes
+// each record it emits to w as a JSON object, one per line, in the format of slog.JSONHandler. The
+// JSON records include the source location of each record and all of its attributes without
+// truncation or other formatting, so the complete structured log of an interactive session remains
+// available for later analysis. OpenSessionFile may be used to create a file for w. The new
+// Handler is otherwise identical to the receiver.
+func (h *Handler) WithSidecar(w io.Writer) *Handler {
+	h2 := h.clone()
+	var sidecar slog.Handler = slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.Level(math.MinInt), // records are filtered by the Handler
+	})
+	h2.sidecar = h.attrs.replay(sidecar)
+	return h2
+}
+
+// replay returns the result of adding the attributes and groups in the chain ending at n to sh, in
+// the order they were added to the Handler.
+func (n *attrNode) replay(sh slog.Handler) slog.Handler {
+	if n == nil {
+		return sh
+	}
+	sh = n.parent.replay(sh)
+	if n.group != "" {
+		return sh.WithGroup(n.group)
+	}
+	return sh.WithAttrs(n.attrs)
+}
+
+// OpenSessionFile creates a file for recording the logs of a session, such as with WithSidecar.
+// The placeholders {pid} and {time} in pattern are replaced by the id of the current process and
+// the current time in the form 20060102T150405. The file is created if it does not exist and
+// appended to if it does.
+func OpenSessionFile(pattern string) (*os.File, error) {
+	name := strings.NewReplacer(
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{time}", time.Now().Format("20060102T150405"),
+	).Replace(pattern)
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open session file: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,65 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+// StaticAttrs determines how a Handler distinguishes the attributes added to it using WithAttrs,
+// which usually describe the context of a logger, from the attributes of each record.
+type StaticAttrs int
+
+const (
+	// StaticAttrsPlain shows attributes added using WithAttrs in the same way as the attributes of
+	// each record. This is the default.
+	StaticAttrsPlain StaticAttrs = iota
+
+	// StaticAttrsDim shows attributes added using WithAttrs using the Static style of the theme,
+	// which dims them by default, so that the attributes of each record stand out. It has no effect
+	// when color is not used.
+	StaticAttrsDim
+
+	// StaticAttrsBracket encloses the attributes added using WithAttrs in square brackets.
+	StaticAttrsBracket
+)
+
+// WithStaticAttrs returns a new Handler that shows the attributes added using WithAttrs as
+// described by mode. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithStaticAttrs(mode StaticAttrs) *Handler {
+	h2 := h.clone()
+	h2.static = mode
+	return h2
+}
+
+// staticWriter returns the handler used to write attributes added using WithAttrs, which writes
+// them without color when they are to be dimmed, since the reset following a colored key would
+// also end the dimming.
+func (h *Handler) staticWriter() *Handler {
+	if h.static != StaticAttrsDim || h.nocolor {
+		return h
+	}
+	h2 := h.clone()
+	h2.nocolor = true
+	return h2
+}
+
+// writeStatic appends the attributes added using WithAttrs, as written to s, to b in the manner
+// given by the handler's StaticAttrs mode.
+func (h *Handler) writeStatic(b *buffer, s []byte) {
+	if len(s) == 0 {
+		return
+	}
+	sep := h.separator()
+	switch {
+	case h.static == StaticAttrsBracket:
+		b.WriteString(sep)
+		b.WriteString("[")
+		b.Write(s[len(sep):]) // each attribute is preceded by the separator
+		b.WriteString("]")
+	case h.static == StaticAttrsDim && !h.nocolor:
+		b.WriteString(sep)
+		styled := h.openStyle(b, h.styles().Static)
+		b.Write(s[len(sep):])
+		closeStyle(b, styled)
+	default:
+		b.Write(s)
+	}
+}
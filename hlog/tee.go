@@ -0,0 +1,101 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+var _ slog.Handler = (*TeeHandler)(nil)
+
+// TeeHandler is a slog.Handler that dispatches each record to several handlers, for example
+// writing human friendly output to a terminal using a Handler while writing JSON to a file using a
+// slog.JSONHandler. Each handler only receives the records it is enabled for, so the handlers may
+// have different minimum levels. LevelFilter may be used to impose a minimum level on a handler
+// that has no such option.
+type TeeHandler struct {
+	handlers []slog.Handler
+}
+
+// Tee returns a TeeHandler that dispatches records to each of handlers.
+//
+//	logger := slog.New(hlog.Tee(
+//		hlog.New(os.Stdout, nil),
+//		hlog.LevelFilter(slog.NewJSONHandler(f, nil), slog.LevelWarn),
+//	))
+func Tee(handlers ...slog.Handler) *TeeHandler {
+	return &TeeHandler{handlers: append([]slog.Handler(nil), handlers...)}
+}
+
+// Enabled reports whether any of the handlers is enabled for records at the given level.
+func (t *TeeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes a copy of r to each handler that is enabled for its level. Every such handler
+// receives the record even if others fail, and the errors they return are joined.
+func (t *TeeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a TeeHandler whose handlers each have the given attributes added.
+func (t *TeeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hs := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		hs[i] = h.WithAttrs(attrs)
+	}
+	return &TeeHandler{handlers: hs}
+}
+
+// WithGroup returns a TeeHandler whose handlers each have the given group opened.
+func (t *TeeHandler) WithGroup(name string) slog.Handler {
+	hs := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		hs[i] = h.WithGroup(name)
+	}
+	return &TeeHandler{handlers: hs}
+}
+
+// LevelFilter returns a slog.Handler that passes records to h only when they are at or above the
+// level reported by level, in addition to any filtering done by h itself.
+func LevelFilter(h slog.Handler, level slog.Leveler) slog.Handler {
+	return &levelFilter{next: h, level: level}
+}
+
+type levelFilter struct {
+	next  slog.Handler
+	level slog.Leveler
+}
+
+func (f *levelFilter) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= f.level.Level() && f.next.Enabled(ctx, level)
+}
+
+func (f *levelFilter) Handle(ctx context.Context, r slog.Record) error {
+	return f.next.Handle(ctx, r)
+}
+
+func (f *levelFilter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelFilter{next: f.next.WithAttrs(attrs), level: f.level}
+}
+
+func (f *levelFilter) WithGroup(name string) slog.Handler {
+	return &levelFilter{next: f.next.WithGroup(name), level: f.level}
+}
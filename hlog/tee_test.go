@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	var human, machine bytes.Buffer
+	logger := slog.New(Tee(
+		new(Handler).WithoutColor().WithWriter(&human).WithLevel(slog.LevelDebug),
+		LevelFilter(slog.NewJSONHandler(&machine, nil), slog.LevelWarn),
+	)).With("svc", "api").WithGroup("req")
+
+	logger.Debug("starting")
+	logger.Warn("slow", "ms", 250)
+
+	if got := strings.Count(human.String(), "\n"); got != 2 {
+		t.Errorf("got %d lines of human output, wanted 2: %q", got, human.String())
+	}
+	if !strings.Contains(human.String(), "svc=api req.ms=250") {
+		t.Errorf("got human output %q, wanted attributes", human.String())
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal(machine.Bytes(), &rec); err != nil {
+		t.Fatalf("got JSON output %q, wanted a single record: %v", machine.String(), err)
+	}
+	if rec["msg"] != "slow" || rec["svc"] != "api" {
+		t.Errorf("got JSON record %v, wanted the warning with its attributes", rec)
+	}
+}
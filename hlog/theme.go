@@ -0,0 +1,135 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import "log/slog"
+
+// Style is an ANSI SGR escape sequence that sets the appearance of text, such as "\x1b[1;31m" for
+// bold red. The empty Style leaves text unchanged.
+type Style string
+
+// Styles using the standard ANSI colors, which terminals adapt to their own palettes. Other colors
+// may be used with Color256 and RGB.
+const (
+	Black   Style = "\x1b[30m"
+	Red     Style = "\x1b[31m"
+	Green   Style = "\x1b[32m"
+	Yellow  Style = "\x1b[33m"
+	Blue    Style = "\x1b[34m"
+	Magenta Style = "\x1b[35m"
+	Cyan    Style = "\x1b[36m"
+	White   Style = "\x1b[37m"
+	Gray    Style = "\x1b[90m"
+	Dim     Style = "\x1b[2m"
+)
+
+// Bold returns the style rendered in bold.
+func (s Style) Bold() Style {
+	if s == "" {
+		return "\x1b[1m"
+	}
+	return "\x1b[1;" + s[len("\x1b["):]
+}
+
+// wrap returns text surrounded by the style and a reset directive, or text unchanged if the style
+// is empty.
+func (s Style) wrap(text string) string {
+	if s == "" {
+		return text
+	}
+	return string(s) + text + colorReset
+}
+
+// Theme determines the colors used by a Handler when color is enabled. The zero Theme uses no
+// color at all.
+type Theme struct {
+	Debug Style // level of records below LevelInfo
+	Info  Style // level of records from LevelInfo up to LevelWarn
+	Warn  Style // level of records from LevelWarn up to LevelError
+	Error Style // level of records at LevelError and above
+
+	Time    Style // timestamp of each record
+	Message Style // message of each record
+	Source  Style // source location of each record, when shown after the attributes
+	Key     Style // keys of attributes
+	Static  Style // attributes added using WithAttrs, when shown using StaticAttrsDim
+
+	// Keys gives the styles used for both the key and the value of particular attributes,
+	// overriding Key. Keys are matched after being qualified by their groups, as in "req.method".
+	// The map must not be modified once the Theme has been passed to WithTheme.
+	Keys map[string]Style
+}
+
+// DefaultTheme returns the theme used by a Handler unless changed using WithTheme, which suits
+// terminals with a dark background.
+func DefaultTheme() Theme {
+	return Theme{
+		Info:   Green.Bold(),
+		Warn:   Yellow.Bold(),
+		Error:  Red.Bold(),
+		Source: Dim,
+		Key:    Blue.Bold(),
+		Static: Dim,
+	}
+}
+
+// LightTheme returns a theme suited to terminals with a light background, avoiding yellow and
+// bold text that can be hard to read against white.
+func LightTheme() Theme {
+	return Theme{
+		Info:   Green,
+		Warn:   Magenta,
+		Error:  Red.Bold(),
+		Source: Gray,
+		Key:    Blue,
+		Static: Gray,
+	}
+}
+
+var defaultTheme = DefaultTheme()
+
+// level returns the style of the level column for a record at the given level.
+func (t *Theme) level(level slog.Level) Style {
+	switch {
+	case level >= slog.LevelError:
+		return t.Error
+	case level >= slog.LevelWarn:
+		return t.Warn
+	case level >= slog.LevelInfo:
+		return t.Info
+	default:
+		return t.Debug
+	}
+}
+
+// WithTheme returns a new Handler that uses the colors of t when color is enabled. For example, a
+// theme may be used to highlight the errors attached to records:
+//
+//	t := hlog.DefaultTheme()
+//	t.Keys = map[string]hlog.Style{"err": hlog.Red.Bold()}
+//	h = h.WithTheme(t)
+//
+// The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithTheme(t Theme) *Handler {
+	h2 := h.clone()
+	h2.theme = &t
+	return h2
+}
+
+// styles returns the theme used by the handler.
+func (h *Handler) styles() *Theme {
+	if h.theme == nil {
+		return &defaultTheme
+	}
+	return h.theme
+}
+
+// keyStyle returns the style of the key of the attribute with the given qualified key, and whether
+// the style also applies to its value.
+func (t *Theme) keyStyle(key string) (Style, bool) {
+	if s, ok := t.Keys[key]; ok {
+		return s, true
+	}
+	return t.Key, false
+}
@@ -0,0 +1,96 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeLocale holds the names of days and months used when formatting timestamps, replacing the
+// English names produced by the "Monday", "Mon", "January" and "Jan" elements of a time layout.
+// Days are indexed by time.Weekday, starting with Sunday, and months from January. Empty names
+// fall back to English.
+type TimeLocale struct {
+	Days        [7]string
+	ShortDays   [7]string
+	Months      [12]string
+	ShortMonths [12]string
+}
+
+// WithTimeLocale returns a new Handler that uses the names of days and months given by l when
+// formatting timestamps. It only has an effect on layouts that include day or month names, set
+// using WithTimeFormat. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithTimeLocale(l TimeLocale) *Handler {
+	h2 := h.clone()
+	h2.locale = &l
+	return h2
+}
+
+// WithISOWeek returns a new Handler that precedes each timestamp with its ISO 8601 week number, as
+// in "W07", which helps when reviewing the output of services that run for weeks. Combined with a
+// layout that includes the day of the week, such as "Mon 15:04:05", readers can orient themselves
+// without converting dates. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithISOWeek() *Handler {
+	h2 := h.clone()
+	h2.isoWeek = true
+	return h2
+}
+
+// format formats t according to layout, using the names of the locale for days and months.
+func (l *TimeLocale) format(t time.Time, layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		i, elem := nextNameElem(layout)
+		b.WriteString(t.Format(layout[:i]))
+		if elem == "" {
+			break
+		}
+		b.WriteString(l.name(t, elem))
+		layout = layout[i+len(elem):]
+	}
+	return b.String()
+}
+
+// nameElems are the elements of a time layout that produce names, longest first.
+var nameElems = []string{"Monday", "Mon", "January", "Jan"}
+
+// nextNameElem returns the index of the first element of layout that produces a name, and the
+// element, or the length of layout and an empty string if there is none.
+func nextNameElem(layout string) (int, string) {
+	for i := range layout {
+		for _, elem := range nameElems {
+			if strings.HasPrefix(layout[i:], elem) {
+				return i, elem
+			}
+		}
+	}
+	return len(layout), ""
+}
+
+// name returns the name of the day or month of t produced by the layout element elem.
+func (l *TimeLocale) name(t time.Time, elem string) string {
+	var s string
+	switch elem {
+	case "Monday":
+		s = l.Days[t.Weekday()]
+	case "Mon":
+		s = l.ShortDays[t.Weekday()]
+	case "January":
+		s = l.Months[t.Month()-1]
+	case "Jan":
+		s = l.ShortMonths[t.Month()-1]
+	}
+	if s == "" {
+		return t.Format(elem)
+	}
+	return s
+}
+
+// isoWeekText returns the ISO 8601 week number of t, as in "W07".
+func isoWeekText(t time.Time) string {
+	_, w := t.ISOWeek()
+	return fmt.Sprintf("W%02d", w)
+}
@@ -0,0 +1,20 @@
+package hlog
+
+import "unicode/utf8"
+
+// truncation describes how long values are shortened, by keeping a number of characters from the
+// head and tail of the value.
+type truncation struct {
+	head int
+	tail int
+}
+
+// apply shortens s if it is longer than the combined head and tail plus the ellipsis that would
+// replace its middle.
+func (t truncation) apply(s string) string {
+	if t.head < 0 || t.tail < 0 || utf8.RuneCountInString(s) <= t.head+t.tail+1 {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:t.head]) + "…" + string(runes[len(runes)-t.tail:])
+}
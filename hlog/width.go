@@ -0,0 +1,43 @@
+package hlog
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// messageWidthWindow is the number of recent messages whose lengths determine the width of the
+// message column when it is tuned automatically.
+const messageWidthWindow = 64
+
+// messageWidth tracks the lengths of recent messages to determine the width of the message
+// column. It is shared by all handlers derived from the handler that enabled it so that their
+// output stays aligned.
+type messageWidth struct {
+	limit int // maximum width
+
+	mu      sync.Mutex
+	lengths [messageWidthWindow]int
+	next    int
+}
+
+// width records the length of msg and returns the width to pad it to, which is the maximum length
+// of the recent messages, bounded by the limit.
+func (m *messageWidth) width(msg string) int {
+	n := utf8.RuneCountInString(msg)
+	if n > m.limit {
+		n = m.limit
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lengths[m.next] = n
+	m.next = (m.next + 1) % messageWidthWindow
+
+	w := 0
+	for _, l := range m.lengths {
+		if l > w {
+			w = l
+		}
+	}
+	return w
+}
@@ -0,0 +1,174 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// resolveColor decides whether output written to each of the handler's writers uses color and
+// detects the color profile if it is ProfileAuto, so that the environment and the writers are not
+// examined for every record. It must be called whenever the writers or the color mode of the
+// handler change.
+func (h *Handler) resolveColor() {
+	if h.profile == ProfileAuto {
+		h.profile = detectColorProfile()
+	}
+	writers := h.writers
+	if writers == nil {
+		writers = []io.Writer{h.writer}
+	}
+	h.colorOut = make([]bool, len(writers))
+	for i, w := range writers {
+		if w == nil {
+			w = os.Stdout
+		}
+		h.colorOut[i] = !h.nocolor && (h.color != ColorAuto || autoColor(w))
+	}
+}
+
+// stdoutColor reports whether color is used for standard output by handlers whose writers and
+// color mode were never set.
+var stdoutColor = sync.OnceValue(func() bool { return autoColor(os.Stdout) })
+
+// useColor reports whether output written to the handler's i-th writer should use color.
+func (h *Handler) useColor(i int) bool {
+	if h.nocolor {
+		return false
+	}
+	if i < len(h.colorOut) {
+		return h.colorOut[i]
+	}
+	return stdoutColor()
+}
+
+// colorForAny reports whether output written to any of n writers should use color.
+func (h *Handler) colorForAny(n int) bool {
+	for i := 0; i < n; i++ {
+		if h.useColor(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// DropReasonWriteFailed is the reason recorded when a Handler drops a record because writing it
+// to one or more of its writers failed.
+const DropReasonWriteFailed = "write_failed"
+
+// WithOnError returns a new Handler that calls fn with the error when writing a record fails, such
+// as when a disk is full or a pipe has been closed, so that broken log sinks can be detected. fn
+// is called once for each record that could not be written to one or more writers, with the errors
+// from those writers joined together, whether or not the Handler is asynchronous. The error is
+// also returned from Handle, which is discarded by slog.Logger. For an asynchronous Handler write
+// errors occur in the background goroutine, so fn is the only way to observe them. Records that
+// could not be written are counted as dropped with the reason DropReasonWriteFailed rather than
+// emitted when drop statistics are enabled using WithDropStats. fn may be called concurrently and
+// must not log using the Handler. The new Handler is otherwise identical to the receiver.
+func (h *Handler) WithOnError(fn func(err error)) *Handler {
+	h2 := h.clone()
+	h2.onError = fn
+	return h2
+}
+
+// write writes the formatted output of a record at the given level to each of writers, removing
+// color for those that should not receive it, and counts the record as emitted or dropped. Each
+// writer receives the output in a single call to Write. A failure to write to one writer does not
+// prevent writing to the others, and the errors are joined in the returned error. When the
+// handler is asynchronous a copy of the output is queued instead, and write reports whether it
+// was accepted.
+func (h *Handler) write(writers []io.Writer, p []byte, level slog.Level) (bool, error) {
+	var plain []byte
+	var pending []pendingWrite
+	var errs []error
+	for i, w := range writers {
+		mu := h.writerMutex(i)
+		out := p
+		if !h.nocolor && !h.useColor(i) {
+			if plain == nil {
+				plain = stripColor(p)
+			}
+			out = plain
+		}
+		if h.async != nil {
+			pending = append(pending, pendingWrite{w: w, mu: mu, p: append([]byte(nil), out...)})
+			continue
+		}
+		if err := writeLocked(w, mu, out); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if h.async != nil {
+		return h.async.enqueue(asyncEntry{writes: pending, level: level, drops: h.drops, onError: h.onError}), nil
+	}
+	err := errors.Join(errs...)
+	if err != nil && h.onError != nil {
+		h.onError(err)
+	}
+	h.drops.written(level, err)
+	return true, err
+}
+
+// writeLocked writes p to w while holding mu, reporting a short write as an error.
+func writeLocked(w io.Writer, mu *sync.Mutex, p []byte) error {
+	mu.Lock()
+	defer mu.Unlock()
+	n, err := w.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// stdoutMu serializes writes to standard output by handlers that have no writer.
+var stdoutMu sync.Mutex
+
+// writerLock returns the lock that serializes writes to w by the new Handler being derived from h.
+// A writer the receiver already writes to keeps its lock, so that handlers derived from one
+// another never interleave their output, while any other writer is given a lock of its own so
+// that writes to different writers never wait for one another.
+func (h *Handler) writerLock(w io.Writer) *sync.Mutex {
+	writers := h.writers
+	if h.writer != nil {
+		writers = []io.Writer{h.writer}
+	}
+	for i, hw := range writers {
+		if i < len(h.writeMu) && sameWriter(hw, w) {
+			return h.writeMu[i]
+		}
+	}
+	return newWriterLock(w)
+}
+
+// newWriterLock returns a new lock to serialize writes to w, or the lock for standard output if w
+// is nil.
+func newWriterLock(w io.Writer) *sync.Mutex {
+	if w == nil {
+		return &stdoutMu
+	}
+	return new(sync.Mutex)
+}
+
+// sameWriter reports whether a and b are the same writer, without panicking on writers that
+// cannot be compared.
+func sameWriter(a, b io.Writer) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.ValueOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// writerMutex returns the lock that serializes writes to the handler's i-th writer. The locks are
+// made by WithWriter and WithWriters and shared by all handlers derived from them.
+func (h *Handler) writerMutex(i int) *sync.Mutex {
+	if i < len(h.writeMu) {
+		return h.writeMu[i]
+	}
+	return &stdoutMu
+}
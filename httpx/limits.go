@@ -0,0 +1,172 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout returns middleware that limits the time allowed to handle each request to d. The
+// request's context is cancelled after d and, if the handler has not yet written its response, a
+// 503 Service Unavailable problem details response is sent instead and the breach logged with the
+// request's context. The handler's response is buffered until it returns, in the manner of
+// http.TimeoutHandler, so handlers that stream their responses should not use Timeout.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{h: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for k, vv := range tw.h {
+					dst[k] = vv
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				_, _ = w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					// The client went away; there is no one to respond to
+					return
+				}
+				slog.WarnContext(ctx, "http request timed out", "method", r.Method, "path", r.URL.Path, "timeout", d)
+				WriteProblem(w, Problem{
+					Status: http.StatusServiceUnavailable,
+					Detail: fmt.Sprintf("request not handled within %v", d),
+				})
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler writing it returns.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	h        http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// BodyLimit returns middleware that limits the size of request bodies to n bytes. Requests that
+// declare a larger Content-Length are rejected with a 413 Content Too Large problem details
+// response without calling the handler. For other requests, such as those without a
+// Content-Length, reads beyond the limit return an *http.MaxBytesError, which the handler should
+// treat as it would any other error reading the body. If the handler returns without writing a
+// response after such a read, the same 413 response is written for it. Breaches are logged with
+// the request's context.
+func BodyLimit(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				slog.WarnContext(r.Context(), "http request body too large", "method", r.Method, "path", r.URL.Path, "limit", n, "content_length", r.ContentLength)
+				WriteProblem(w, Problem{
+					Status: http.StatusRequestEntityTooLarge,
+					Detail: fmt.Sprintf("request body exceeds limit of %d bytes", n),
+				})
+				return
+			}
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, n), r: r, limit: n}
+			r.Body = body
+			rw := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+			if body.exceeded && !rw.wrote {
+				WriteProblem(w, Problem{
+					Status: http.StatusRequestEntityTooLarge,
+					Detail: fmt.Sprintf("request body exceeds limit of %d bytes", n),
+				})
+			}
+		})
+	}
+}
+
+// responseWriter records whether a response has been written.
+type responseWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.wrote = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.wrote = true
+	return rw.ResponseWriter.Write(p)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying ResponseWriter.
+func (rw *responseWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+// limitedBody logs the first read that exceeds the body limit.
+type limitedBody struct {
+	io.ReadCloser
+	r        *http.Request
+	limit    int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var mbe *http.MaxBytesError
+	if !b.exceeded && errors.As(err, &mbe) {
+		b.exceeded = true
+		slog.WarnContext(b.r.Context(), "http request body too large", "method", b.r.Method, "path", b.r.URL.Path, "limit", b.limit)
+	}
+	return n, err
+}
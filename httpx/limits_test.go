@@ -0,0 +1,88 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "fast")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusTeapot || rec.Body.String() != "fast" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("got response %d %q %q, wanted handler's response", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	checkProblem(t, rec, http.StatusServiceUnavailable)
+}
+
+func TestBodyLimit(t *testing.T) {
+	var readErr error
+	h := BodyLimit(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too long")))
+	checkProblem(t, rec, http.StatusRequestEntityTooLarge)
+
+	// Without a content length the limit is enforced as the body is read
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("too long")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var mbe *http.MaxBytesError
+	if !errors.As(readErr, &mbe) {
+		t.Errorf("got read error %v, wanted *http.MaxBytesError", readErr)
+	}
+	// The handler wrote no response, so the problem response is written for it
+	checkProblem(t, rec, http.StatusRequestEntityTooLarge)
+
+	// A response written by the handler is left alone
+	h = BodyLimit(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, "bad body", http.StatusBadRequest)
+		}
+	}))
+	req = httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("too long")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, wanted the handler's %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func checkProblem(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Errorf("got status %d, wanted %d", rec.Code, status)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("got content type %q, wanted application/problem+json", ct)
+	}
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if p.Status != status {
+		t.Errorf("got problem status %d, wanted %d", p.Status, status)
+	}
+}
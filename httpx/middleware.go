@@ -0,0 +1,17 @@
+package httpx
+
+import "net/http"
+
+// Middleware wraps an http.Handler to add behaviour before or after it handles a request.
+type Middleware func(http.Handler) http.Handler
+
+// Chain returns middleware that applies each of mws in turn, so that the first is outermost and
+// sees each request first.
+func Chain(mws ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		return h
+	}
+}
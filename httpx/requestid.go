@@ -0,0 +1,71 @@
+// Package httpx provides middleware and transports for HTTP servers and clients.
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/iand/pontium/hlog"
+)
+
+// RequestIDHeader is the header used to carry request ids between services.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen limits the length of request ids accepted from clients.
+const maxRequestIDLen = 128
+
+// RequestID returns middleware that assigns each request an id. The id is taken from the
+// RequestIDHeader of the request if present and valid, otherwise a new one is generated with
+// hlog.NewRequestID. The id is stored in the request's context with hlog.ContextWithRequestID, so
+// that it is included in every record logged with the context, and echoed in the RequestIDHeader of
+// the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = hlog.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(hlog.ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID reports whether id is acceptable as a request id supplied by a client. Ids are
+// limited in length and to printable ASCII without spaces so they cannot be used to forge log
+// lines or inflate log volume.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Transport is an http.RoundTripper that adds the request id carried by the context of each
+// request, if any, to the RequestIDHeader of the outgoing request so that calls made while
+// handling a request can be correlated with it.
+type Transport struct {
+	// Base is the transport used to make requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	id, ok := hlog.RequestIDFromContext(req.Context())
+	if !ok || req.Header.Get(RequestIDHeader) != "" {
+		return base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	req.Header.Set(RequestIDHeader, id)
+	return base.RoundTrip(req)
+}
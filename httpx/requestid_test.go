@@ -0,0 +1,67 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iand/pontium/hlog"
+)
+
+func TestRequestID(t *testing.T) {
+	var downstream string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get(RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = hlog.RequestIDFromContext(r.Context())
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("downstream request: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}))
+
+	testCases := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{name: "supplied", header: "abc-123"},
+		{name: "missing", generate: true},
+		{name: "invalid", header: "abc\n123", generate: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got == "" {
+				t.Fatalf("no request id in context")
+			}
+			if !tc.generate && got != tc.header {
+				t.Errorf("got request id %q, wanted %q", got, tc.header)
+			}
+			if tc.generate && got == tc.header {
+				t.Errorf("got supplied request id %q, wanted a generated one", got)
+			}
+			if echoed := rec.Header().Get(RequestIDHeader); echoed != got {
+				t.Errorf("got response header %q, wanted %q", echoed, got)
+			}
+			if downstream != got {
+				t.Errorf("got downstream header %q, wanted %q", downstream, got)
+			}
+		})
+	}
+}
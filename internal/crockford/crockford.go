@@ -0,0 +1,15 @@
+// Package crockford encodes integers using Crockford's base32 alphabet, as used by ULIDs and hlog
+// request ids.
+package crockford
+
+// Alphabet is the Crockford base32 alphabet, which omits easily confused characters and sorts in
+// the same order as the values it encodes.
+const Alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Encode fills dst with the low 5*len(dst) bits of v, most significant first.
+func Encode(dst []byte, v uint64) {
+	for i := len(dst) - 1; i >= 0; i-- {
+		dst[i] = Alphabet[v&0x1f]
+		v >>= 5
+	}
+}
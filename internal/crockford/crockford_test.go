@@ -0,0 +1,25 @@
+package crockford
+
+import "testing"
+
+func TestEncode(t *testing.T) {
+	testCases := []struct {
+		v    uint64
+		n    int
+		want string
+	}{
+		{v: 0, n: 4, want: "0000"},
+		{v: 31, n: 2, want: "0Z"},
+		{v: 32, n: 2, want: "10"},
+		{v: 1<<50 - 1, n: 10, want: "ZZZZZZZZZZ"},
+		{v: 1 << 50, n: 10, want: "0000000000"},
+	}
+
+	for _, tc := range testCases {
+		dst := make([]byte, tc.n)
+		Encode(dst, tc.v)
+		if got := string(dst); got != tc.want {
+			t.Errorf("Encode(%d) into %d: got %q, wanted %q", tc.v, tc.n, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package prom
+
+import (
+	"fmt"
+	"math"
+)
+
+// Histogram bucket presets for common measurements. They are intended to be used as the Buckets
+// of a HistogramOpts so that the same measurements have the same buckets across services. The
+// slices must not be modified.
+var (
+	// HTTPLatencyBuckets are buckets in seconds for the latency of HTTP and RPC requests, from 1ms
+	// to 10s.
+	HTTPLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	// DBLatencyBuckets are buckets in seconds for the latency of database queries, from 500µs to
+	// 2.5s.
+	DBLatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+	// PayloadSizeBuckets are buckets in bytes for the size of request and response payloads, from
+	// 64B to 64MiB in powers of four.
+	PayloadSizeBuckets = []float64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20}
+)
+
+// BucketsForSLO returns count exponential buckets arranged so that target, such as the latency
+// objective of a service level objective, is one of the bucket boundaries with about half of the
+// buckets below it. Each bucket boundary is factor times the previous one. Having a boundary at
+// the target means the proportion of observations within the objective can be computed exactly.
+// Like prometheus.ExponentialBuckets, it panics if target is not positive, factor is not greater
+// than one or count is less than one.
+func BucketsForSLO(target, factor float64, count int) []float64 {
+	if target <= 0 {
+		panic(fmt.Sprintf("BucketsForSLO needs a positive target, got %v", target))
+	}
+	if factor <= 1 {
+		panic(fmt.Sprintf("BucketsForSLO needs a factor greater than 1, got %v", factor))
+	}
+	if count < 1 {
+		panic(fmt.Sprintf("BucketsForSLO needs a positive count, got %d", count))
+	}
+
+	below := (count - 1) / 2
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = target * math.Pow(factor, float64(i-below))
+	}
+	buckets[below] = target // avoid rounding error at the target
+	return buckets
+}
@@ -0,0 +1,20 @@
+package prom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBucketsForSLO(t *testing.T) {
+	got := BucketsForSLO(0.2, 2, 5)
+	want := []float64{0.05, 0.1, 0.2, 0.4, 0.8}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	got = BucketsForSLO(1, 10, 4)
+	want = []float64{0.1, 1, 10, 100}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
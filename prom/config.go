@@ -0,0 +1,155 @@
+package prom
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"time"
+
+	"go.opencensus.io/stats/view"
+)
+
+// Config holds the options for a metrics server. Its fields are tagged with the environment
+// variable and command line flag names used to set them, together with their defaults, so the
+// configuration can be loaded declaratively.
+type Config struct {
+	Addr            string        `env:"PROM_ADDR" flag:"prom-addr" default:":9090" usage:"Address on which to serve metrics, or a comma separated list of addresses; unix domain sockets are given as unix:path"`
+	Path            string        `env:"PROM_PATH" flag:"prom-path" default:"/metrics" usage:"Path on which to serve metrics"`
+	TLSCertFile     string        `env:"PROM_TLS_CERT_FILE" flag:"prom-tls-cert-file" usage:"File containing a TLS certificate, enables TLS when set together with a key file"`
+	TLSKeyFile      string        `env:"PROM_TLS_KEY_FILE" flag:"prom-tls-key-file" usage:"File containing the TLS private key"`
+	Username        string        `env:"PROM_USERNAME" flag:"prom-username" usage:"Username required to access metrics using basic authentication"`
+	Password        string        `env:"PROM_PASSWORD" flag:"prom-password" secret:"true" usage:"Password required to access metrics using basic authentication"`
+	Pprof           bool          `env:"PROM_PPROF" flag:"prom-pprof" usage:"Serve pprof profiles under /debug/pprof/"`
+	ReportingPeriod time.Duration `env:"PROM_REPORTING_PERIOD" flag:"prom-reporting-period" default:"2s" usage:"Interval at which opencensus views are reported"`
+	LogRequests     bool          `env:"PROM_LOG_REQUESTS" flag:"prom-log-requests" usage:"Log each request to the metrics server at debug level"`
+}
+
+// DefaultConfig returns a Config holding the default value of each option.
+func DefaultConfig() Config {
+	return Config{
+		Addr:            ":9090",
+		Path:            "/metrics",
+		ReportingPeriod: defaultReportingPeriod,
+	}
+}
+
+// Validate reports whether the configuration is consistent.
+func (c *Config) Validate() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tls certificate and key files must be set together")
+	}
+	if (c.Username == "") != (c.Password == "") {
+		return fmt.Errorf("username and password must be set together")
+	}
+	if c.ReportingPeriod < 0 {
+		return fmt.Errorf("reporting period must not be negative")
+	}
+	return nil
+}
+
+// NewFromConfig returns a PrometheusServer configured by cfg, exporting opencensus views using
+// appName as the metric namespace. A zero reporting period leaves the default in place. The
+// reporting period of opencensus views is global to the process.
+func NewFromConfig(cfg Config, appName string) (*PrometheusServer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	p, err := NewPrometheusServer(cfg.Addr, cfg.Path, appName)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ReportingPeriod > 0 {
+		view.SetReportingPeriod(cfg.ReportingPeriod)
+	}
+	p.certFile = cfg.TLSCertFile
+	p.keyFile = cfg.TLSKeyFile
+	p.username = cfg.Username
+	p.password = cfg.Password
+	p.pprof = cfg.Pprof
+	p.logRequests = cfg.LogRequests
+	return p, nil
+}
+
+// handler returns the handler that serves the server's endpoints.
+func (p *PrometheusServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(p.metricsPath, withScrapeTrace(p.pe))
+	if p.pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	var h http.Handler = mux
+	if p.username != "" {
+		h = withBasicAuth(h, p.username, p.password)
+	}
+	h = withRecovery(h)
+	if p.logRequests {
+		h = withRequestLog(h)
+	}
+	return h
+}
+
+// withRecovery wraps next so that a panic while serving a request, such as one raised by a
+// collector during a scrape, is logged with its stack and answered with an internal server error
+// rather than silently dropping the connection.
+func withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			slog.ErrorContext(r.Context(), "panic serving prometheus request", "method", r.Method, "path", r.URL.Path, "panic", v, "stack", string(debug.Stack()))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withRequestLog wraps next so that each request is logged at debug level once it has been served.
+func withRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		slog.DebugContext(r.Context(), "prometheus request", "method", r.Method, "path", r.URL.Path, "status", sw.status, "duration", time.Since(start), "remote", r.RemoteAddr)
+	})
+}
+
+// statusWriter records the status code written to a ResponseWriter.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap returns the underlying ResponseWriter for use by http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withBasicAuth wraps next so that requests must carry the given basic authentication credentials.
+func withBasicAuth(next http.Handler, username, password string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, pw, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(u), []byte(username)) != 1 || subtle.ConstantTimeCompare([]byte(pw), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
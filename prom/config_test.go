@@ -0,0 +1,82 @@
+package prom
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("default config: unexpected error: %v", err)
+	}
+
+	cfg.TLSCertFile = "cert.pem"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("certificate without key: expected an error")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Username = "scraper"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("username without password: expected an error")
+	}
+}
+
+func TestWithBasicAuth(t *testing.T) {
+	h := withBasicAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "scraper", "secret")
+
+	testCases := []struct {
+		username string
+		password string
+		want     int
+	}{
+		{username: "scraper", password: "secret", want: http.StatusOK},
+		{username: "scraper", password: "wrong", want: http.StatusUnauthorized},
+		{want: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tc.username != "" {
+			req.SetBasicAuth(tc.username, tc.password)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s:%s: got status %d, wanted %d", tc.username, tc.password, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestWithRecovery(t *testing.T) {
+	h := withRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("collector failed")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, wanted %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestWithRequestLog(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(prev)
+
+	h := withRequestLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := buf.String(); !strings.Contains(got, "path=/metrics") || !strings.Contains(got, "status=418") {
+		t.Errorf("got log %q, wanted the request's path and status", got)
+	}
+}
@@ -0,0 +1,111 @@
+package prom
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Deltas reports how much a set of existing counters have changed between successive snapshots.
+// It is intended for deriving periodic progress summaries, such as "processed 1234 items in the
+// last 10s", from counters that are already maintained for prometheus rather than keeping
+// duplicate counters for logging. The zero value is ready for use. A Deltas is safe for concurrent
+// use.
+type Deltas struct {
+	mu       sync.Mutex
+	tracked  map[string]prometheus.Collector
+	last     map[string]float64
+	lastTime time.Time
+}
+
+// Track adds the counter c to the set of counters reported under name. c may be a Counter or a
+// collector of several counters, such as a CounterVec, in which case the sum of all its counters
+// is tracked. Collected metrics that are not counters are ignored. The first snapshot after a
+// counter is tracked reports its change since Track was called.
+func (d *Deltas) Track(name string, c prometheus.Collector) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.tracked == nil {
+		d.tracked = make(map[string]prometheus.Collector)
+		d.last = make(map[string]float64)
+		d.lastTime = time.Now()
+	}
+	d.tracked[name] = c
+	d.last[name] = counterSum(c)
+}
+
+// Snapshot returns the change in each tracked counter since the previous snapshot, or since the
+// counter was tracked if there has been no previous snapshot.
+func (d *Deltas) Snapshot() DeltaSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	s := DeltaSnapshot{
+		Interval: now.Sub(d.lastTime),
+		Values:   make(map[string]float64, len(d.tracked)),
+	}
+	for name, c := range d.tracked {
+		v := counterSum(c)
+		s.Values[name] = v - d.last[name]
+		d.last[name] = v
+	}
+	d.lastTime = now
+	return s
+}
+
+// DeltaSnapshot holds the changes in tracked counters over an interval.
+type DeltaSnapshot struct {
+	Interval time.Duration      // the time since the previous snapshot
+	Values   map[string]float64 // the change in each tracked counter, keyed by name
+}
+
+// Rate returns the change in the named counter per second over the interval of the snapshot.
+func (s DeltaSnapshot) Rate(name string) float64 {
+	if s.Interval <= 0 {
+		return 0
+	}
+	return s.Values[name] / s.Interval.Seconds()
+}
+
+// LogValue implements slog.LogValuer, logging the snapshot as a group holding the interval and
+// the change in each counter in name order.
+func (s DeltaSnapshot) LogValue() slog.Value {
+	names := make([]string, 0, len(s.Values))
+	for name := range s.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]slog.Attr, 0, len(names)+1)
+	attrs = append(attrs, slog.Duration("interval", s.Interval))
+	for _, name := range names {
+		attrs = append(attrs, slog.Float64(name, s.Values[name]))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// counterSum returns the sum of the values of the counters collected from c.
+func counterSum(c prometheus.Collector) float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	sum := 0.0
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			continue
+		}
+		if pb.Counter != nil {
+			sum += pb.Counter.GetValue()
+		}
+	}
+	return sum
+}
@@ -0,0 +1,38 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDeltas(t *testing.T) {
+	items := prometheus.NewCounter(prometheus.CounterOpts{Name: "items_total"})
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total"}, []string{"code"})
+	items.Add(5)
+
+	var d Deltas
+	d.Track("items", items)
+	d.Track("requests", requests)
+
+	items.Add(3)
+	requests.WithLabelValues("200").Add(2)
+	requests.WithLabelValues("500").Inc()
+
+	s := d.Snapshot()
+	if got := s.Values["items"]; got != 3 {
+		t.Errorf("items: got delta %v, wanted 3", got)
+	}
+	if got := s.Values["requests"]; got != 3 {
+		t.Errorf("requests: got delta %v, wanted 3", got)
+	}
+
+	items.Inc()
+	s = d.Snapshot()
+	if got := s.Values["items"]; got != 1 {
+		t.Errorf("items: got second delta %v, wanted 1", got)
+	}
+	if got := s.Values["requests"]; got != 0 {
+		t.Errorf("requests: got second delta %v, wanted 0", got)
+	}
+}
@@ -0,0 +1,143 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcServerHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Number of RPCs completed by the server, regardless of success or failure.",
+	}, []string{"service", "method", "code"})
+
+	grpcServerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Duration of RPCs handled by the server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method", "code"})
+
+	grpcClientHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_handled_total",
+		Help: "Number of RPCs completed by the client, regardless of success or failure.",
+	}, []string{"service", "method", "code"})
+
+	grpcClientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_client_handling_seconds",
+		Help:    "Duration of RPCs made by the client, until the last message was received.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method", "code"})
+)
+
+// GRPCServerInterceptors returns unary and stream server interceptors that record the number and
+// duration of RPCs, labelled with the gRPC service, method and status code. The interceptors
+// should be installed using grpc.ChainUnaryInterceptor and grpc.ChainStreamInterceptor.
+func GRPCServerInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, error) {
+	for _, c := range []prometheus.Collector{grpcServerHandled, grpcServerDuration} {
+		if _, err := registerOrExisting(c); err != nil {
+			return nil, nil, fmt.Errorf("register grpc server metrics: %w", err)
+		}
+	}
+
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeRPC(grpcServerHandled, grpcServerDuration, info.FullMethod, start, err)
+		return resp, err
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeRPC(grpcServerHandled, grpcServerDuration, info.FullMethod, start, err)
+		return err
+	}
+
+	return unary, stream, nil
+}
+
+// GRPCClientInterceptors returns unary and stream client interceptors that record the number and
+// duration of RPCs, labelled with the gRPC service, method and status code. The interceptors
+// should be installed using grpc.WithChainUnaryInterceptor and grpc.WithChainStreamInterceptor.
+// The duration of a streaming RPC is measured until the client receives the end of the stream or
+// an error or, when the server does not stream, until the client receives the response.
+func GRPCClientInterceptors() (grpc.UnaryClientInterceptor, grpc.StreamClientInterceptor, error) {
+	for _, c := range []prometheus.Collector{grpcClientHandled, grpcClientDuration} {
+		if _, err := registerOrExisting(c); err != nil {
+			return nil, nil, fmt.Errorf("register grpc client metrics: %w", err)
+		}
+	}
+
+	unary := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		observeRPC(grpcClientHandled, grpcClientDuration, method, start, err)
+		return err
+	}
+
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			observeRPC(grpcClientHandled, grpcClientDuration, method, start, err)
+			return nil, err
+		}
+		return &monitoredClientStream{ClientStream: cs, method: method, start: start, serverStreams: desc.ServerStreams}, nil
+	}
+
+	return unary, stream, nil
+}
+
+// monitoredClientStream records metrics for a client stream once it has finished.
+type monitoredClientStream struct {
+	grpc.ClientStream
+	method        string
+	start         time.Time
+	serverStreams bool // whether the server sends a stream of messages rather than a single response
+	once          sync.Once
+}
+
+func (s *monitoredClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil && !s.serverStreams {
+		// The single response of a client streaming RPC completes it
+		s.once.Do(func() {
+			observeRPC(grpcClientHandled, grpcClientDuration, s.method, s.start, nil)
+		})
+	}
+	if err != nil {
+		s.once.Do(func() {
+			if errors.Is(err, io.EOF) {
+				observeRPC(grpcClientHandled, grpcClientDuration, s.method, s.start, nil)
+				return
+			}
+			observeRPC(grpcClientHandled, grpcClientDuration, s.method, s.start, err)
+		})
+	}
+	return err
+}
+
+func observeRPC(handled *prometheus.CounterVec, duration *prometheus.HistogramVec, fullMethod string, start time.Time, err error) {
+	service, method := splitMethodName(fullMethod)
+	code := status.Code(err).String()
+	handled.WithLabelValues(service, method, code).Inc()
+	duration.WithLabelValues(service, method, code).Observe(time.Since(start).Seconds())
+}
+
+// splitMethodName splits a full gRPC method name of the form /package.service/method.
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
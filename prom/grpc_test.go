@@ -0,0 +1,124 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClientStream is a client stream that receives a number of messages followed by err.
+type fakeClientStream struct {
+	grpc.ClientStream
+	msgs int
+	err  error
+}
+
+func (s *fakeClientStream) RecvMsg(m any) error {
+	if s.msgs > 0 {
+		s.msgs--
+		return nil
+	}
+	return s.err
+}
+
+func TestGRPCClientInterceptors(t *testing.T) {
+	unary, stream, err := GRPCClientInterceptors()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handled := func(method, code string) float64 {
+		return testutil.ToFloat64(grpcClientHandled.WithLabelValues("test.Service", method, code))
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			return status.Error(codes.NotFound, "missing")
+		}
+		before := handled("Unary", "NotFound")
+		_ = unary(context.Background(), "/test.Service/Unary", nil, nil, nil, invoker)
+		if got := handled("Unary", "NotFound") - before; got != 1 {
+			t.Errorf("got %v RPCs recorded, wanted 1", got)
+		}
+	})
+
+	testCases := []struct {
+		name   string
+		desc   grpc.StreamDesc
+		stream *fakeClientStream
+		recvs  int
+	}{
+		{
+			name:   "ClientStream",
+			desc:   grpc.StreamDesc{ClientStreams: true},
+			stream: &fakeClientStream{msgs: 1, err: io.EOF},
+			recvs:  1,
+		},
+		{
+			name:   "ServerStream",
+			desc:   grpc.StreamDesc{ServerStreams: true},
+			stream: &fakeClientStream{msgs: 3, err: io.EOF},
+			recvs:  4,
+		},
+		{
+			name:   "BidiStream",
+			desc:   grpc.StreamDesc{ClientStreams: true, ServerStreams: true},
+			stream: &fakeClientStream{msgs: 2, err: io.EOF},
+			recvs:  3,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return tc.stream, nil
+			}
+			before := handled(tc.name, "OK")
+			cs, err := stream(context.Background(), &tc.desc, nil, "/test.Service/"+tc.name, streamer)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := 0; i < tc.recvs; i++ {
+				if err := cs.RecvMsg(nil); err != nil && !errors.Is(err, io.EOF) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := handled(tc.name, "OK") - before; got != 0 && i < tc.recvs-1 {
+					t.Fatalf("got RPC recorded after %d messages, wanted it recorded once complete", i+1)
+				}
+			}
+			if got := handled(tc.name, "OK") - before; got != 1 {
+				t.Errorf("got %v RPCs recorded, wanted 1", got)
+			}
+		})
+	}
+}
+
+func TestGRPCServerInterceptors(t *testing.T) {
+	unary, stream, err := GRPCServerInterceptors()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handled := func(method, code string) float64 {
+		return testutil.ToFloat64(grpcServerHandled.WithLabelValues("test.Service", method, code))
+	}
+
+	before := handled("Unary", "OK")
+	_, _ = unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Unary"}, func(context.Context, any) (any, error) {
+		return nil, nil
+	})
+	if got := handled("Unary", "OK") - before; got != 1 {
+		t.Errorf("unary: got %v RPCs recorded, wanted 1", got)
+	}
+
+	before = handled("Stream", "Unavailable")
+	_ = stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, func(any, grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, "down")
+	})
+	if got := handled("Stream", "Unavailable") - before; got != 1 {
+		t.Errorf("stream: got %v RPCs recorded, wanted 1", got)
+	}
+}
@@ -0,0 +1,85 @@
+package prom
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// healthGatherer wraps a prometheus.Gatherer, recording metrics about each gather so that the
+// health of the exporter itself is observable. Because the metrics are recorded after a gather
+// completes, each scrape reports the results of the previous gather.
+type healthGatherer struct {
+	g prometheus.Gatherer
+
+	duration prometheus.Gauge
+	errors   prometheus.Counter
+	families prometheus.Gauge
+	series   prometheus.Gauge
+}
+
+var _ prometheus.Gatherer = (*healthGatherer)(nil)
+
+// newHealthGatherer wraps g and registers its metrics with reg, reusing any already registered
+// by another server.
+func newHealthGatherer(reg prometheus.Registerer, g prometheus.Gatherer) (*healthGatherer, error) {
+	hg := &healthGatherer{
+		g: g,
+		duration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "prom_exporter_last_gather_duration_seconds",
+			Help: "Time taken by the most recent gather of metrics.",
+		}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "prom_exporter_gather_errors_total",
+			Help: "Number of gathers of metrics that reported an error.",
+		}),
+		families: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "prom_exporter_metric_families",
+			Help: "Number of metric families returned by the most recent gather, approximating the number of registered collectors.",
+		}),
+		series: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "prom_exporter_series",
+			Help: "Number of series returned by the most recent gather.",
+		}),
+	}
+
+	// Servers sharing a registry share these metrics, since their names are fixed
+	for _, c := range []*prometheus.Gauge{&hg.duration, &hg.families, &hg.series} {
+		existing, err := registerOrExistingWith(reg, *c)
+		if err != nil {
+			return nil, fmt.Errorf("register: %w", err)
+		}
+		*c = existing
+	}
+	existing, err := registerOrExistingWith(reg, hg.errors)
+	if err != nil {
+		return nil, fmt.Errorf("register: %w", err)
+	}
+	hg.errors = existing
+
+	return hg, nil
+}
+
+// Gather implements prometheus.Gatherer. Errors are logged using slog, since they are otherwise
+// only visible to whoever made the scrape.
+func (hg *healthGatherer) Gather() ([]*dto.MetricFamily, error) {
+	start := time.Now()
+	mfs, err := hg.g.Gather()
+	hg.duration.Set(time.Since(start).Seconds())
+
+	series := 0
+	for _, mf := range mfs {
+		series += len(mf.GetMetric())
+	}
+	hg.families.Set(float64(len(mfs)))
+	hg.series.Set(float64(series))
+
+	if err != nil {
+		hg.errors.Inc()
+		slog.Error("failed to gather prometheus metrics", "error", err)
+	}
+	return mfs, err
+}
@@ -0,0 +1,51 @@
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iand/pontium/hlog"
+)
+
+var (
+	logEmittedDesc = prometheus.NewDesc("hlog_records_emitted_total", "Number of log records emitted, by level.", []string{"level"}, nil)
+	logDroppedDesc = prometheus.NewDesc("hlog_records_dropped_total", "Number of log records dropped, by reason.", []string{"reason"}, nil)
+	logRingDesc    = prometheus.NewDesc("hlog_ring_buffer_lines", "Number of lines held by the log ring buffer.", nil, nil)
+)
+
+// LogCollector is a prometheus collector that exports the statistics of an hlog Handler, so that
+// the health of logging itself is observable. The handler must have statistics enabled using
+// WithDropStats.
+type LogCollector struct {
+	h    *hlog.Handler
+	ring *hlog.RingBuffer
+}
+
+var _ prometheus.Collector = (*LogCollector)(nil)
+
+// NewLogCollector returns a collector exporting the statistics of h. If ring is not nil then the
+// number of lines it holds is also exported. The collector is not registered.
+func NewLogCollector(h *hlog.Handler, ring *hlog.RingBuffer) *LogCollector {
+	return &LogCollector{h: h, ring: ring}
+}
+
+// Describe implements prometheus.Collector.
+func (c *LogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- logEmittedDesc
+	ch <- logDroppedDesc
+	if c.ring != nil {
+		ch <- logRingDesc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *LogCollector) Collect(ch chan<- prometheus.Metric) {
+	for level, n := range c.h.Emitted() {
+		ch <- prometheus.MustNewConstMetric(logEmittedDesc, prometheus.CounterValue, float64(n), level.String())
+	}
+	for reason, n := range c.h.Stats() {
+		ch <- prometheus.MustNewConstMetric(logDroppedDesc, prometheus.CounterValue, float64(n), reason)
+	}
+	if c.ring != nil {
+		ch <- prometheus.MustNewConstMetric(logRingDesc, prometheus.GaugeValue, float64(c.ring.Len()))
+	}
+}
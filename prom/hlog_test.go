@@ -0,0 +1,40 @@
+package prom
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/iand/pontium/hlog"
+)
+
+func TestLogCollector(t *testing.T) {
+	h := new(hlog.Handler).WithWriter(io.Discard).WithLevel(slog.LevelInfo).WithDropStats()
+	logger := slog.New(h)
+	logger.Info("one")
+	logger.Info("two")
+	// Records rejected by Enabled never reach the handler, so only those passed to Handle count
+	logger.Debug("not counted")
+	_ = h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelDebug, "dropped", 0))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewLogCollector(h, nil))
+
+	want := `
+# HELP hlog_records_dropped_total Number of log records dropped, by reason.
+# TYPE hlog_records_dropped_total counter
+hlog_records_dropped_total{reason="level"} 1
+# HELP hlog_records_emitted_total Number of log records emitted, by level.
+# TYPE hlog_records_emitted_total counter
+hlog_records_emitted_total{level="INFO"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
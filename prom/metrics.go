@@ -2,7 +2,10 @@ package prom
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -10,7 +13,6 @@ import (
 	promexp "contrib.go.opencensus.io/exporter/prometheus"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opencensus.io/stats/view"
-	"golang.org/x/exp/slog"
 )
 
 type (
@@ -18,17 +20,41 @@ type (
 	Gauge   = prometheus.Gauge
 )
 
+// defaultReportingPeriod is the interval at which opencensus views are reported to the exporter.
+const defaultReportingPeriod = 2 * time.Second
+
 type PrometheusServer struct {
 	addr        string
 	metricsPath string
 	pe          *promexp.Exporter
+	certFile    string // serve using TLS when set, together with keyFile
+	keyFile     string
+	username    string // require basic authentication when set
+	password    string
+	pprof       bool // serve pprof profiles
+	logRequests bool // log each request at debug level
 }
 
 func NewPrometheusServer(addr string, metricsPath string, appName string) (*PrometheusServer, error) {
+	return newPrometheusServer(addr, metricsPath, appName, prometheus.DefaultRegisterer, prometheus.DefaultGatherer)
+}
+
+// NewPrometheusServerWithRegistry is like NewPrometheusServer but registers and gathers metrics
+// using reg instead of the default prometheus registry.
+func NewPrometheusServerWithRegistry(addr string, metricsPath string, appName string, reg *prometheus.Registry) (*PrometheusServer, error) {
+	return newPrometheusServer(addr, metricsPath, appName, reg, reg)
+}
+
+func newPrometheusServer(addr string, metricsPath string, appName string, reg prometheus.Registerer, g prometheus.Gatherer) (*PrometheusServer, error) {
+	hg, err := newHealthGatherer(reg, g)
+	if err != nil {
+		return nil, fmt.Errorf("new exporter health metrics: %w", err)
+	}
+
 	pe, err := promexp.NewExporter(promexp.Options{
 		Namespace:  appName,
-		Registerer: prometheus.DefaultRegisterer,
-		Gatherer:   prometheus.DefaultGatherer,
+		Registerer: reg,
+		Gatherer:   hg,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("new prometheus exporter: %w", err)
@@ -40,7 +66,7 @@ func NewPrometheusServer(addr string, metricsPath string, appName string) (*Prom
 
 	// register prometheus with opencensus
 	view.RegisterExporter(pe)
-	view.SetReportingPeriod(2 * time.Second)
+	view.SetReportingPeriod(defaultReportingPeriod)
 	return &PrometheusServer{
 		addr:        addr,
 		metricsPath: metricsPath,
@@ -48,53 +74,126 @@ func NewPrometheusServer(addr string, metricsPath string, appName string) (*Prom
 	}, nil
 }
 
+// Run listens on the server's address and serves metrics until the context is cancelled. The
+// address may be a comma separated list of addresses, such as "127.0.0.1:9090,[::1]:9090", to
+// listen on several at once. An address of the form "unix:/path/to/socket" listens on a unix
+// domain socket. If listening on any address fails, Run closes those already opened and returns
+// the error.
 func (p *PrometheusServer) Run(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.Handle(p.metricsPath, p.pe)
-	server := &http.Server{Addr: p.addr, Handler: mux}
+	var lns []net.Listener
+	for _, addr := range strings.Split(p.addr, ",") {
+		network, address := listenNetwork(strings.TrimSpace(addr))
+		ln, err := net.Listen(network, address)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return fmt.Errorf("listen: %w", err)
+		}
+		lns = append(lns, ln)
+	}
+	return p.ServeListeners(ctx, lns...)
+}
+
+// listenNetwork returns the network and address to listen on for addr, which is a TCP address or
+// a unix domain socket prefixed by "unix:".
+func listenNetwork(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return "unix", path
+	}
+	return "tcp", addr
+}
+
+// Serve serves metrics using connections accepted from ln until the context is cancelled.
+// Serve always closes ln before returning.
+func (p *PrometheusServer) Serve(ctx context.Context, ln net.Listener) error {
+	return p.ServeListeners(ctx, ln)
+}
+
+// ServeListeners serves metrics using connections accepted from each of lns until the context is
+// cancelled or serving any of the listeners fails, in which case the others are shut down too.
+// It returns the errors from the listeners that failed joined together, or http.ErrServerClosed
+// once the context is cancelled. ServeListeners always closes the listeners before returning.
+func (p *PrometheusServer) ServeListeners(ctx context.Context, lns ...net.Listener) error {
+	server := &http.Server{Addr: p.addr, Handler: p.handler()}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		<-ctx.Done()
 		if err := server.Shutdown(context.Background()); err != nil {
-			slog.Error("failed to shut down prometheus server", err)
+			slog.Error("failed to shut down prometheus server", "error", err)
 		}
 	}()
 
-	slog.Info("starting prometheus server", "addr", p.addr, "path", p.metricsPath)
-	return server.ListenAndServe()
+	errs := make(chan error, len(lns))
+	for _, ln := range lns {
+		slog.Info("starting prometheus server", "addr", ln.Addr().String(), "path", p.metricsPath, "tls", p.certFile != "")
+		go func(ln net.Listener) {
+			var err error
+			if p.certFile != "" {
+				err = server.ServeTLS(ln, p.certFile, p.keyFile)
+			} else {
+				err = server.Serve(ln)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				// Stop serving the other listeners
+				cancel()
+			}
+			errs <- err
+		}(ln)
+	}
+
+	var failed []error
+	for range lns {
+		if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+			failed = append(failed, err)
+		}
+	}
+	cancel()
+	<-stopped
+	if len(failed) > 0 {
+		return errors.Join(failed...)
+	}
+	return http.ErrServerClosed
+}
+
+// Close stops the server's exporter from receiving opencensus views. It does not stop a running
+// server, which is controlled by the context passed to Run.
+func (p *PrometheusServer) Close() error {
+	view.UnregisterExporter(p.pe)
+	return nil
 }
 
 func NewPrometheusCounter(name string, help string, labels map[string]string) (Counter, error) {
-	m := prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name:        name,
-			Help:        help,
-			ConstLabels: labels,
-		},
-	)
-	if err := prometheus.Register(m); err != nil {
-		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
-			m = are.ExistingCollector.(prometheus.Counter)
-		} else {
-			return nil, fmt.Errorf("register %s counter: %w", name, err)
-		}
+	m, err := getOrCreate(defaultCollectors, name, labels, func() prometheus.Counter {
+		return prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name:        name,
+				Help:        help,
+				ConstLabels: labels,
+			},
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("register %s counter: %w", name, err)
 	}
 	return m, nil
 }
 
 func NewPrometheusGauge(name string, help string, labels map[string]string) (Gauge, error) {
-	m := prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name:        name,
-			Help:        help,
-			ConstLabels: labels,
-		},
-	)
-	if err := prometheus.Register(m); err != nil {
-		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
-			m = are.ExistingCollector.(prometheus.Gauge)
-		} else {
-			return nil, fmt.Errorf("register %s gauge: %w", name, err)
-		}
+	m, err := getOrCreate(defaultCollectors, name, labels, func() prometheus.Gauge {
+		return prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name:        name,
+				Help:        help,
+				ConstLabels: labels,
+			},
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("register %s gauge: %w", name, err)
 	}
 	return m, nil
 }
@@ -0,0 +1,102 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRunMultipleAddrs(t *testing.T) {
+	// Unix socket paths are limited in length so avoid the long paths of t.TempDir
+	dir, err := os.MkdirTemp("", "prom")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socks := []string{filepath.Join(dir, "a.sock"), filepath.Join(dir, "b.sock")}
+
+	p, err := NewPrometheusServerWithRegistry("unix:"+socks[0]+", unix:"+socks[1], "/metrics", "test", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	for _, sock := range socks {
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", sock)
+			},
+		}}
+
+		var resp *http.Response
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			resp, err = client.Get("http://prom/metrics")
+			if err == nil || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("%s: get metrics: %v", sock, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: got status %d, wanted %d", sock, resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("got error %v, wanted %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestRunListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	// The second address is already in use so the first must be released
+	p, err := NewPrometheusServerWithRegistry("127.0.0.1:0,"+ln.Addr().String(), "/metrics", "test", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	defer p.Close()
+	if err := p.Run(context.Background()); err == nil {
+		t.Errorf("got no error, wanted listen error")
+	}
+}
+
+func TestNewPrometheusServerTwice(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		p, err := NewPrometheusServer("127.0.0.1:0", "/metrics", "test")
+		if err != nil {
+			t.Fatalf("server %d: unexpected error: %v", i+1, err)
+		}
+		defer p.Close()
+	}
+
+	reg := prometheus.NewRegistry()
+	for i := 0; i < 2; i++ {
+		p, err := NewPrometheusServerWithRegistry("127.0.0.1:0", "/metrics", "test", reg)
+		if err != nil {
+			t.Fatalf("server %d with registry: unexpected error: %v", i+1, err)
+		}
+		defer p.Close()
+	}
+}
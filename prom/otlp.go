@@ -0,0 +1,110 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+const (
+	// defaultOTLPInterval is the default interval between pushes of metrics to an OTLP collector.
+	defaultOTLPInterval = 30 * time.Second
+
+	// otlpShutdownTimeout limits the time taken by Run to make a final push when shutting down.
+	otlpShutdownTimeout = 5 * time.Second
+)
+
+// OTLPConfig holds the options for pushing metrics to an OTLP collector using gRPC.
+type OTLPConfig struct {
+	Endpoint string        `env:"PROM_OTLP_ENDPOINT" flag:"prom-otlp-endpoint" usage:"Address of an OTLP collector to push metrics to using gRPC"`
+	Insecure bool          `env:"PROM_OTLP_INSECURE" flag:"prom-otlp-insecure" usage:"Connect to the OTLP collector without TLS"`
+	Headers  string        `env:"PROM_OTLP_HEADERS" flag:"prom-otlp-headers" secret:"true" usage:"Comma separated key=value headers sent with each push, such as for authentication"`
+	Interval time.Duration `env:"PROM_OTLP_INTERVAL" flag:"prom-otlp-interval" default:"30s" usage:"Interval between pushes of metrics"`
+}
+
+// OTLPPusher periodically pushes the metrics gathered from a prometheus Gatherer to an OTLP
+// collector. It allows instrumented code to keep registering prometheus metrics in environments
+// that collect metrics using OTLP rather than by scraping.
+type OTLPPusher struct {
+	provider *sdkmetric.MeterProvider
+}
+
+// NewOTLPPusher returns an OTLPPusher that pushes metrics gathered from g, usually
+// prometheus.DefaultGatherer, as configured by cfg. Pushing starts immediately and continues
+// until Shutdown is called, or until the context passed to Run is cancelled.
+func NewOTLPPusher(ctx context.Context, g prometheus.Gatherer, cfg OTLPConfig) (*OTLPPusher, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("otlp endpoint must be set")
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultOTLPInterval
+	}
+
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("otlp headers: %w", err)
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(headers))
+	}
+	exp, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("new otlp exporter: %w", err)
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exp,
+		sdkmetric.WithInterval(interval),
+		sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(g))),
+	)
+	return &OTLPPusher{
+		provider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	}, nil
+}
+
+// parseHeaders parses a comma separated list of key=value pairs, such as
+// "authorization=Bearer abc,x-tenant=1". Whitespace around keys and values is ignored. Headers
+// often hold credentials, so errors identify a malformed pair by its position rather than its text.
+func parseHeaders(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	headers := make(map[string]string)
+	for i, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid header %d, expected key=value", i+1)
+		}
+		headers[k] = strings.TrimSpace(v)
+	}
+	return headers, nil
+}
+
+// Run waits until the context is cancelled and then shuts down the pusher, making a final push.
+// It returns any error from shutting down.
+func (p *OTLPPusher) Run(ctx context.Context) error {
+	<-ctx.Done()
+	sctx, cancel := context.WithTimeout(context.Background(), otlpShutdownTimeout)
+	defer cancel()
+	return p.Shutdown(sctx)
+}
+
+// Shutdown makes a final push of metrics and stops pushing.
+func (p *OTLPPusher) Shutdown(ctx context.Context) error {
+	if err := p.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown otlp pusher: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package prom
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/iand/pontium/envconf"
+)
+
+func TestNewOTLPPusherRequiresEndpoint(t *testing.T) {
+	if _, err := NewOTLPPusher(context.Background(), prometheus.NewRegistry(), OTLPConfig{}); err == nil {
+		t.Errorf("expected an error when no endpoint is configured")
+	}
+}
+
+func TestOTLPConfigFromEnv(t *testing.T) {
+	t.Setenv("PROM_OTLP_ENDPOINT", "collector:4317")
+	t.Setenv("PROM_OTLP_HEADERS", "authorization=Bearer abc, x-tenant=1")
+
+	var cfg OTLPConfig
+	if err := envconf.Load(&cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(headers) != 2 || headers["authorization"] != "Bearer abc" || headers["x-tenant"] != "1" {
+		t.Errorf("got headers %v", headers)
+	}
+
+	if _, err := parseHeaders("novalue"); err == nil {
+		t.Errorf("expected an error for a header without a value")
+	}
+	if _, err := parseHeaders("x-tenant=1,authorization:Bearer xyz"); err == nil || strings.Contains(err.Error(), "xyz") {
+		t.Errorf("got error %v, wanted one that does not reveal the header value", err)
+	}
+}
+
+// stubCollector is an OTLP metrics collector that records the requests it receives.
+type stubCollector struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*colmetricspb.ExportMetricsServiceRequest
+	metadata []metadata.MD
+}
+
+func (c *stubCollector) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	c.metadata = append(c.metadata, md)
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestOTLPPusherPush(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	col := &stubCollector{}
+	srv := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(srv, col)
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Stop()
+
+	reg := prometheus.NewRegistry()
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "otlp_test_total", Help: "Test counter."})
+	reg.MustRegister(c)
+	c.Add(7)
+
+	ctx := context.Background()
+	p, err := NewOTLPPusher(ctx, reg, OTLPConfig{
+		Endpoint: ln.Addr().String(),
+		Insecure: true,
+		Headers:  "x-tenant=acme",
+		Interval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Shutting down makes a final push
+	sctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := p.Shutdown(sctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	if len(col.requests) == 0 {
+		t.Fatalf("collector received no pushes")
+	}
+	if got := col.metadata[0].Get("x-tenant"); len(got) != 1 || got[0] != "acme" {
+		t.Errorf("got x-tenant header %v, wanted acme", got)
+	}
+	found := false
+	for _, rm := range col.requests[0].GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				if m.GetName() == "otlp_test_total" || m.GetName() == "otlp_test" {
+					found = true
+				}
+			}
+		}
+	}
+	if !found {
+		t.Errorf("push did not contain the test counter: %v", col.requests[0])
+	}
+}
@@ -0,0 +1,95 @@
+package prom
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultCollectors holds the counters and gauges created by NewPrometheusCounter and
+// NewPrometheusGauge, which are registered with the default registry.
+var defaultCollectors = &collectorRegistry{reg: prometheus.DefaultRegisterer}
+
+// collectorRegistry wraps a prometheus.Registerer, remembering the collectors it registers by
+// metric name and constant labels so that repeated requests for the same metric return the
+// collector created first. It is safe for concurrent use.
+type collectorRegistry struct {
+	reg prometheus.Registerer
+
+	mu         sync.Mutex
+	collectors map[string]prometheus.Collector
+}
+
+// getOrCreate returns the collector registered under name and labels, calling create to create and
+// register one if there is none. It returns an error if the existing collector is not a T or the
+// new collector cannot be registered.
+func getOrCreate[T prometheus.Collector](r *collectorRegistry, name string, labels map[string]string, create func() T) (T, error) {
+	key := collectorKey(name, labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var zero T
+	if c, ok := r.collectors[key]; ok {
+		existing, ok := c.(T)
+		if !ok {
+			return zero, fmt.Errorf("%s already registered as a different type of collector (%T)", name, c)
+		}
+		return existing, nil
+	}
+
+	// The collector may have been registered directly with the registerer
+	c, err := registerOrExistingWith(r.reg, create())
+	if err != nil {
+		return zero, err
+	}
+
+	if r.collectors == nil {
+		r.collectors = make(map[string]prometheus.Collector)
+	}
+	r.collectors[key] = c
+	return c, nil
+}
+
+// registerOrExisting registers c with the default registry. If an equivalent collector of the
+// same type is already registered then it is returned instead of c, while an equivalent collector
+// of a different type is reported as an error.
+func registerOrExisting[T prometheus.Collector](c T) (T, error) {
+	return registerOrExistingWith(prometheus.DefaultRegisterer, c)
+}
+
+// registerOrExistingWith is like registerOrExisting but registers c with reg.
+func registerOrExistingWith[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			existing, ok := are.ExistingCollector.(T)
+			if !ok {
+				return c, fmt.Errorf("already registered as a different type of collector (%T): %w", are.ExistingCollector, err)
+			}
+			return existing, nil
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+// collectorKey returns a key identifying a metric by its name and constant labels.
+func collectorKey(name string, labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range names {
+		fmt.Fprintf(&b, ",%s=%q", k, labels[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package prom
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGetOrCreate(t *testing.T) {
+	r := &collectorRegistry{reg: prometheus.NewRegistry()}
+	newCounter := func() prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{Name: "jobs_total", Help: "Jobs.", ConstLabels: prometheus.Labels{"a": "1"}})
+	}
+
+	var wg sync.WaitGroup
+	counters := make([]prometheus.Counter, 10)
+	for i := range counters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := getOrCreate(r, "jobs_total", map[string]string{"a": "1"}, newCounter)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			counters[i] = c
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range counters[1:] {
+		if c != counters[0] {
+			t.Fatalf("got distinct counters for the same metric")
+		}
+	}
+
+	_, err := getOrCreate(r, "jobs_total", map[string]string{"a": "1"}, func() prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{Name: "jobs_total", Help: "Jobs.", ConstLabels: prometheus.Labels{"a": "1"}})
+	})
+	if err == nil {
+		t.Errorf("got no error when requesting a gauge registered as a counter")
+	}
+}
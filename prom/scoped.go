@@ -0,0 +1,30 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterScoped registers c with the default registry for as long as ctx is active, unregistering
+// it when ctx is cancelled. This suits collectors that belong to a tenant, job or connection in a
+// long-running process, whose series should disappear along with the work they describe rather
+// than leak as stale entries in the registry.
+func RegisterScoped(ctx context.Context, c prometheus.Collector) error {
+	return RegisterScopedWith(ctx, prometheus.DefaultRegisterer, c)
+}
+
+// RegisterScopedWith is like RegisterScoped but registers c with reg.
+func RegisterScopedWith(ctx context.Context, reg prometheus.Registerer, c prometheus.Collector) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := reg.Register(c); err != nil {
+		return fmt.Errorf("register scoped collector: %w", err)
+	}
+	context.AfterFunc(ctx, func() {
+		reg.Unregister(c)
+	})
+	return nil
+}
@@ -0,0 +1,42 @@
+package prom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/iand/pontium/prom"
+	"github.com/iand/pontium/test"
+)
+
+func TestRegisterScoped(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "job_items_total", Help: "Items processed by the job."})
+	if err := prom.RegisterScopedWith(ctx, reg, c); err != nil {
+		t.Fatalf("RegisterScopedWith: %v", err)
+	}
+	c.Inc()
+
+	count := func() any {
+		n, err := testutil.GatherAndCount(reg, "job_items_total")
+		if err != nil {
+			t.Fatalf("gather: %v", err)
+		}
+		return n
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("got %d series while scope active, wanted 1", n)
+	}
+
+	cancel()
+	test.EventuallyEqual(t, 0, count, time.Second)
+
+	if err := prom.RegisterScopedWith(ctx, reg, c); err == nil {
+		t.Errorf("registered with cancelled context")
+	}
+}
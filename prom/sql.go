@@ -0,0 +1,205 @@
+package prom
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of database queries.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"db", "query"})
+
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Number of database queries that returned an error, excluding sql.ErrNoRows.",
+	}, []string{"db", "query"})
+)
+
+// DB wraps a *sql.DB, recording the duration of queries and the number that fail as Prometheus
+// metrics labelled with the database name and a query name. The query name is taken from the
+// context, when set using WithQueryName, and otherwise derived from the leading SQL keyword of the
+// query, such as "select" or "insert". Methods not overridden by DB are passed through to the
+// underlying *sql.DB without recording metrics.
+type DB struct {
+	*sql.DB
+	name     string
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// InstrumentDB returns a DB that records metrics for queries made through it using the given
+// database name as a label. It also registers a collector that exports the connection pool
+// statistics of db.
+func InstrumentDB(db *sql.DB, name string) (*DB, error) {
+	duration, err := registerOrExisting(dbQueryDuration)
+	if err != nil {
+		return nil, fmt.Errorf("register db query metrics: %w", err)
+	}
+	errs, err := registerOrExisting(dbQueryErrors)
+	if err != nil {
+		return nil, fmt.Errorf("register db query metrics: %w", err)
+	}
+	if err := prometheus.Register(NewDBStatsCollector(db, name)); err != nil {
+		return nil, fmt.Errorf("register db stats collector for %s: %w", name, err)
+	}
+	return &DB{DB: db, name: name, duration: duration, errors: errs}, nil
+}
+
+// ExecContext executes a query without returning any rows, recording its duration and outcome.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	db.observe(ctx, query, start, err)
+	return res, err
+}
+
+// QueryContext executes a query that returns rows, recording its duration and outcome. The
+// duration excludes the time taken to iterate over the rows.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	db.observe(ctx, query, start, err)
+	return rows, err
+}
+
+// QueryRowContext executes a query that is expected to return at most one row, recording its
+// duration and outcome.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	db.observe(ctx, query, start, row.Err())
+	return row
+}
+
+// Observe runs fn, recording its duration and outcome under the given query name. It may be used
+// to instrument transactions or other operations that are not made through the methods of DB.
+func (db *DB) Observe(ctx context.Context, name string, fn func(context.Context) error) error {
+	start := time.Now()
+	err := fn(ctx)
+	db.observe(WithQueryName(ctx, name), "", start, err)
+	return err
+}
+
+func (db *DB) observe(ctx context.Context, query string, start time.Time, err error) {
+	name := queryName(ctx, query)
+	db.duration.WithLabelValues(db.name, name).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		db.errors.WithLabelValues(db.name, name).Inc()
+	}
+}
+
+type ctxQueryNameKey struct{}
+
+// WithQueryName returns a copy of ctx that causes queries made with it through a DB to be
+// recorded using the given name. The name is sanitized for use as a label value: it is converted
+// to lower case, characters other than letters, digits and underscores are replaced by
+// underscores and it is truncated to 64 characters.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ctxQueryNameKey{}, sanitizeQueryName(name))
+}
+
+// queryName returns the name to use for the query in metric labels.
+func queryName(ctx context.Context, query string) string {
+	if name, ok := ctx.Value(ctxQueryNameKey{}).(string); ok && name != "" {
+		return name
+	}
+	verb, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	switch verb = strings.ToLower(verb); verb {
+	case "select", "insert", "update", "delete", "with", "create", "alter", "drop", "begin", "commit", "rollback":
+		return verb
+	default:
+		return "other"
+	}
+}
+
+const maxQueryNameLen = 64
+
+func sanitizeQueryName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if b.Len() >= maxQueryNameLen {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// DBStatsCollector is a prometheus.Collector that exports the connection pool statistics of a
+// *sql.DB.
+type DBStatsCollector struct {
+	db *sql.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*DBStatsCollector)(nil)
+
+// NewDBStatsCollector returns a collector for the connection pool statistics of db, labelled with
+// the given database name.
+func NewDBStatsCollector(db *sql.DB, name string) *DBStatsCollector {
+	labels := prometheus.Labels{"db": name}
+	desc := func(n, help string) *prometheus.Desc {
+		return prometheus.NewDesc(n, help, nil, labels)
+	}
+	return &DBStatsCollector{
+		db:                db,
+		maxOpen:           desc("db_connections_max_open", "Maximum number of open connections to the database."),
+		open:              desc("db_connections_open", "Number of established connections, both in use and idle."),
+		inUse:             desc("db_connections_in_use", "Number of connections currently in use."),
+		idle:              desc("db_connections_idle", "Number of idle connections."),
+		waitCount:         desc("db_connections_wait_total", "Total number of connections waited for."),
+		waitDuration:      desc("db_connections_wait_seconds_total", "Total time spent waiting for a new connection."),
+		maxIdleClosed:     desc("db_connections_max_idle_closed_total", "Total number of connections closed due to the maximum idle connections limit."),
+		maxIdleTimeClosed: desc("db_connections_max_idle_time_closed_total", "Total number of connections closed due to the maximum idle time limit."),
+		maxLifetimeClosed: desc("db_connections_max_lifetime_closed_total", "Total number of connections closed due to the maximum connection lifetime limit."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *DBStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector.
+func (c *DBStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(s.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(s.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(s.MaxLifetimeClosed))
+}
@@ -0,0 +1,108 @@
+package prom
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestQueryName(t *testing.T) {
+	testCases := []struct {
+		ctx   context.Context
+		query string
+		want  string
+	}{
+		{ctx: context.Background(), query: "SELECT * FROM users", want: "select"},
+		{ctx: context.Background(), query: "  insert into users values (1)", want: "insert"},
+		{ctx: context.Background(), query: "VACUUM", want: "other"},
+		{ctx: WithQueryName(context.Background(), "Load User-By.ID"), query: "SELECT 1", want: "load_user_by_id"},
+	}
+
+	for _, tc := range testCases {
+		if got := queryName(tc.ctx, tc.query); got != tc.want {
+			t.Errorf("%q: got %q, wanted %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+// stubDriver is a database driver whose statements succeed unless their query contains "fail".
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(query string) (driver.Stmt, error) { return stubStmt{query: query}, nil }
+func (stubConn) Close() error                              { return nil }
+func (stubConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type stubStmt struct {
+	query string
+}
+
+func (s stubStmt) Close() error  { return nil }
+func (s stubStmt) NumInput() int { return -1 }
+
+func (s stubStmt) Exec([]driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("query failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s stubStmt) Query([]driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("query failed")
+	}
+	return stubRows{}, nil
+}
+
+type stubRows struct{}
+
+func (stubRows) Columns() []string         { return []string{"n"} }
+func (stubRows) Close() error              { return nil }
+func (stubRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("promstub", stubDriver{})
+}
+
+func TestInstrumentDB(t *testing.T) {
+	sdb, err := sql.Open("promstub", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer sdb.Close()
+
+	db, err := InstrumentDB(sdb, "instrumented")
+	if err != nil {
+		t.Fatalf("InstrumentDB: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "INSERT INTO jobs VALUES (1)"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM jobs WHERE fail"); err == nil {
+		t.Fatalf("got no error from failing exec")
+	}
+	if err := db.QueryRowContext(WithQueryName(ctx, "load"), "SELECT n FROM jobs").Scan(new(int)); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("got error %v, wanted %v", err, sql.ErrNoRows)
+	}
+
+	if got := testutil.CollectAndCount(db.duration); got != 3 {
+		t.Errorf("got %d query duration series, wanted 3", got)
+	}
+	if got := testutil.ToFloat64(db.errors.WithLabelValues("instrumented", "delete")); got != 1 {
+		t.Errorf("got %v delete errors, wanted 1", got)
+	}
+	if got := testutil.ToFloat64(db.errors.WithLabelValues("instrumented", "load")); got != 0 {
+		t.Errorf("got %v errors for a query with no rows, wanted 0", got)
+	}
+}
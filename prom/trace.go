@@ -0,0 +1,38 @@
+package prom
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
+	"go.opencensus.io/trace"
+)
+
+// traceResponseHeader is the W3C Trace Context response header used to report the trace that a
+// scrape was served under.
+const traceResponseHeader = "Traceresponse"
+
+// withScrapeTrace wraps a metrics handler so that scrapes made while a trace is active, such as
+// those triggered manually while debugging, can be correlated with collector-side traces. The
+// trace is taken from the request context or, failing that, from the request's W3C traceparent
+// header. When a trace is found its context is returned in the traceresponse header and the
+// scrape is logged with its trace id.
+func withScrapeTrace(next http.Handler) http.Handler {
+	format := &tracecontext.HTTPFormat{}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sc trace.SpanContext
+		ok := false
+		if span := trace.FromContext(r.Context()); span != nil {
+			sc, ok = span.SpanContext(), true
+		} else {
+			sc, ok = format.SpanContextFromRequest(r)
+		}
+
+		if ok {
+			tp, _ := format.SpanContextToHeaders(sc)
+			w.Header().Set(traceResponseHeader, tp)
+			slog.Info("serving traced metrics scrape", "trace_id", sc.TraceID.String(), "remote_addr", r.RemoteAddr)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,28 @@
+package prom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithScrapeTrace(t *testing.T) {
+	h := withScrapeTrace(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(traceResponseHeader); !strings.Contains(got, traceID) {
+		t.Errorf("got traceresponse header %q, wanted it to contain trace id %s", got, traceID)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Header().Get(traceResponseHeader); got != "" {
+		t.Errorf("got traceresponse header %q for untraced scrape, wanted none", got)
+	}
+}
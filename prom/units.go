@@ -0,0 +1,116 @@
+package prom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus convention is for metrics to be recorded in base units, with the unit as a suffix of
+// the metric name. The helpers in this file enforce the convention for the most commonly confused
+// units so that dashboards do not end up mixing milliseconds with seconds.
+
+// nonBaseTimeSuffixes are suffixes that indicate a time unit other than seconds.
+var nonBaseTimeSuffixes = []string{"_ns", "_nanoseconds", "_us", "_microseconds", "_ms", "_millis", "_milliseconds", "_minutes", "_hours", "_days"}
+
+// nonBaseSizeSuffixes are suffixes that indicate a size unit other than bytes.
+var nonBaseSizeSuffixes = []string{"_bits", "_kb", "_kib", "_kilobytes", "_mb", "_mib", "_megabytes", "_gb", "_gib", "_gigabytes"}
+
+// SecondsHistogram is a histogram of durations. Values are observed as time.Duration and recorded
+// in seconds.
+type SecondsHistogram struct {
+	h prometheus.Histogram
+}
+
+// Seconds returns a SecondsHistogram created from opts and registered with the default registry.
+// The suffix "_seconds" is appended to the name if it is not already present. An error is
+// returned if the name has a suffix indicating a different unit of time, such as "_ms".
+func Seconds(opts prometheus.HistogramOpts) (*SecondsHistogram, error) {
+	name, err := withUnit(opts.Name, "_seconds", nonBaseTimeSuffixes)
+	if err != nil {
+		return nil, err
+	}
+	opts.Name = name
+	h, err := registerOrExisting(prometheus.NewHistogram(opts))
+	if err != nil {
+		return nil, fmt.Errorf("register %s histogram: %w", name, err)
+	}
+	return &SecondsHistogram{h: h}, nil
+}
+
+// Observe records a duration.
+func (s *SecondsHistogram) Observe(d time.Duration) {
+	s.h.Observe(d.Seconds())
+}
+
+// ObserveSince records the time elapsed since start.
+func (s *SecondsHistogram) ObserveSince(start time.Time) {
+	s.h.Observe(time.Since(start).Seconds())
+}
+
+// Histogram returns the underlying prometheus histogram.
+func (s *SecondsHistogram) Histogram() prometheus.Histogram {
+	return s.h
+}
+
+// BytesHistogram is a histogram of sizes recorded in bytes.
+type BytesHistogram struct {
+	h prometheus.Histogram
+}
+
+// Bytes returns a BytesHistogram created from opts and registered with the default registry.
+// The suffix "_bytes" is appended to the name if it is not already present. An error is returned
+// if the name has a suffix indicating a different unit of size, such as "_kb".
+func Bytes(opts prometheus.HistogramOpts) (*BytesHistogram, error) {
+	name, err := withUnit(opts.Name, "_bytes", nonBaseSizeSuffixes)
+	if err != nil {
+		return nil, err
+	}
+	opts.Name = name
+	h, err := registerOrExisting(prometheus.NewHistogram(opts))
+	if err != nil {
+		return nil, fmt.Errorf("register %s histogram: %w", name, err)
+	}
+	return &BytesHistogram{h: h}, nil
+}
+
+// Observe records a size in bytes.
+func (b *BytesHistogram) Observe(n int64) {
+	b.h.Observe(float64(n))
+}
+
+// Histogram returns the underlying prometheus histogram.
+func (b *BytesHistogram) Histogram() prometheus.Histogram {
+	return b.h
+}
+
+// Total returns a counter created from opts and registered with the default registry. The suffix
+// "_total" is appended to the name if it is not already present.
+func Total(opts prometheus.CounterOpts) (Counter, error) {
+	name, err := withUnit(opts.Name, "_total", nil)
+	if err != nil {
+		return nil, err
+	}
+	opts.Name = name
+	c, err := registerOrExisting(prometheus.NewCounter(opts))
+	if err != nil {
+		return nil, fmt.Errorf("register %s counter: %w", name, err)
+	}
+	return c, nil
+}
+
+// withUnit returns name with the unit suffix appended if it is not already present. It returns an
+// error if name ends with one of the disallowed suffixes.
+func withUnit(name string, unit string, disallowed []string) (string, error) {
+	if strings.HasSuffix(name, unit) {
+		return name, nil
+	}
+	for _, s := range disallowed {
+		if strings.HasSuffix(name, s) {
+			return "", fmt.Errorf("metric name %q has unit suffix %q, must be recorded in base unit %q", name, s, strings.TrimPrefix(unit, "_"))
+		}
+	}
+	return name + unit, nil
+}
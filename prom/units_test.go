@@ -0,0 +1,41 @@
+package prom
+
+import "testing"
+
+func TestWithUnit(t *testing.T) {
+	testCases := []struct {
+		name    string
+		unit    string
+		want    string
+		wantErr bool
+	}{
+		{name: "request_duration", unit: "_seconds", want: "request_duration_seconds"},
+		{name: "request_duration_seconds", unit: "_seconds", want: "request_duration_seconds"},
+		{name: "request_duration_ms", unit: "_seconds", wantErr: true},
+		{name: "payload", unit: "_bytes", want: "payload_bytes"},
+		{name: "payload_kb", unit: "_bytes", wantErr: true},
+		{name: "requests", unit: "_total", want: "requests_total"},
+	}
+
+	disallowed := map[string][]string{
+		"_seconds": nonBaseTimeSuffixes,
+		"_bytes":   nonBaseSizeSuffixes,
+	}
+
+	for _, tc := range testCases {
+		got, err := withUnit(tc.name, tc.unit, disallowed[tc.unit])
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, wanted %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/iand/pontium/wait"
+)
+
+// A Rule is a threshold condition evaluated by a Watcher.
+type Rule struct {
+	// Name identifies the rule in log records.
+	Name string
+
+	// Metric is the name of the metric family to evaluate. The values of all series in the family
+	// that match Labels are summed. Counters, gauges and untyped metrics are supported.
+	Metric string
+
+	// Labels optionally restricts the series that are evaluated to those with these label values.
+	Labels map[string]string
+
+	// Rate causes the rule to evaluate the per-second rate of change of the metric between
+	// successive evaluations rather than its value. This is usually wanted for counters.
+	Rate bool
+
+	// Above is the threshold. The rule is breached while the evaluated value is greater than Above.
+	Above float64
+
+	// Level is the level of the record logged when the rule is breached. The zero value logs
+	// at info level, so most rules should set it to slog.LevelWarn or slog.LevelError.
+	Level slog.Level
+}
+
+// Watcher periodically evaluates threshold rules against gathered metrics and logs a record when
+// a rule becomes breached and when it recovers. It is a lightweight substitute for alerting in
+// development and single node deployments that have no prometheus server.
+type Watcher struct {
+	g        prometheus.Gatherer
+	interval time.Duration
+	rules    []Rule
+	logger   *slog.Logger
+
+	prev     map[int]float64 // previous value of each rate rule, indexed by rule
+	prevTime time.Time
+	breached map[int]bool
+}
+
+// NewWatcher returns a Watcher that evaluates rules against metrics gathered from g every interval
+// and logs using the default slog logger.
+func NewWatcher(g prometheus.Gatherer, interval time.Duration, rules ...Rule) *Watcher {
+	return &Watcher{
+		g:        g,
+		interval: interval,
+		rules:    rules,
+		logger:   slog.Default(),
+		prev:     make(map[int]float64),
+		breached: make(map[int]bool),
+	}
+}
+
+// Run evaluates the watcher's rules every interval until the context is cancelled, when it returns
+// the context's error. Errors gathering metrics are logged and do not stop the watcher.
+func (w *Watcher) Run(ctx context.Context) error {
+	return wait.Forever(ctx, func(ctx context.Context) error {
+		if err := w.evaluate(ctx, time.Now()); err != nil {
+			w.logger.ErrorContext(ctx, "watcher failed to gather metrics", "error", err)
+		}
+		return nil
+	}, 0, w.interval, 0)
+}
+
+// evaluate gathers metrics and evaluates each rule, logging changes in whether rules are breached.
+func (w *Watcher) evaluate(ctx context.Context, now time.Time) error {
+	mfs, err := w.g.Gather()
+	if err != nil {
+		return fmt.Errorf("gather: %w", err)
+	}
+	families := make(map[string]*dto.MetricFamily, len(mfs))
+	for _, mf := range mfs {
+		families[mf.GetName()] = mf
+	}
+
+	elapsed := now.Sub(w.prevTime).Seconds()
+	first := w.prevTime.IsZero()
+	w.prevTime = now
+
+	for i, r := range w.rules {
+		mf, ok := families[r.Metric]
+		if !ok {
+			continue
+		}
+		v := sumSeries(mf, r.Labels)
+		if r.Rate {
+			prev, seen := w.prev[i]
+			w.prev[i] = v
+			if first || !seen || elapsed <= 0 {
+				continue
+			}
+			v = (v - prev) / elapsed
+		}
+
+		breached := v > r.Above
+		if breached == w.breached[i] {
+			continue
+		}
+		w.breached[i] = breached
+		if breached {
+			w.logger.Log(ctx, r.Level, "metric threshold breached", "rule", r.Name, "metric", r.Metric, "value", v, "threshold", r.Above)
+		} else {
+			w.logger.InfoContext(ctx, "metric threshold recovered", "rule", r.Name, "metric", r.Metric, "value", v, "threshold", r.Above)
+		}
+	}
+	return nil
+}
+
+// sumSeries returns the sum of the values of the series in mf that have the given label values.
+func sumSeries(mf *dto.MetricFamily, labels map[string]string) float64 {
+	sum := 0.0
+	for _, m := range mf.GetMetric() {
+		if !hasLabels(m, labels) {
+			continue
+		}
+		switch {
+		case m.Counter != nil:
+			sum += m.Counter.GetValue()
+		case m.Gauge != nil:
+			sum += m.Gauge.GetValue()
+		case m.Untyped != nil:
+			sum += m.Untyped.GetValue()
+		}
+	}
+	return sum
+}
+
+// hasLabels reports whether m has all of the given label values.
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, lp := range m.GetLabel() {
+		if v, ok := labels[lp.GetName()]; ok {
+			if v != lp.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
@@ -0,0 +1,54 @@
+package prom
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWatcher(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	errs := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors_total"}, []string{"kind"})
+	reg.MustRegister(errs)
+
+	var buf bytes.Buffer
+	w := NewWatcher(reg, time.Second, Rule{
+		Name:   "error rate",
+		Metric: "errors_total",
+		Labels: map[string]string{"kind": "db"},
+		Rate:   true,
+		Above:  5,
+		Level:  slog.LevelWarn,
+	})
+	w.logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	ctx := context.Background()
+	now := time.Now()
+	errs.WithLabelValues("db").Add(1)
+	evaluate := func() {
+		t.Helper()
+		now = now.Add(time.Second)
+		if err := w.evaluate(ctx, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	evaluate()
+	errs.WithLabelValues("db").Add(10)
+	errs.WithLabelValues("http").Add(100)
+	evaluate()
+	if !strings.Contains(buf.String(), "metric threshold breached") {
+		t.Errorf("expected breach to be logged, got %q", buf.String())
+	}
+
+	buf.Reset()
+	evaluate()
+	if !strings.Contains(buf.String(), "metric threshold recovered") {
+		t.Errorf("expected recovery to be logged, got %q", buf.String())
+	}
+}
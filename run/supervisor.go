@@ -0,0 +1,341 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/iand/pontium/health"
+	"github.com/iand/pontium/wait"
+)
+
+// RestartPolicy determines whether a Supervisor restarts a service that returns while the
+// supervisor is running.
+type RestartPolicy int
+
+const (
+	// RestartNever stops the supervisor when the service returns. This is the default.
+	RestartNever RestartPolicy = iota
+
+	// RestartOnFailure restarts the service when it returns an error and stops the supervisor when
+	// it returns nil.
+	RestartOnFailure
+
+	// RestartAlways restarts the service whenever it returns.
+	RestartAlways
+)
+
+// defaultRestartReset is how long a service must run before its restarts are forgotten when none
+// is configured.
+const defaultRestartReset = time.Minute
+
+// defaultRestartBackoff is the delay between restarts of a service when none is configured.
+var defaultRestartBackoff = wait.ExponentialBackoff{Initial: time.Second, Multiplier: 2, Max: time.Minute, Jitter: 0.1}
+
+// ServiceOptions configures how a Supervisor starts and stops a service.
+type ServiceOptions struct {
+	// DependsOn lists the names of services that must be started before this one and stopped
+	// after it.
+	DependsOn []string
+
+	// Ready optionally reports when the service has started, for example by waiting for it to
+	// accept connections. Services that depend on this one are not started until Ready returns
+	// nil. If Ready is nil the service is considered started as soon as it is running.
+	Ready func(context.Context) error
+
+	// StartTimeout limits the time allowed for Ready to succeed. Zero means no limit.
+	StartTimeout time.Duration
+
+	// Critical marks the service as critical to the readiness of the program. When the
+	// supervisor reports to a health.Health, the program is only ready while all critical
+	// services are ready.
+	Critical bool
+
+	// Restart determines whether the service is restarted when it returns while the supervisor
+	// is running.
+	Restart RestartPolicy
+
+	// RestartBackoff gives the delay before each consecutive restart of the service. If nil, an
+	// exponential backoff from one second up to one minute is used.
+	RestartBackoff wait.BackoffPolicy
+
+	// MaxRestarts limits the number of times the service may be restarted. Once the limit is
+	// exceeded the service's last error is reported and the supervisor stops all services. Zero
+	// means no limit.
+	MaxRestarts int
+
+	// RestartReset is how long the service must run before returning for the return to be
+	// treated as a new failure rather than a consecutive one. A service that runs for at least
+	// this long has its restart count, and so its backoff, reset. If zero, one minute is used.
+	RestartReset time.Duration
+
+	// StopTimeout limits the time allowed for the service to return after its context is
+	// cancelled. Zero means no limit. A service that exceeds its limit is abandoned and an error
+	// reported so that the remaining services can still be stopped.
+	StopTimeout time.Duration
+}
+
+// Supervisor runs a set of services that depend on one another. Services are started in an order
+// that respects their dependencies and stopped in the reverse order, so that, for example, an
+// application server drains its requests before the database pool it uses is closed.
+//
+// A Supervisor must be created with NewSupervisor.
+type Supervisor struct {
+	services []*service
+	byName   map[string]*service
+	health   *health.Health
+}
+
+type service struct {
+	name string
+	r    Runnable
+	opts ServiceOptions
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error // valid once done is closed
+}
+
+// NewSupervisor returns a new Supervisor with no services.
+func NewSupervisor() *Supervisor {
+	return &Supervisor{byName: make(map[string]*service)}
+}
+
+// Add registers a service under name. It returns an error if a service with the same name has
+// already been registered. Dependencies are checked when the supervisor is run.
+func (s *Supervisor) Add(name string, r Runnable, opts ServiceOptions) error {
+	if _, exists := s.byName[name]; exists {
+		return fmt.Errorf("service %q already registered", name)
+	}
+	svc := &service{name: name, r: r, opts: opts}
+	s.services = append(s.services, svc)
+	s.byName[name] = svc
+	return nil
+}
+
+// ReportHealth causes the supervisor to report the readiness of each service to h as a component
+// with the service's name. A service is ready once it has started, including waiting for its Ready
+// function, and until it returns or is stopped. ReportHealth must be called before Run.
+func (s *Supervisor) ReportHealth(h *health.Health) {
+	s.health = h
+}
+
+// Run starts each service in dependency order, waiting for it to become ready before starting the
+// services that depend on it, and then waits until the context is cancelled or any service returns.
+// It then stops the services that were started in the reverse order. Run returns an error if the
+// dependencies cannot be satisfied, containing any cycle, or a join of the errors returned by
+// services and any failures to start or stop them. Errors caused by the supervisor cancelling a
+// service are omitted.
+func (s *Supervisor) Run(ctx context.Context) error {
+	order, err := s.startOrder()
+	if err != nil {
+		return err
+	}
+
+	if s.health != nil {
+		for _, svc := range order {
+			s.health.Register(svc.name, svc.opts.Critical)
+		}
+	}
+
+	exited := make(chan *service, len(order))
+	var errs []error
+	var started []*service
+
+	for _, svc := range order {
+		svc.start(ctx, exited, s.health)
+		started = append(started, svc)
+		if err := svc.waitReady(ctx); err != nil {
+			// Cancellation during startup is a request to stop rather than a failure
+			if ctx.Err() == nil {
+				errs = append(errs, err)
+			}
+			break
+		}
+		if s.health != nil {
+			s.health.SetReady(svc.name, true)
+		}
+	}
+
+	if len(errs) == 0 && len(started) == len(order) {
+		select {
+		case <-ctx.Done():
+		case <-exited:
+		}
+	}
+
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := started[i].stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startOrder returns the services sorted so that each follows the services it depends on. Services
+// without a dependency between them keep their registration order.
+func (s *Supervisor) startOrder() ([]*service, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(s.services))
+	order := make([]*service, 0, len(s.services))
+
+	var visit func(svc *service, path []string) error
+	visit = func(svc *service, path []string) error {
+		switch state[svc.name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), svc.name)
+		}
+		state[svc.name] = visiting
+		path = append(path, svc.name)
+		for _, dep := range svc.opts.DependsOn {
+			d, ok := s.byName[dep]
+			if !ok {
+				return fmt.Errorf("service %q depends on unknown service %q", svc.name, dep)
+			}
+			if err := visit(d, path); err != nil {
+				return err
+			}
+		}
+		state[svc.name] = visited
+		order = append(order, svc)
+		return nil
+	}
+
+	for _, svc := range s.services {
+		if err := visit(svc, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// start runs the service in a new goroutine, restarting it according to its restart policy, and
+// sends it on exited once it returns for the last time. The service is reported as not ready to h,
+// if not nil, whenever it returns. The service's context is not cancelled when ctx is, so that the
+// supervisor controls the order of shutdown, but it carries the values of ctx.
+func (svc *service) start(ctx context.Context, exited chan<- *service, h *health.Health) {
+	sctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	svc.cancel = cancel
+	svc.done = make(chan struct{})
+	go func() {
+		defer func() {
+			close(svc.done)
+			exited <- svc
+		}()
+
+		reset := svc.opts.RestartReset
+		if reset <= 0 {
+			reset = defaultRestartReset
+		}
+		for restarts := 0; ; restarts++ {
+			started := time.Now()
+			err := svc.r.Run(sctx)
+			if h != nil {
+				h.SetReady(svc.name, false)
+			}
+			if sctx.Err() != nil || !svc.shouldRestart(err) {
+				svc.err = err
+				return
+			}
+			if time.Since(started) >= reset {
+				// The service was stable, so this is not a consecutive failure
+				restarts = 0
+			}
+			if svc.opts.MaxRestarts > 0 && restarts >= svc.opts.MaxRestarts {
+				svc.err = fmt.Errorf("exceeded %d restarts: %w", svc.opts.MaxRestarts, err)
+				return
+			}
+
+			backoff := svc.opts.RestartBackoff
+			if backoff == nil {
+				backoff = defaultRestartBackoff
+			}
+			delay := backoff.Delay(restarts + 1)
+			slog.Warn("restarting service", "service", svc.name, "error", err, "restarts", restarts, "delay", delay)
+			if err := wait.WithJitter(sctx, delay, 0); err != nil {
+				return
+			}
+			if h != nil {
+				go svc.reportReady(sctx, h)
+			}
+		}
+	}()
+}
+
+// shouldRestart reports whether the service's restart policy calls for a restart after it returned
+// err.
+func (svc *service) shouldRestart(err error) bool {
+	switch svc.opts.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// reportReady reports the service as ready to h once it has started after a restart.
+func (svc *service) reportReady(ctx context.Context, h *health.Health) {
+	if err := svc.waitReady(ctx); err == nil && ctx.Err() == nil {
+		h.SetReady(svc.name, true)
+	}
+}
+
+// waitReady waits until the service reports that it is ready, it returns, its start timeout
+// passes or the context is cancelled.
+func (svc *service) waitReady(ctx context.Context) error {
+	if svc.opts.Ready == nil {
+		return nil
+	}
+	if svc.opts.StartTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, svc.opts.StartTimeout)
+		defer cancel()
+	}
+
+	ready := make(chan error, 1)
+	go func() { ready <- svc.opts.Ready(ctx) }()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			return fmt.Errorf("start %s: %w", svc.name, err)
+		}
+		return nil
+	case <-svc.done:
+		return fmt.Errorf("start %s: service returned before it was ready", svc.name)
+	}
+}
+
+// stop cancels the service's context and waits for it to return, up to its stop timeout.
+func (svc *service) stop() error {
+	svc.cancel()
+
+	var timeout <-chan time.Time
+	if svc.opts.StopTimeout > 0 {
+		t := time.NewTimer(svc.opts.StopTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case <-svc.done:
+	case <-timeout:
+		return fmt.Errorf("stop %s: did not stop within %v", svc.name, svc.opts.StopTimeout)
+	}
+
+	if svc.err != nil && !errors.Is(svc.err, context.Canceled) {
+		return fmt.Errorf("%s: %w", svc.name, svc.err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iand/pontium/health"
+	"github.com/iand/pontium/test"
+	"github.com/iand/pontium/wait"
+)
+
+// eventLog records the order in which services start and stop.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(e string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+func (l *eventLog) service(name string) Runnable {
+	return RunnableFunc(func(ctx context.Context) error {
+		l.add("start " + name)
+		<-ctx.Done()
+		l.add("stop " + name)
+		return ctx.Err()
+	})
+}
+
+// started returns a readiness check that waits until the named service has recorded its start.
+func (l *eventLog) started(name string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			l.mu.Lock()
+			for _, e := range l.events {
+				if e == "start "+name {
+					l.mu.Unlock()
+					return nil
+				}
+			}
+			l.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+}
+
+func TestSupervisorOrder(t *testing.T) {
+	var log eventLog
+	s := NewSupervisor()
+	s.Add("app", log.service("app"), ServiceOptions{DependsOn: []string{"db", "metrics"}, Ready: log.started("app")})
+	s.Add("metrics", log.service("metrics"), ServiceOptions{Ready: log.started("metrics")})
+	s.Add("db", log.service("db"), ServiceOptions{Ready: log.started("db"), StartTimeout: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	if err := log.started("app")(context.Background()); err != nil {
+		t.Fatalf("app did not start: %v", err)
+	}
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"start db", "start metrics", "start app", "stop app", "stop metrics", "stop db"}
+	if !reflect.DeepEqual(log.events, want) {
+		t.Errorf("got events %v, wanted %v", log.events, want)
+	}
+}
+
+func TestSupervisorCycle(t *testing.T) {
+	var log eventLog
+	s := NewSupervisor()
+	s.Add("a", log.service("a"), ServiceOptions{DependsOn: []string{"b"}})
+	s.Add("b", log.service("b"), ServiceOptions{DependsOn: []string{"a"}})
+
+	err := s.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("got error %v, wanted a dependency cycle to be reported", err)
+	}
+}
+
+func TestSupervisorReportHealth(t *testing.T) {
+	var log eventLog
+	h := health.New()
+	s := NewSupervisor()
+	s.ReportHealth(h)
+	s.Add("app", log.service("app"), ServiceOptions{Critical: true, Ready: log.started("app")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	if err := log.started("app")(context.Background()); err != nil {
+		t.Fatalf("app did not start: %v", err)
+	}
+	test.EventuallyEqual(t, true, func() any { return h.Ready() }, 5*time.Second)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.Ready() {
+		t.Errorf("still ready after the supervisor stopped")
+	}
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	errFail := errors.New("fail")
+	fast := wait.FixedBackoff{Interval: time.Millisecond}
+
+	var runs atomic.Int32
+	s := NewSupervisor()
+	s.Add("flaky", RunnableFunc(func(ctx context.Context) error {
+		if runs.Add(1) < 3 {
+			return errFail
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}), ServiceOptions{Restart: RestartOnFailure, RestartBackoff: fast, MaxRestarts: 5})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	test.EventuallyEqual(t, int32(3), func() any { return runs.Load() }, 5*time.Second)
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A service that keeps failing exhausts its restarts and stops the supervisor
+	s = NewSupervisor()
+	s.Add("broken", RunnableFunc(func(ctx context.Context) error {
+		return errFail
+	}), ServiceOptions{Restart: RestartAlways, RestartBackoff: fast, MaxRestarts: 2})
+	if err := s.Run(context.Background()); !errors.Is(err, errFail) {
+		t.Errorf("got error %v, wanted %v", err, errFail)
+	}
+}
+
+func TestSupervisorRestartReset(t *testing.T) {
+	errFail := errors.New("fail")
+	fast := wait.FixedBackoff{Interval: time.Millisecond}
+
+	// A service that runs for longer than RestartReset before each failure is never out of restarts
+	var runs atomic.Int32
+	s := NewSupervisor()
+	s.Add("occasional", RunnableFunc(func(ctx context.Context) error {
+		runs.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return errFail
+	}), ServiceOptions{Restart: RestartOnFailure, RestartBackoff: fast, MaxRestarts: 1, RestartReset: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	test.EventuallyEqual(t, true, func() any { return runs.Load() >= 4 }, 5*time.Second)
+	cancel()
+	if err := <-done; err != nil && !errors.Is(err, errFail) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
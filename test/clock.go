@@ -0,0 +1,106 @@
+package test
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a virtual clock for testing code that waits, such as the loops in the wait package,
+// without waiting in real time. It implements wait.Clock.
+//
+// A Clock created by NewClock only moves when Advance is called, firing any timers that become
+// due. A Clock created by NewAutoClock advances itself whenever a timer is created, so that every
+// timer fires immediately and the clock's time moves on by the timer's duration. An auto clock
+// makes a single goroutine that waits repeatedly run as fast as possible while observing the same
+// sequence of times it would in real time.
+//
+// A Clock is safe for concurrent use.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	auto   bool
+	timers []*clockTimer
+}
+
+type clockTimer struct {
+	when time.Time
+	ch   chan time.Time
+}
+
+// NewClock returns a Clock set to start that only moves when Advance is called.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// NewAutoClock returns a Clock set to start that advances by the duration of each timer as the
+// timer is created.
+func NewAutoClock(start time.Time) *Clock {
+	return &Clock{now: start, auto: true}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a channel that receives the time at which the timer became due once the clock
+// has advanced by d, and a function that stops the timer, reporting whether it was stopped before
+// it fired.
+func (c *Clock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &clockTimer{when: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if c.auto || d <= 0 {
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		t.ch <- c.now
+		return t.ch, func() bool { return false }
+	}
+
+	c.timers = append(c.timers, t)
+	return t.ch, func() bool { return c.stop(t) }
+}
+
+// Advance moves the clock on by d, firing any timers that become due in the order they are due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].when.Before(c.timers[j].when) })
+	n := 0
+	for _, t := range c.timers {
+		if t.when.After(c.now) {
+			c.timers[n] = t
+			n++
+			continue
+		}
+		t.ch <- t.when
+	}
+	c.timers = c.timers[:n]
+}
+
+// Timers returns the number of timers that have not yet fired or been stopped. It is useful for
+// waiting until the code under test has started waiting before calling Advance.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func (c *Clock) stop(t *clockTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, ct := range c.timers {
+		if ct == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
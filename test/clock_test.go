@@ -0,0 +1,38 @@
+package test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+
+	ch, _ := c.NewTimer(time.Second)
+	_, stop := c.NewTimer(time.Minute)
+	if !stop() {
+		t.Errorf("stop did not report that the timer was pending")
+	}
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatalf("timer fired early")
+	default:
+	}
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case got := <-ch:
+		if want := start.Add(time.Second); !got.Equal(want) {
+			t.Errorf("timer fired at %v, wanted %v", got, want)
+		}
+	default:
+		t.Fatalf("timer did not fire")
+	}
+
+	if n := c.Timers(); n != 0 {
+		t.Errorf("got %d pending timers, wanted 0", n)
+	}
+}
@@ -12,6 +12,15 @@ import (
 // is sooner.
 func CtxShort(t *testing.T) context.Context {
 	t.Helper()
+	ctx, _ := CtxShortCancel(t)
+	return ctx
+}
+
+// CtxShortCancel is like CtxShort but also returns a function that cancels the
+// context, allowing a test to cancel early, for example to exercise shutdown
+// paths. The context is also cancelled when the test completes.
+func CtxShortCancel(t *testing.T) (context.Context, context.CancelFunc) {
+	t.Helper()
 
 	timeout := 10 * time.Second
 	goal := time.Now().Add(timeout)
@@ -28,5 +37,29 @@ func CtxShort(t *testing.T) context.Context {
 
 	ctx, cancel := context.WithDeadline(context.Background(), deadline)
 	t.Cleanup(cancel)
-	return ctx
+	return ctx, cancel
+}
+
+// BenchCtx returns a function that produces a Context for each iteration of a benchmark. Each
+// context has a deadline perIter from when it was produced. Producing a context cancels the one
+// produced before it, and the last context is cancelled when the benchmark completes, so timers
+// started by code under test do not outlive the iteration that started them.
+//
+//	next := test.BenchCtx(b, time.Second)
+//	for i := 0; i < b.N; i++ {
+//		ctx := next()
+//		...
+//	}
+func BenchCtx(b *testing.B, perIter time.Duration) func() context.Context {
+	b.Helper()
+
+	cancel := context.CancelFunc(func() {})
+	b.Cleanup(func() { cancel() })
+
+	return func() context.Context {
+		cancel()
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(context.Background(), perIter)
+		return ctx
+	}
 }
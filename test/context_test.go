@@ -0,0 +1,44 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCtxShortCancel(t *testing.T) {
+	ctx, cancel := CtxShortCancel(t)
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatalf("context has no deadline")
+	}
+	if time.Until(deadline) > 10*time.Second {
+		t.Errorf("deadline %v is later than the CtxShort limit", deadline)
+	}
+
+	cancel()
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("got error %v after cancel, wanted %v", ctx.Err(), context.Canceled)
+	}
+}
+
+func TestBenchCtx(t *testing.T) {
+	var ctxs []context.Context
+	testing.Benchmark(func(b *testing.B) {
+		next := BenchCtx(b, time.Minute)
+		for i := 0; i < b.N && i < 3; i++ {
+			ctxs = append(ctxs, next())
+		}
+		if err := ctxs[len(ctxs)-1].Err(); err != nil {
+			t.Errorf("current context is done: %v", err)
+		}
+	})
+
+	for i, ctx := range ctxs {
+		if ctx.Err() == nil {
+			t.Errorf("context %d was not cancelled", i)
+		}
+	}
+}
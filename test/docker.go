@@ -0,0 +1,182 @@
+//go:build docker
+// +build docker
+
+// The helpers in this file start throwaway containers using the docker command line tool. They
+// are only built when the docker build tag is supplied, for example:
+//
+//	go test -tags docker ./...
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/pontium/wait"
+)
+
+// containerStartTimeout limits the time taken to start a container and wait for it to become
+// ready, which may include pulling the image.
+const containerStartTimeout = 2 * time.Minute
+
+// ContainerOptions specifies how StartContainer should run a container.
+type ContainerOptions struct {
+	// Image is the image to run.
+	Image string
+
+	// Env holds environment variables to set in the container.
+	Env map[string]string
+
+	// Cmd overrides the image's default command when non-empty.
+	Cmd []string
+
+	// Ports lists the container ports to publish on an ephemeral loopback port, such as "5432/tcp".
+	Ports []string
+
+	// ReadyCmd is an optional command run inside the container with docker exec. The container is
+	// not considered ready until the command exits successfully. It is checked after all
+	// published ports accept TCP connections.
+	ReadyCmd []string
+}
+
+// Container is a running container started by StartContainer.
+type Container struct {
+	ID    string
+	ports map[string]string
+}
+
+// Addr returns the host address, in the form host:port, on which the given container port was
+// published. The port must have been listed in ContainerOptions.Ports.
+func (c *Container) Addr(port string) string {
+	return c.ports[port]
+}
+
+// StartContainer runs a container for the duration of the test. It waits until every published
+// port accepts TCP connections and any ReadyCmd succeeds. The container is removed when the test
+// completes, and if the test failed its logs are written to the test log first. The test is
+// skipped if the docker command is not available.
+func StartContainer(t *testing.T, opts ContainerOptions) *Container {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skipf("docker is not available: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerStartTimeout)
+	defer cancel()
+
+	args := []string{"run", "--detach"}
+	envKeys := make([]string, 0, len(opts.Env))
+	for k := range opts.Env {
+		envKeys = append(envKeys, k)
+	}
+	sort.Strings(envKeys)
+	for _, k := range envKeys {
+		args = append(args, "--env", k+"="+opts.Env[k])
+	}
+	for _, p := range opts.Ports {
+		args = append(args, "--publish", "127.0.0.1::"+p)
+	}
+	args = append(args, opts.Image)
+	args = append(args, opts.Cmd...)
+
+	out, err := docker(ctx, args...)
+	if err != nil {
+		t.Fatalf("failed to start container %s: %v", opts.Image, err)
+	}
+	c := &Container{
+		ID:    strings.TrimSpace(out),
+		ports: make(map[string]string),
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if t.Failed() {
+			logs, err := docker(ctx, "logs", c.ID)
+			if err != nil {
+				t.Logf("failed to read logs of container %s: %v", c.ID, err)
+			} else {
+				t.Logf("logs of container %s (%s):\n%s", c.ID, opts.Image, logs)
+			}
+		}
+		if _, err := docker(ctx, "rm", "--force", "--volumes", c.ID); err != nil {
+			t.Errorf("failed to remove container %s: %v", c.ID, err)
+		}
+	})
+
+	for _, p := range opts.Ports {
+		out, err := docker(ctx, "port", c.ID, p)
+		if err != nil {
+			t.Fatalf("failed to discover mapping of port %s: %v", p, err)
+		}
+		// docker may list several mappings, such as for IPv4 and IPv6. We only published on the
+		// IPv4 loopback address so the first one is sufficient.
+		addr, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+		c.ports[p] = addr
+
+		if err := wait.ForTCP(ctx, addr); err != nil {
+			t.Fatalf("port %s of container %s did not become ready: %v", p, c.ID, err)
+		}
+	}
+
+	if len(opts.ReadyCmd) > 0 {
+		args := append([]string{"exec", c.ID}, opts.ReadyCmd...)
+		err := wait.Until(ctx, func(ctx context.Context) (bool, error) {
+			_, err := docker(ctx, args...)
+			return err == nil, nil
+		}, 0, 250*time.Millisecond, 0.1)
+		if err != nil {
+			t.Fatalf("container %s did not become ready: %v", c.ID, err)
+		}
+	}
+
+	return c
+}
+
+// Postgres starts a throwaway PostgreSQL server for the duration of the test and returns a
+// connection string for it, suitable for use with lib/pq or pgx.
+func Postgres(t *testing.T) string {
+	t.Helper()
+	c := StartContainer(t, ContainerOptions{
+		Image: "postgres:16-alpine",
+		Env: map[string]string{
+			"POSTGRES_USER":     "test",
+			"POSTGRES_PASSWORD": "test",
+			"POSTGRES_DB":       "test",
+		},
+		Ports:    []string{"5432/tcp"},
+		ReadyCmd: []string{"pg_isready", "--username", "test", "--dbname", "test", "--host", "127.0.0.1"},
+	})
+	return fmt.Sprintf("postgres://test:test@%s/test?sslmode=disable", c.Addr("5432/tcp"))
+}
+
+// Redis starts a throwaway Redis server for the duration of the test and returns its address in
+// the form host:port.
+func Redis(t *testing.T) string {
+	t.Helper()
+	c := StartContainer(t, ContainerOptions{
+		Image:    "redis:7-alpine",
+		Ports:    []string{"6379/tcp"},
+		ReadyCmd: []string{"redis-cli", "ping"},
+	})
+	return c.Addr("6379/tcp")
+}
+
+// docker runs the docker command with the given arguments and returns its standard output.
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
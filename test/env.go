@@ -0,0 +1,35 @@
+package test
+
+import (
+	"os"
+	"testing"
+)
+
+// SetEnv sets the environment variable key to value for the duration of the test, restoring its
+// previous value, or unsetting it, when the test completes. Since the environment is shared by the
+// whole process, SetEnv panics if called from a parallel test or a test with parallel ancestors.
+func SetEnv(t *testing.T, key, value string) {
+	t.Helper()
+	t.Setenv(key, value)
+}
+
+// UnsetEnv unsets the environment variable key for the duration of the test, restoring its
+// previous value when the test completes. Like SetEnv, it panics if called from a parallel test
+// or a test with parallel ancestors.
+func UnsetEnv(t *testing.T, key string) {
+	t.Helper()
+	// t.Setenv checks for parallel tests and arranges for the previous value to be restored
+	t.Setenv(key, "")
+	if err := os.Unsetenv(key); err != nil {
+		t.Fatalf("unset environment variable %q: %v", key, err)
+	}
+}
+
+// forbidParallel panics if t is a parallel test or has parallel ancestors, and prevents the test
+// from calling t.Parallel afterwards, as required by helpers that change state shared by the whole
+// process. name identifies the helper.
+func forbidParallel(t *testing.T, name string) {
+	t.Helper()
+	// t.Setenv enforces the restrictions on parallel tests
+	t.Setenv("PONTIUM_TEST_"+name, t.Name())
+}
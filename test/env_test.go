@@ -0,0 +1,22 @@
+package test
+
+import (
+	"os"
+	"testing"
+)
+
+func TestUnsetEnv(t *testing.T) {
+	const key = "PONTIUM_TEST_UNSET_ENV"
+	SetEnv(t, key, "outer")
+
+	t.Run("sub", func(t *testing.T) {
+		UnsetEnv(t, key)
+		if v, ok := os.LookupEnv(key); ok {
+			t.Errorf("got value %q, wanted variable to be unset", v)
+		}
+	})
+
+	if v := os.Getenv(key); v != "outer" {
+		t.Errorf("got value %q after subtest, wanted %q", v, "outer")
+	}
+}
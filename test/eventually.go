@@ -0,0 +1,59 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/iand/pontium/wait"
+)
+
+// eventuallyInterval is the interval between polls made by EventuallyEqual.
+const eventuallyInterval = 10 * time.Millisecond
+
+// EventuallyEqual repeatedly calls get until it returns a value equal to want, failing the test
+// immediately if no equal value has been returned within timeout. The failure message shows the
+// difference between want and the last value returned by get. Values are compared using cmp.Equal
+// with opts, such as cmp.AllowUnexported, falling back to reflect.DeepEqual for values cmp.Equal
+// cannot compare, such as structs with unexported fields when no option allows them.
+func EventuallyEqual(t *testing.T, want any, get func() any, timeout time.Duration, opts ...cmp.Option) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var last any
+	err := wait.Until(ctx, func(context.Context) (bool, error) {
+		last = get()
+		return equal(want, last, opts), nil
+	}, 0, eventuallyInterval, 0, wait.Immediate())
+	if err != nil {
+		t.Fatalf("value did not become equal within %v (-want +got):\n%s", timeout, diff(want, last, opts))
+	}
+}
+
+// equal reports whether want and got are equal using cmp.Equal, or reflect.DeepEqual if cmp.Equal
+// panics because it cannot compare the values.
+func equal(want, got any, opts []cmp.Option) (eq bool) {
+	defer func() {
+		if recover() != nil {
+			eq = reflect.DeepEqual(want, got)
+		}
+	}()
+	return cmp.Equal(want, got, opts...)
+}
+
+// diff describes the difference between want and got using cmp.Diff, or by printing both values
+// if cmp.Diff panics because it cannot compare them.
+func diff(want, got any, opts []cmp.Option) (d string) {
+	defer func() {
+		if recover() != nil {
+			d = fmt.Sprintf("-%#v\n+%#v\n", want, got)
+		}
+	}()
+	return cmp.Diff(want, got, opts...)
+}
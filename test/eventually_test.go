@@ -0,0 +1,39 @@
+package test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEventuallyEqual(t *testing.T) {
+	var n atomic.Int64
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			n.Add(1)
+		}
+	}()
+
+	EventuallyEqual(t, int64(3), func() any { return n.Load() }, 5*time.Second)
+}
+
+type counter struct {
+	n int
+}
+
+func TestEventuallyEqualUnexported(t *testing.T) {
+	var n atomic.Int64
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			n.Add(1)
+		}
+	}()
+	get := func() any { return counter{n: int(n.Load())} }
+
+	EventuallyEqual(t, counter{n: 3}, get, 5*time.Second)
+	EventuallyEqual(t, counter{n: 3}, get, 5*time.Second, cmp.AllowUnexported(counter{}))
+}
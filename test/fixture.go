@@ -0,0 +1,89 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Fixtures is a set of ordered setup steps for a test, created with Fixture. Each step may
+// register a teardown function. Teardowns are run in the reverse order of their setup steps
+// when the test completes, even if the test or an earlier teardown panics. Setup steps may
+// share values with later steps and with the test using Set and Value.
+type Fixtures struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	values    map[string]any
+	teardowns []func()
+}
+
+// Fixture returns a new, empty set of fixtures for t.
+func Fixture(t *testing.T) *Fixtures {
+	t.Helper()
+	f := &Fixtures{t: t}
+	t.Cleanup(f.teardown)
+	return f
+}
+
+// Setup runs the setup step fn, failing the test immediately if it returns an error. If fn
+// succeeds and teardown is non-nil then teardown is registered to run when the test completes.
+func (f *Fixtures) Setup(fn func(f *Fixtures) error, teardown func()) {
+	f.t.Helper()
+	if err := fn(f); err != nil {
+		f.t.Fatalf("fixture setup: %v", err)
+	}
+	if teardown != nil {
+		f.mu.Lock()
+		f.teardowns = append(f.teardowns, teardown)
+		f.mu.Unlock()
+	}
+}
+
+// Set records a value under key for use by later setup steps or the test.
+func (f *Fixtures) Set(key string, v any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.values == nil {
+		f.values = make(map[string]any)
+	}
+	f.values[key] = v
+}
+
+// Value returns the value recorded under key, failing the test immediately if no value has been
+// recorded.
+func (f *Fixtures) Value(key string) any {
+	f.t.Helper()
+	f.mu.Lock()
+	v, ok := f.values[key]
+	f.mu.Unlock()
+	if !ok {
+		f.t.Fatalf("fixture value %q has not been set", key)
+	}
+	return v
+}
+
+// teardown runs the registered teardowns in reverse order. A panicking teardown is reported as a
+// test error and does not prevent the remaining teardowns from running.
+func (f *Fixtures) teardown() {
+	f.mu.Lock()
+	teardowns := f.teardowns
+	f.teardowns = nil
+	f.mu.Unlock()
+
+	for i := len(teardowns) - 1; i >= 0; i-- {
+		if err := safeTeardown(teardowns[i]); err != nil {
+			f.t.Errorf("fixture teardown: %v", err)
+		}
+	}
+}
+
+func safeTeardown(fn func()) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,32 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFixture(t *testing.T) {
+	var order []string
+
+	t.Run("sub", func(t *testing.T) {
+		f := Fixture(t)
+		f.Setup(func(f *Fixtures) error {
+			f.Set("name", "first")
+			return nil
+		}, func() { order = append(order, "first") })
+
+		f.Setup(func(f *Fixtures) error {
+			f.Set("name", f.Value("name").(string)+"+second")
+			return nil
+		}, func() { order = append(order, "second") })
+
+		if got := f.Value("name"); got != "first+second" {
+			t.Errorf("got value %q, wanted %q", got, "first+second")
+		}
+	})
+
+	want := []string{"second", "first"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("got teardown order %v, wanted %v", order, want)
+	}
+}
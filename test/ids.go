@@ -0,0 +1,82 @@
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/pontium/hlog"
+	"github.com/iand/pontium/internal/crockford"
+)
+
+// idEpoch is the time encoded in the first id generated by an IDs.
+var idEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IDs generates a deterministic sequence of ids from a seed, so that golden logs and snapshots
+// containing ids are stable between runs. Ids that encode a time use a fixed epoch that advances
+// by one millisecond with each id, so they sort in the order they were generated. An IDs is safe
+// for concurrent use, although the order in which concurrent callers receive ids is not
+// deterministic.
+type IDs struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+	n   int64 // number of ids generated
+}
+
+// NewIDs returns an IDs that generates the sequence of ids determined by seed.
+func NewIDs(seed int64) *IDs {
+	return &IDs{rng: rand.New(rand.NewSource(seed))}
+}
+
+// next returns the time and random bits of the next id.
+func (g *IDs) next() (time.Time, uint64, uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ts := idEpoch.Add(time.Duration(g.n) * time.Millisecond)
+	g.n++
+	return ts, g.rng.Uint64(), g.rng.Uint64()
+}
+
+// RequestID returns the next id in the format of hlog.NewRequestID.
+func (g *IDs) RequestID() string {
+	ts, hi, _ := g.next()
+	var id [20]byte
+	crockford.Encode(id[:10], uint64(ts.UnixMilli()))
+	crockford.Encode(id[10:], hi)
+	return string(id[:])
+}
+
+// ULID returns the next id as a 26 character ULID.
+func (g *IDs) ULID() string {
+	ts, hi, lo := g.next()
+	var id [26]byte
+	crockford.Encode(id[:10], uint64(ts.UnixMilli()))
+	crockford.Encode(id[10:18], hi) // 40 random bits
+	crockford.Encode(id[18:], lo)   // 40 random bits
+	return string(id[:])
+}
+
+// UUID returns the next id as a version 4 UUID, such as "0c2d7f3e-5b9a-4c1d-8e6f-a1b2c3d4e5f6".
+func (g *IDs) UUID() string {
+	_, hi, lo := g.next()
+	hi = hi&^0xf000 | 0x4000     // version 4
+	lo = lo&^(0xc<<60) | 0x8<<60 // RFC 4122 variant
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, hi>>16&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
+
+// UseDeterministicIDs installs the RequestID method of a new IDs seeded with seed as the request
+// id generator used by hlog.NewRequestID for the duration of the test, restoring the previous
+// generator when the test completes. It returns the IDs so that the test may generate other ids
+// from the same sequence. Since the generator is shared by the whole process, UseDeterministicIDs
+// panics if called from a parallel test or a test with parallel ancestors, and the test may not
+// call t.Parallel afterwards.
+func UseDeterministicIDs(t *testing.T, seed int64) *IDs {
+	t.Helper()
+	forbidParallel(t, "DETERMINISTIC_IDS")
+
+	g := NewIDs(seed)
+	t.Cleanup(hlog.SetRequestIDGenerator(g.RequestID))
+	return g
+}
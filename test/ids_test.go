@@ -0,0 +1,58 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/iand/pontium/hlog"
+)
+
+func TestIDs(t *testing.T) {
+	a, b := NewIDs(1), NewIDs(1)
+	for i := 0; i < 3; i++ {
+		if x, y := a.ULID(), b.ULID(); x != y {
+			t.Errorf("got %q and %q from the same seed, wanted the same id", x, y)
+		}
+	}
+
+	g := NewIDs(2)
+	checks := []struct {
+		name    string
+		id      string
+		pattern string
+	}{
+		{name: "request id", id: g.RequestID(), pattern: `^[0-9A-HJKMNP-TV-Z]{20}$`},
+		{name: "ulid", id: g.ULID(), pattern: `^[0-9A-HJKMNP-TV-Z]{26}$`},
+		{name: "uuid", id: g.UUID(), pattern: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+	}
+	for _, c := range checks {
+		if !regexp.MustCompile(c.pattern).MatchString(c.id) {
+			t.Errorf("%s: got %q, wanted it to match %s", c.name, c.id, c.pattern)
+		}
+	}
+
+	first, second := NewIDs(3).ULID(), NewIDs(3)
+	second.ULID()
+	if next := second.ULID(); next <= first {
+		t.Errorf("got %q after %q, wanted ids to sort in the order generated", next, first)
+	}
+}
+
+func TestUseDeterministicIDs(t *testing.T) {
+	var got []string
+	for i := 0; i < 2; i++ {
+		t.Run("sub", func(t *testing.T) {
+			UseDeterministicIDs(t, 42)
+			got = append(got, hlog.NewRequestID())
+		})
+	}
+	if got[0] != got[1] {
+		t.Errorf("got request ids %q and %q, wanted the same id from the same seed", got[0], got[1])
+	}
+	if want := NewIDs(42).RequestID(); got[0] != want {
+		t.Errorf("got request id %q, wanted %q", got[0], want)
+	}
+	if id := hlog.NewRequestID(); id == got[0] {
+		t.Errorf("got request id %q after the test, wanted the generator to be restored", id)
+	}
+}
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"log"
+	"log/slog"
+	"testing"
+)
+
+// UseDefaultLogger installs a logger using h as slog's default logger for the duration of the test,
+// restoring the previous default logger, and the output, flags and prefix of the log package, when
+// the test completes. This allows testing code that logs using the package level functions of slog.
+// Since the default logger is shared by the whole process, UseDefaultLogger panics if called from a
+// parallel test or a test with parallel ancestors, and the test may not call t.Parallel afterwards.
+func UseDefaultLogger(t *testing.T, h slog.Handler) {
+	t.Helper()
+	forbidParallel(t, "DEFAULT_LOGGER")
+
+	prev := slog.Default()
+	w, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		log.SetOutput(w)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	})
+	slog.SetDefault(slog.New(h))
+}
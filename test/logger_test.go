@@ -0,0 +1,25 @@
+package test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestUseDefaultLogger(t *testing.T) {
+	prev := slog.Default()
+
+	var buf bytes.Buffer
+	t.Run("sub", func(t *testing.T) {
+		UseDefaultLogger(t, slog.NewTextHandler(&buf, nil))
+		slog.Info("hello")
+	})
+
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Errorf("got output %q, wanted it to contain the logged message", buf.String())
+	}
+	if slog.Default() != prev {
+		t.Errorf("default logger was not restored")
+	}
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// MetricsDiff gathers metrics from g before and after calling fn and returns the change in the
+// value of each series that changed, keyed by series in the prometheus text format, such as
+// `http_requests_total{code="200",method="GET"}`. Labels appear in name order. Series of counters,
+// gauges and untyped metrics are keyed by the metric name. Histograms and summaries contribute
+// their _count and _sum series and, for histograms, a _bucket series for each bucket including
+// +Inf. Series that appear during fn are compared against zero, while series that disappear
+// report the negation of their previous value. Series that did not change are omitted, so a test
+// can assert that exactly the expected metrics moved by comparing the result to a literal map:
+//
+//	diff := test.MetricsDiff(t, reg, func() { handler.ServeHTTP(w, req) })
+//	if want := map[string]float64{`requests_total{code="200"}`: 1}; !reflect.DeepEqual(diff, want) {
+//		t.Errorf("got metric changes %v, wanted %v", diff, want)
+//	}
+//
+// The test fails immediately if metrics cannot be gathered.
+func MetricsDiff(t *testing.T, g prometheus.Gatherer, fn func()) map[string]float64 {
+	t.Helper()
+
+	before := gatherSeries(t, g)
+	fn()
+	after := gatherSeries(t, g)
+
+	diff := make(map[string]float64)
+	for k, v := range after {
+		if d := v - before[k]; d != 0 {
+			diff[k] = d
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok && v != 0 {
+			diff[k] = -v
+		}
+	}
+	return diff
+}
+
+// gatherSeries gathers metrics from g and returns the value of each series keyed by series.
+func gatherSeries(t *testing.T, g prometheus.Gatherer) map[string]float64 {
+	t.Helper()
+
+	mfs, err := g.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	series := make(map[string]float64)
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			switch {
+			case m.Counter != nil:
+				series[seriesKey(name, m.GetLabel())] = m.Counter.GetValue()
+			case m.Gauge != nil:
+				series[seriesKey(name, m.GetLabel())] = m.Gauge.GetValue()
+			case m.Untyped != nil:
+				series[seriesKey(name, m.GetLabel())] = m.Untyped.GetValue()
+			case m.Histogram != nil:
+				h := m.Histogram
+				series[seriesKey(name+"_count", m.GetLabel())] = float64(h.GetSampleCount())
+				series[seriesKey(name+"_sum", m.GetLabel())] = h.GetSampleSum()
+				for _, b := range h.GetBucket() {
+					le := strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)
+					series[seriesKey(name+"_bucket", m.GetLabel(), "le", le)] = float64(b.GetCumulativeCount())
+				}
+				// The +Inf bucket is implied by the sample count
+				series[seriesKey(name+"_bucket", m.GetLabel(), "le", "+Inf")] = float64(h.GetSampleCount())
+			case m.Summary != nil:
+				series[seriesKey(name+"_count", m.GetLabel())] = float64(m.Summary.GetSampleCount())
+				series[seriesKey(name+"_sum", m.GetLabel())] = m.Summary.GetSampleSum()
+			}
+		}
+	}
+	return series
+}
+
+// seriesKey returns the name of a series followed by its labels, together with any extra label
+// name and value pairs, in name order.
+func seriesKey(name string, labels []*dto.LabelPair, extra ...string) string {
+	pairs := make([][2]string, 0, len(labels)+len(extra)/2)
+	for _, lp := range labels {
+		pairs = append(pairs, [2]string{lp.GetName(), lp.GetValue()})
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, [2]string{extra[i], extra[i+1]})
+	}
+	if len(pairs) == 0 {
+		return name
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(p[0])
+		b.WriteString("=")
+		b.WriteString(strconv.Quote(p[1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetricsDiff(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "Requests."}, []string{"method", "code"})
+	inflight := prometheus.NewGauge(prometheus.GaugeOpts{Name: "inflight", Help: "In flight."})
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "Latency.", Buckets: []float64{0.1, 1}})
+	unchanged := prometheus.NewCounter(prometheus.CounterOpts{Name: "unchanged_total", Help: "Unchanged."})
+	reg.MustRegister(requests, inflight, latency, unchanged)
+
+	requests.WithLabelValues("GET", "200").Add(5)
+	inflight.Set(3)
+	unchanged.Inc()
+
+	got := MetricsDiff(t, reg, func() {
+		requests.WithLabelValues("GET", "200").Inc()
+		requests.WithLabelValues("POST", "500").Add(2)
+		inflight.Dec()
+		latency.Observe(0.5)
+	})
+
+	want := map[string]float64{
+		`requests_total{code="200",method="GET"}`:  1,
+		`requests_total{code="500",method="POST"}`: 2,
+		`inflight`:                          -1,
+		`latency_seconds_count`:             1,
+		`latency_seconds_sum`:               0.5,
+		`latency_seconds_bucket{le="1"}`:    1,
+		`latency_seconds_bucket{le="+Inf"}`: 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
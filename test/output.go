@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+// CaptureOutput calls fn while os.Stdout and os.Stderr are redirected to pipes and returns
+// everything written to each. The original files are restored when fn returns, or when the test
+// completes if fn exits the goroutine, for example by calling t.Fatal. Since os.Stdout and
+// os.Stderr are shared by the whole process, CaptureOutput must not be used in parallel tests.
+func CaptureOutput(t *testing.T, fn func()) (stdout, stderr string) {
+	t.Helper()
+
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create stdout pipe: %v", err)
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create stderr pipe: %v", err)
+	}
+
+	origOut, origErr := os.Stdout, os.Stderr
+	restored := false
+	restore := func() {
+		if restored {
+			return
+		}
+		restored = true
+		os.Stdout, os.Stderr = origOut, origErr
+		outW.Close()
+		errW.Close()
+	}
+	t.Cleanup(restore)
+
+	outC := drain(outR)
+	errC := drain(errR)
+
+	os.Stdout, os.Stderr = outW, errW
+	func() {
+		defer restore()
+		fn()
+	}()
+
+	return <-outC, <-errC
+}
+
+// drain reads r until EOF in a new goroutine, sending everything read on the returned channel.
+func drain(r *os.File) <-chan string {
+	ch := make(chan string, 1)
+	go func() {
+		defer r.Close()
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		ch <- buf.String()
+	}()
+	return ch
+}
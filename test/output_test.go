@@ -0,0 +1,21 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestCaptureOutput(t *testing.T) {
+	stdout, stderr := CaptureOutput(t, func() {
+		fmt.Fprint(os.Stdout, "to stdout")
+		fmt.Fprint(os.Stderr, "to stderr")
+	})
+
+	if stdout != "to stdout" {
+		t.Errorf("got stdout %q, wanted %q", stdout, "to stdout")
+	}
+	if stderr != "to stderr" {
+		t.Errorf("got stderr %q, wanted %q", stderr, "to stderr")
+	}
+}
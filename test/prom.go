@@ -0,0 +1,54 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iand/pontium/prom"
+)
+
+// PromMetricsPath is the path on which the server started by PromServer serves metrics.
+const PromMetricsPath = "/metrics"
+
+// PromServer starts a prom.PrometheusServer for the duration of the test. The server listens on an
+// ephemeral loopback port and uses a private registry so it is isolated from other tests and
+// from the default prometheus registry. PromServer returns the registry, with which tests should
+// register their collectors, and the base URL of the server. Metrics may be scraped from the base
+// URL followed by PromMetricsPath. The server is shut down when the test completes.
+func PromServer(t *testing.T) (*prometheus.Registry, string) {
+	t.Helper()
+
+	reg := prometheus.NewRegistry()
+	srv, err := prom.NewPrometheusServerWithRegistry("127.0.0.1:0", PromMetricsPath, "test", reg)
+	if err != nil {
+		t.Fatalf("failed to create prometheus server: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, ln)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("prometheus server failed: %v", err)
+		}
+		if err := srv.Close(); err != nil {
+			t.Errorf("failed to close prometheus server: %v", err)
+		}
+	})
+
+	return reg, "http://" + ln.Addr().String()
+}
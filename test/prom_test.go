@@ -0,0 +1,33 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPromServer(t *testing.T) {
+	reg, url := PromServer(t)
+
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "prom_server_test_total", Help: "Test counter."})
+	reg.MustRegister(c)
+	c.Add(3)
+
+	resp, err := http.Get(url + PromMetricsPath)
+	if err != nil {
+		t.Fatalf("failed to scrape: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	if !strings.Contains(string(body), "prom_server_test_total 3") {
+		t.Errorf("scrape did not contain test counter:\n%s", body)
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	// readyTimeout is the time allowed for a service started by RunService to become ready.
+	readyTimeout = 10 * time.Second
+
+	// readyInterval is the interval between calls to the readiness probe of a service.
+	readyInterval = 10 * time.Millisecond
+)
+
+// RunService calls the Run method of svc in a new goroutine with a context that is cancelled when
+// the test completes. If ready is not nil, RunService calls it repeatedly until it returns nil,
+// failing the test immediately if the service does not become ready within ten seconds or Run
+// returns first. The test fails if Run returns before the test completes, if it does not return
+// within a few seconds of its context being cancelled, or if it returns an error other than
+// context.Canceled.
+func RunService(t *testing.T, svc interface{ Run(context.Context) error }, ready func(context.Context) error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stopping atomic.Bool
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err = svc.Run(ctx)
+		if !stopping.Load() {
+			t.Errorf("service returned unexpectedly: %v", err)
+		}
+	}()
+
+	t.Cleanup(func() {
+		stopping.Store(true)
+		cancel()
+
+		release := time.NewTimer(releaseTimeout)
+		defer release.Stop()
+		select {
+		case <-done:
+		case <-release.C:
+			t.Errorf("service did not return within %v of its context being cancelled", releaseTimeout)
+			return
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.Errorf("service returned error: %v", err)
+		}
+	})
+
+	if ready == nil {
+		return
+	}
+
+	rctx, rcancel := context.WithTimeout(ctx, readyTimeout)
+	defer rcancel()
+	ticker := time.NewTicker(readyInterval)
+	defer ticker.Stop()
+	for {
+		rerr := ready(rctx)
+		if rerr == nil {
+			return
+		}
+		select {
+		case <-done:
+			t.Fatalf("service returned before it was ready")
+		case <-rctx.Done():
+			t.Fatalf("service was not ready within %v: %v", readyTimeout, rerr)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+type serviceFunc func(context.Context) error
+
+func (f serviceFunc) Run(ctx context.Context) error { return f(ctx) }
+
+func TestRunService(t *testing.T) {
+	var started, stopped atomic.Bool
+	t.Run("sub", func(t *testing.T) {
+		RunService(t, serviceFunc(func(ctx context.Context) error {
+			started.Store(true)
+			<-ctx.Done()
+			stopped.Store(true)
+			return ctx.Err()
+		}), func(context.Context) error {
+			if !started.Load() {
+				return errors.New("not started")
+			}
+			return nil
+		})
+		if !started.Load() {
+			t.Errorf("service was not ready when RunService returned")
+		}
+	})
+
+	if !stopped.Load() {
+		t.Errorf("service was not stopped when the test completed")
+	}
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+// A LineParser parses a single line of output written by a slog.Handler into a map of attributes.
+// Attributes belonging to a group should be represented by a nested map[string]any keyed by the
+// group name. The built-in time, level and message attributes should use the keys slog.TimeKey,
+// slog.LevelKey and slog.MessageKey.
+type LineParser func(line []byte) (map[string]any, error)
+
+// HandlerConformance runs the standard library's slogtest suite against the handlers returned by
+// newHandler, followed by additional cases covering nested groups, the ordering of attributes
+// added with WithAttrs and the treatment of empty attributes. Each case runs as a subtest of t
+// and calls newHandler to obtain a handler that writes to a fresh buffer. The single line of
+// output written by the handler for the case is converted using parse and then checked.
+func HandlerConformance(t *testing.T, newHandler func(w io.Writer) slog.Handler, parse LineParser) {
+	t.Helper()
+
+	var buf *bytes.Buffer
+	slogtest.Run(t, func(*testing.T) slog.Handler {
+		buf = new(bytes.Buffer)
+		return newHandler(buf)
+	}, func(t *testing.T) map[string]any {
+		return parseSingleLine(t, buf.Bytes(), parse)
+	})
+
+	for _, c := range conformanceCases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			h := c.handler(newHandler(buf))
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0)
+			r.AddAttrs(c.attrs...)
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatalf("handle: %v", err)
+			}
+
+			m := parseSingleLine(t, buf.Bytes(), parse)
+			for _, check := range c.checks {
+				if msg := check(m, buf.String()); msg != "" {
+					t.Errorf("%s\noutput: %s", msg, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func parseSingleLine(t *testing.T, out []byte, parse LineParser) map[string]any {
+	t.Helper()
+	lines := bytes.Split(bytes.TrimRight(out, "\n"), []byte{'\n'})
+	if len(lines) != 1 {
+		t.Fatalf("expected one line of output, got %d: %q", len(lines), out)
+	}
+	m, err := parse(lines[0])
+	if err != nil {
+		t.Fatalf("parse %q: %v", lines[0], err)
+	}
+	return m
+}
+
+// conformanceCheck checks a parsed record, which was parsed from the raw line of output.
+type conformanceCheck func(m map[string]any, line string) string
+
+var conformanceCases = []struct {
+	name    string
+	handler func(slog.Handler) slog.Handler
+	attrs   []slog.Attr
+	checks  []conformanceCheck
+}{
+	{
+		name: "nested-groups",
+		handler: func(h slog.Handler) slog.Handler {
+			return h.WithGroup("g1").WithAttrs([]slog.Attr{slog.String("a", "x")}).WithGroup("g2")
+		},
+		attrs: []slog.Attr{slog.String("b", "y"), slog.Group("g3", slog.String("c", "z"))},
+		checks: []conformanceCheck{
+			attrIs([]string{"g1", "a"}, "x"),
+			attrIs([]string{"g1", "g2", "b"}, "y"),
+			attrIs([]string{"g1", "g2", "g3", "c"}, "z"),
+			attrMissing([]string{"a"}),
+			attrMissing([]string{"b"}),
+		},
+	},
+	{
+		name: "with-attrs-ordering",
+		handler: func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.String("o1", "first-value")}).WithAttrs([]slog.Attr{slog.String("o2", "second-value")})
+		},
+		attrs: []slog.Attr{slog.String("o3", "third-value")},
+		checks: []conformanceCheck{
+			attrIs([]string{"o1"}, "first-value"),
+			attrIs([]string{"o2"}, "second-value"),
+			attrIs([]string{"o3"}, "third-value"),
+			ordered("first-value", "second-value", "third-value"),
+		},
+	},
+	{
+		name: "empty-attrs",
+		handler: func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{{}}).WithGroup("g").WithAttrs([]slog.Attr{{}})
+		},
+		attrs: []slog.Attr{{}, slog.Group("e", slog.Attr{}), slog.String("a", "x")},
+		checks: []conformanceCheck{
+			attrMissing([]string{""}),
+			attrMissing([]string{"g", ""}),
+			attrMissing([]string{"g", "e"}),
+			attrIs([]string{"g", "a"}, "x"),
+		},
+	},
+	{
+		name: "empty-group-only",
+		handler: func(h slog.Handler) slog.Handler {
+			return h.WithGroup("g")
+		},
+		attrs: []slog.Attr{},
+		checks: []conformanceCheck{
+			attrMissing([]string{"g"}),
+		},
+	},
+}
+
+// lookup follows path through nested groups, reporting whether the final key was found.
+func lookup(m map[string]any, path []string) (any, bool) {
+	var v any = m
+	for _, k := range path {
+		g, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		v, ok = g[k]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func attrIs(path []string, want string) conformanceCheck {
+	return func(m map[string]any, _ string) string {
+		v, ok := lookup(m, path)
+		if !ok {
+			return fmt.Sprintf("missing key %q", strings.Join(path, "."))
+		}
+		if got := fmt.Sprint(v); got != want {
+			return fmt.Sprintf("%q: got %q, want %q", strings.Join(path, "."), got, want)
+		}
+		return ""
+	}
+}
+
+func attrMissing(path []string) conformanceCheck {
+	return func(m map[string]any, _ string) string {
+		if _, ok := lookup(m, path); ok {
+			return fmt.Sprintf("unexpected key %q", strings.Join(path, "."))
+		}
+		return ""
+	}
+}
+
+// ordered checks that the given strings appear in the raw line in the order given.
+func ordered(ss ...string) conformanceCheck {
+	return func(_ map[string]any, line string) string {
+		last := -1
+		for _, s := range ss {
+			i := strings.Index(line, s)
+			if i == -1 {
+				return fmt.Sprintf("missing %q", s)
+			}
+			if i < last {
+				return fmt.Sprintf("%q appears out of order, expected order %q", s, ss)
+			}
+			last = i
+		}
+		return ""
+	}
+}
@@ -0,0 +1,18 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestHandlerConformanceJSON(t *testing.T) {
+	HandlerConformance(t, func(w io.Writer) slog.Handler {
+		return slog.NewJSONHandler(w, nil)
+	}, func(line []byte) (map[string]any, error) {
+		var m map[string]any
+		err := json.Unmarshal(line, &m)
+		return m, err
+	})
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// releaseTimeout is the time allowed for a function to return after its context has been cancelled.
+const releaseTimeout = 5 * time.Second
+
+// RequireCompletesWithin calls fn with a context that has a deadline d from now and fails the test
+// immediately if fn has not returned by the deadline. The context is cancelled once the deadline
+// passes, whether or not fn has returned.
+func RequireCompletesWithin(t *testing.T, d time.Duration, fn func(context.Context)) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		t.Fatalf("function did not complete within %v", d)
+	}
+
+	// The function may have returned because its context was cancelled at the deadline, which is
+	// not the same as completing within it.
+	if elapsed := time.Since(start); elapsed >= d {
+		t.Fatalf("function took %v, wanted it to complete within %v", elapsed, d)
+	}
+}
+
+// RequireBlocks calls fn and fails the test if fn returns before d has elapsed. After d has
+// elapsed the context passed to fn is cancelled and fn is expected to return promptly; the test
+// fails if it does not return within a few seconds of cancellation.
+func RequireBlocks(t *testing.T, d time.Duration, fn func(context.Context)) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		t.Fatalf("function returned after %v, wanted it to block for at least %v", time.Since(start), d)
+	case <-timer.C:
+	}
+
+	cancel()
+	release := time.NewTimer(releaseTimeout)
+	defer release.Stop()
+
+	select {
+	case <-done:
+	case <-release.C:
+		t.Fatalf("function did not return within %v of its context being cancelled", releaseTimeout)
+	}
+}
@@ -0,0 +1,19 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequireCompletesWithin(t *testing.T) {
+	RequireCompletesWithin(t, time.Second, func(ctx context.Context) {
+		time.Sleep(time.Millisecond)
+	})
+}
+
+func TestRequireBlocks(t *testing.T) {
+	RequireBlocks(t, 20*time.Millisecond, func(ctx context.Context) {
+		<-ctx.Done()
+	})
+}
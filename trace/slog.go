@@ -0,0 +1,150 @@
+// Package trace connects logging with OpenTelemetry tracing.
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/iand/pontium/hlog"
+)
+
+// SlogHandler is a slog.Handler that delegates records to another handler, such as an
+// hlog.Handler, and in addition attaches records at or above an event level to the span active in
+// the record's context as span events. The event is named with the record's message and carries
+// the record's level and attributes, including those added by WithAttrs and those carried by the
+// context using hlog.ContextWithAttrs, so traces include the relevant log context without
+// instrumenting code twice.
+type SlogHandler struct {
+	next   slog.Handler
+	level  slog.Leveler
+	attrs  []attribute.KeyValue // converted attributes added by WithAttrs
+	groups []string             // groups opened by WithGroup
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler returns a SlogHandler delegating to next that attaches records at or above level
+// to spans. If level is nil, warnings and errors are attached.
+func NewSlogHandler(next slog.Handler, level slog.Leveler) *SlogHandler {
+	if level == nil {
+		level = slog.LevelWarn
+	}
+	return &SlogHandler{next: next, level: level}
+}
+
+// Enabled reports whether the record will be handled by the delegate or attached to a span.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.next.Enabled(ctx, level) {
+		return true
+	}
+	return level >= h.level.Level() && oteltrace.SpanFromContext(ctx).IsRecording()
+}
+
+// Handle attaches the record to the active span if its level is high enough and passes it to the
+// delegate if the delegate is enabled for its level.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level.Level() {
+		if span := oteltrace.SpanFromContext(ctx); span.IsRecording() {
+			span.AddEvent(r.Message, oteltrace.WithTimestamp(r.Time), oteltrace.WithAttributes(h.eventAttrs(ctx, r)...))
+		}
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// eventAttrs returns the attributes of a span event for the record.
+func (h *SlogHandler) eventAttrs(ctx context.Context, r slog.Record) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, 1+len(h.attrs)+r.NumAttrs())
+	kvs = append(kvs, attribute.String("log.severity", r.Level.String()))
+	kvs = append(kvs, h.attrs...)
+	for _, a := range hlog.AttrsFromContext(ctx) {
+		kvs = appendAttr(kvs, "", a)
+	}
+	prefix := groupPrefix(h.groups)
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, prefix, a)
+		return true
+	})
+	return kvs
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.next = h.next.WithAttrs(attrs)
+	h2.attrs = h.attrs[:len(h.attrs):len(h.attrs)]
+	prefix := groupPrefix(h.groups)
+	for _, a := range attrs {
+		h2.attrs = appendAttr(h2.attrs, prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
+
+func groupPrefix(groups []string) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	return strings.Join(groups, ".") + "."
+}
+
+// appendAttr appends a to kvs as span attributes with keys qualified by prefix. Groups are
+// flattened into an attribute for each member.
+func appendAttr(kvs []attribute.KeyValue, prefix string, a slog.Attr) []attribute.KeyValue {
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+	v := a.Value.Resolve()
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindGroup:
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range v.Group() {
+			kvs = appendAttr(kvs, prefix, ga)
+		}
+		return kvs
+	case slog.KindString:
+		return append(kvs, attribute.String(key, v.String()))
+	case slog.KindInt64:
+		return append(kvs, attribute.Int64(key, v.Int64()))
+	case slog.KindUint64:
+		if u := v.Uint64(); u <= math.MaxInt64 {
+			return append(kvs, attribute.Int64(key, int64(u)))
+		}
+		return append(kvs, attribute.String(key, v.String()))
+	case slog.KindFloat64:
+		return append(kvs, attribute.Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(kvs, attribute.Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(kvs, attribute.String(key, v.Duration().String()))
+	case slog.KindTime:
+		return append(kvs, attribute.String(key, v.Time().Format(time.RFC3339Nano)))
+	default:
+		return append(kvs, attribute.String(key, fmt.Sprint(v.Any())))
+	}
+}
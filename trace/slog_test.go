@@ -0,0 +1,62 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/iand/pontium/hlog"
+)
+
+func TestSlogHandler(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	var buf bytes.Buffer
+	h := NewSlogHandler(new(hlog.Handler).WithoutColor().WithWriter(&buf).WithLevel(slog.LevelError), nil)
+	logger := slog.New(h).With("component", "db").WithGroup("query")
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	ctx = hlog.ContextWithRequestID(ctx, "req-1")
+	logger.InfoContext(ctx, "started", "table", "users")
+	logger.WarnContext(ctx, "slow query", "table", "users", "rows", 10)
+	span.End()
+
+	if buf.Len() != 0 {
+		t.Errorf("got output below delegate's level: %q", buf.String())
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, wanted 1", len(spans))
+	}
+	events := spans[0].Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, wanted 1", len(events))
+	}
+	if events[0].Name != "slow query" {
+		t.Errorf("got event name %q, wanted %q", events[0].Name, "slow query")
+	}
+	want := []attribute.KeyValue{
+		attribute.String("log.severity", "WARN"),
+		attribute.String("component", "db"),
+		attribute.String(hlog.RequestIDKey, "req-1"),
+		attribute.String("query.table", "users"),
+		attribute.Int64("query.rows", 10),
+	}
+	if diff := cmp.Diff(want, events[0].Attributes, cmp.Comparer(func(a, b attribute.KeyValue) bool { return a == b })); diff != "" {
+		t.Errorf("event attributes mismatch (-want +got):\n%s", diff)
+	}
+
+	logger.ErrorContext(context.Background(), "failed")
+	if !strings.Contains(buf.String(), "failed") {
+		t.Errorf("record not delegated without a span: %q", buf.String())
+	}
+}
@@ -0,0 +1,86 @@
+package wait
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultAdaptiveSmoothing is the smoothing factor used by an AdaptiveInterval when none is specified.
+const DefaultAdaptiveSmoothing = 0.2
+
+// AdaptiveInterval computes a polling interval that adapts to how often a watched resource changes.
+// Each observation moves the interval towards Max when no change was seen and towards Min when a
+// change was seen, using an exponential moving average so that a single observation does not cause
+// the interval to swing from one bound to the other.
+//
+// The zero value is not useful; Min and Max must be set. An AdaptiveInterval is not safe for
+// concurrent use.
+type AdaptiveInterval struct {
+	// Min is the shortest interval that will be returned.
+	Min time.Duration
+
+	// Max is the longest interval that will be returned.
+	Max time.Duration
+
+	// Smoothing is the weight, in the range (0,1], given to each new observation. Larger values
+	// adapt more quickly. If Smoothing is outside that range then DefaultAdaptiveSmoothing is used.
+	Smoothing float64
+
+	current float64
+}
+
+// Current returns the current interval without recording an observation.
+func (a *AdaptiveInterval) Current() time.Duration {
+	if a.current == 0 {
+		return a.Min
+	}
+	return time.Duration(a.current)
+}
+
+// Next records whether the watched resource changed since the last observation and returns the
+// interval to wait before the next one.
+func (a *AdaptiveInterval) Next(changed bool) time.Duration {
+	alpha := a.Smoothing
+	if alpha <= 0 || alpha > 1 {
+		alpha = DefaultAdaptiveSmoothing
+	}
+
+	if a.current == 0 {
+		a.current = float64(a.Min)
+	}
+
+	target := float64(a.Max)
+	if changed {
+		target = float64(a.Min)
+	}
+	a.current += alpha * (target - a.current)
+
+	if a.current < float64(a.Min) {
+		a.current = float64(a.Min)
+	} else if a.current > float64(a.Max) {
+		a.current = float64(a.Max)
+	}
+	return time.Duration(a.current)
+}
+
+// Adaptive repeatedly calls fn until it returns an error or until the context is cancelled,
+// adjusting the interval between calls according to whether fn reports a change. The interval
+// lengthens towards maxInterval while fn keeps returning false and shortens towards minInterval
+// after it returns true. It is intended for watching resources whose rate of change is unknown.
+// It returns any error returned from fn or the cancelled context, which is reported as described
+// for Until.
+// j adds jitter to each interval. See the documentation for JitterDuration for how j is interpreted.
+func Adaptive(ctx context.Context, fn func(context.Context) (bool, error), minInterval time.Duration, maxInterval time.Duration, j float64, opts ...Option) error {
+	o := newOptions(opts)
+	a := &AdaptiveInterval{Min: minInterval, Max: maxInterval}
+	for {
+		changed, err := o.check(ctx, fn)
+		if err != nil {
+			return err
+		}
+
+		if err := o.wait(ctx, a.Next(changed), j); err != nil {
+			return o.ctxError(ctx, err)
+		}
+	}
+}
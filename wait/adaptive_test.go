@@ -0,0 +1,34 @@
+package wait
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveInterval(t *testing.T) {
+	a := &AdaptiveInterval{Min: time.Second, Max: 10 * time.Second, Smoothing: 0.5}
+
+	prev := a.Current()
+	for i := 0; i < 5; i++ {
+		d := a.Next(false)
+		if d <= prev {
+			t.Fatalf("observation %d: interval %v did not lengthen from %v", i, d, prev)
+		}
+		if d > a.Max {
+			t.Fatalf("observation %d: interval %v exceeded max %v", i, d, a.Max)
+		}
+		prev = d
+	}
+
+	d := a.Next(true)
+	if d >= prev {
+		t.Errorf("interval %v did not shorten from %v after a change", d, prev)
+	}
+
+	for i := 0; i < 50; i++ {
+		d = a.Next(true)
+	}
+	if d != a.Min {
+		t.Errorf("got interval %v after repeated changes, wanted %v", d, a.Min)
+	}
+}
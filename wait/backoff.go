@@ -0,0 +1,187 @@
+package wait
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// A BackoffPolicy determines how long to wait between successive attempts of an operation.
+type BackoffPolicy interface {
+	// Delay returns the length of time to wait before making the given attempt. Attempts are
+	// numbered from 1, so Delay(1) is the wait before the first retry.
+	Delay(attempt int) time.Duration
+}
+
+// FixedBackoff is a BackoffPolicy that waits for the same interval before every attempt.
+type FixedBackoff struct {
+	// Interval is the time to wait between attempts.
+	Interval time.Duration
+
+	// Jitter adds jitter to the interval. See the documentation for JitterDuration for how it is interpreted.
+	Jitter float64
+}
+
+var _ BackoffPolicy = FixedBackoff{}
+
+// Delay returns the interval, adjusted by any jitter.
+func (b FixedBackoff) Delay(attempt int) time.Duration {
+	return JitterDuration(b.Interval, b.Jitter)
+}
+
+// String returns the policy in the form accepted by ParseBackoffPolicy.
+func (b FixedBackoff) String() string {
+	s := "fixed(" + b.Interval.String()
+	if b.Jitter != 0 {
+		s += ", jitter=" + formatFloat(b.Jitter)
+	}
+	return s + ")"
+}
+
+// ExponentialBackoff is a BackoffPolicy that multiplies the delay by a constant factor after
+// each attempt.
+type ExponentialBackoff struct {
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+
+	// Multiplier is the factor applied to the delay after each attempt. Values less than 1 are
+	// treated as 1.
+	Multiplier float64
+
+	// Max caps the delay before jitter is applied. Zero means no limit.
+	Max time.Duration
+
+	// Jitter adds jitter to the delay. See the documentation for JitterDuration for how it is interpreted.
+	Jitter float64
+}
+
+var _ BackoffPolicy = ExponentialBackoff{}
+
+// Delay returns Initial * Multiplier^(attempt-1), capped at Max and adjusted by any jitter.
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	m := b.Multiplier
+	if m < 1 {
+		m = 1
+	}
+	return JitterDuration(capDuration(float64(b.Initial)*math.Pow(m, float64(attempt-1)), b.Max), b.Jitter)
+}
+
+// String returns the policy in the form accepted by ParseBackoffPolicy.
+func (b ExponentialBackoff) String() string {
+	s := "exp(" + b.Initial.String() + ", " + formatFloat(b.Multiplier)
+	if b.Max != 0 {
+		s += ", max=" + b.Max.String()
+	}
+	if b.Jitter != 0 {
+		s += ", jitter=" + formatFloat(b.Jitter)
+	}
+	return s + ")"
+}
+
+// LinearBackoff is a BackoffPolicy that increases the delay by a constant step after each attempt,
+// as specified by the retry schedules of some rate limited services.
+type LinearBackoff struct {
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+
+	// Step is added to the delay after each attempt. Negative values are treated as 0.
+	Step time.Duration
+
+	// Max caps the delay before jitter is applied. Zero means no limit.
+	Max time.Duration
+
+	// Jitter adds jitter to the delay. See the documentation for JitterDuration for how it is interpreted.
+	Jitter float64
+}
+
+var _ BackoffPolicy = LinearBackoff{}
+
+// Delay returns Initial + Step*(attempt-1), capped at Max and adjusted by any jitter.
+func (b LinearBackoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	step := b.Step
+	if step < 0 {
+		step = 0
+	}
+	return JitterDuration(capDuration(float64(b.Initial)+float64(step)*float64(attempt-1), b.Max), b.Jitter)
+}
+
+// String returns the policy in the form accepted by ParseBackoffPolicy.
+func (b LinearBackoff) String() string {
+	s := "linear(" + b.Initial.String() + ", " + b.Step.String()
+	if b.Max != 0 {
+		s += ", max=" + b.Max.String()
+	}
+	if b.Jitter != 0 {
+		s += ", jitter=" + formatFloat(b.Jitter)
+	}
+	return s + ")"
+}
+
+// PolynomialBackoff is a BackoffPolicy that grows the delay as a power of the number of attempts,
+// more gently than ExponentialBackoff for large numbers of attempts.
+type PolynomialBackoff struct {
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+
+	// Exponent is the power to which the attempt number is raised. Negative values are treated
+	// as 0.
+	Exponent float64
+
+	// Max caps the delay before jitter is applied. Zero means no limit.
+	Max time.Duration
+
+	// Jitter adds jitter to the delay. See the documentation for JitterDuration for how it is interpreted.
+	Jitter float64
+}
+
+var _ BackoffPolicy = PolynomialBackoff{}
+
+// Delay returns Initial * attempt^Exponent, capped at Max and adjusted by any jitter.
+func (b PolynomialBackoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	e := b.Exponent
+	if e < 0 {
+		e = 0
+	}
+	return JitterDuration(capDuration(float64(b.Initial)*math.Pow(float64(attempt), e), b.Max), b.Jitter)
+}
+
+// String returns the policy in the form accepted by ParseBackoffPolicy.
+func (b PolynomialBackoff) String() string {
+	s := "poly(" + b.Initial.String() + ", " + formatFloat(b.Exponent)
+	if b.Max != 0 {
+		s += ", max=" + b.Max.String()
+	}
+	if b.Jitter != 0 {
+		s += ", jitter=" + formatFloat(b.Jitter)
+	}
+	return s + ")"
+}
+
+// capDuration converts d to a duration, limiting it to limit if limit is positive and to the
+// largest representable duration otherwise.
+func capDuration(d float64, limit time.Duration) time.Duration {
+	if limit > 0 && d > float64(limit) {
+		return limit
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if math.Trunc(f) == f {
+		s += ".0"
+	}
+	return s
+}
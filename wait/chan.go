@@ -0,0 +1,61 @@
+package wait
+
+import (
+	"context"
+	"sync"
+)
+
+// UntilChan waits until ch is closed or receives a value, or until the context is cancelled. It
+// returns nil if ch became ready, otherwise the cancelled context's error, reported as described
+// for Until.
+func UntilChan(ctx context.Context, ch <-chan struct{}, opts ...Option) error {
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return newOptions(opts).ctxError(ctx, ctx.Err())
+	}
+}
+
+// ChanCondition returns a condition for use with Until that reports true once ch has been closed.
+// It allows a channel-based readiness signal to be combined with polled conditions. The condition
+// never blocks.
+func ChanCondition(ch <-chan struct{}) func(context.Context) (bool, error) {
+	return func(context.Context) (bool, error) {
+		select {
+		case <-ch:
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+}
+
+// FirstOf calls each of waiters concurrently with a context derived from ctx and returns the
+// result of the first to return. The derived context is then cancelled and FirstOf waits for the
+// remaining waiters to return before returning. A panic in a waiter is converted into a
+// *PanicError. If waiters is empty FirstOf waits until the context is cancelled.
+func FirstOf(ctx context.Context, waiters ...func(context.Context) error) error {
+	if len(waiters) == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan error, len(waiters))
+	var wg sync.WaitGroup
+	for _, w := range waiters {
+		wg.Add(1)
+		go func(w func(context.Context) error) {
+			defer wg.Done()
+			results <- safeCall(ctx, w)
+		}(w)
+	}
+
+	err := <-results
+	cancel()
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,48 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFirstOf(t *testing.T) {
+	ready := make(chan struct{})
+	close(ready)
+
+	stopped := false
+	err := FirstOf(context.Background(),
+		func(ctx context.Context) error {
+			return UntilChan(ctx, ready)
+		},
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			stopped = true
+			return ctx.Err()
+		},
+	)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !stopped {
+		t.Errorf("remaining waiter had not returned")
+	}
+}
+
+func TestUntilChanCondition(t *testing.T) {
+	ch := make(chan struct{})
+	time.AfterFunc(10*time.Millisecond, func() { close(ch) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Until(ctx, ChanCondition(ch), 0, time.Millisecond, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := UntilChan(ctx, make(chan struct{})); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,78 @@
+package wait
+
+import (
+	"context"
+	prand "math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// A Clock provides the current time and timers to the loops in this package. The default clock
+// uses the time package. A virtual clock may be supplied using the WithClock option so that the
+// timing of loops can be tested deterministically without waiting in real time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer returns a channel that receives the current time once d has elapsed and a function
+	// that stops the timer, reporting whether it was stopped before it fired.
+	NewTimer(d time.Duration) (<-chan time.Time, func() bool)
+}
+
+// realClock is a Clock that uses the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	t := time.NewTimer(d)
+	return t.C, t.Stop
+}
+
+// WithClock causes a loop such as Until or Retry to measure time and wait using c instead of the
+// time package. It is intended for testing. Note that the deadline of the loop's context is still
+// enforced in real time.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// jitterSource holds the function used to generate random jitter.
+var jitterSource atomic.Pointer[func() float64]
+
+// SetJitterSource replaces the source of randomness used to compute jitter throughout this
+// package with f, which must return values in the range [0,1). It returns a function that restores
+// the previous source. It is intended for testing, to make jittered delays deterministic, and
+// affects all loops and backoff policies in the process.
+func SetJitterSource(f func() float64) (restore func()) {
+	prev := jitterSource.Swap(&f)
+	return func() {
+		jitterSource.Store(prev)
+	}
+}
+
+// randFloat64 returns a random number in the range [0,1) from the current jitter source.
+func randFloat64() float64 {
+	if f := jitterSource.Load(); f != nil {
+		return (*f)()
+	}
+	return prand.Float64()
+}
+
+// sleep waits for d using the clock, or until the context is cancelled, in which case it returns
+// the cancellation error.
+func sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	ch, stop := c.NewTimer(d)
+	defer stop()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,6 +2,7 @@ package wait
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -10,17 +11,32 @@ import (
 // delay specifies the length of time to wait before calling condition for the first time.
 // interval specifies the length of time to wait between subsequent calls to condition.
 // j adds jitter to delay and interval. See the documentation for JitterDuration for how j is interpreted.
-func Until(ctx context.Context, condition func(context.Context) (bool, error), delay time.Duration, interval time.Duration, j float64) error {
+// If the context is cancelled the returned error also wraps the context's cancellation cause, when
+// that differs from the context's error, and is prefixed by any name given using the Operation option.
+func Until(ctx context.Context, condition func(context.Context) (bool, error), delay time.Duration, interval time.Duration, j float64, opts ...Option) error {
+	o := newOptions(opts)
+
+	// Optional check before any waiting
+	if o.immediate {
+		done, err := o.check(ctx, condition)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+
 	// Initial delay
 	if delay > 0 {
-		if err := WithJitter(ctx, delay, j); err != nil {
-			return err
+		if err := o.wait(ctx, delay, j); err != nil {
+			return o.ctxError(ctx, err)
 		}
 	}
 
 	// Loop, checking condition and then waiting
 	for {
-		done, err := condition(ctx)
+		done, err := o.check(ctx, condition)
 		if err != nil {
 			return err
 		}
@@ -28,8 +44,8 @@ func Until(ctx context.Context, condition func(context.Context) (bool, error), d
 			return nil
 		}
 
-		if err := WithJitter(ctx, interval, j); err != nil {
-			return err
+		if err := o.wait(ctx, interval, j); err != nil {
+			return o.ctxError(ctx, err)
 		}
 	}
 }
@@ -39,8 +55,28 @@ func Until(ctx context.Context, condition func(context.Context) (bool, error), d
 // delay specifies the length of time to wait before calling fn for the first time.
 // interval specifies the length of time to wait between subsequent calls to fn.
 // j adds jitter to delay and interval. See the documentation for JitterDuration for how j is interpreted.
-func Forever(ctx context.Context, fn func(context.Context) error, delay time.Duration, interval time.Duration, j float64) error {
+// Errors caused by cancellation of the context are reported as described for Until.
+func Forever(ctx context.Context, fn func(context.Context) error, delay time.Duration, interval time.Duration, j float64, opts ...Option) error {
 	return Until(ctx, func(c context.Context) (bool, error) {
 		return false, fn(c)
-	}, delay, interval, j)
+	}, delay, interval, j, opts...)
+}
+
+// ctxError returns the error to report when a loop stops because ctx is done. err is the
+// context's error, which is wrapped together with the context's cancellation cause when that
+// carries more information, prefixed with the operation name if one was given, marked as matching
+// ErrTimeout if the deadline passed and wrapped in a *TimeoutError if the History option was given.
+func (o *options) ctxError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); cause != nil && cause != err {
+		err = fmt.Errorf("%w: %w", err, cause)
+	}
+	return o.withHistory(timedOut(o.named(err)))
+}
+
+// named returns err prefixed with the operation name, if one was given.
+func (o *options) named(err error) error {
+	if o.name != "" {
+		return fmt.Errorf("%s: %w", o.name, err)
+	}
+	return err
 }
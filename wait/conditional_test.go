@@ -0,0 +1,66 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUntilCancelCause(t *testing.T) {
+	errShutdown := errors.New("shutting down")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errShutdown)
+
+	err := Until(ctx, func(context.Context) (bool, error) {
+		return false, nil
+	}, 0, time.Hour, 0, Operation("poll widgets"))
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v is not context.Canceled", err)
+	}
+	if !errors.Is(err, errShutdown) {
+		t.Errorf("error %v does not wrap cancellation cause", err)
+	}
+	if !strings.HasPrefix(err.Error(), "poll widgets: ") {
+		t.Errorf("error %q is not prefixed with operation name", err)
+	}
+}
+
+func TestUntilObserve(t *testing.T) {
+	var stats Stats
+	calls := 0
+	err := Until(context.Background(), func(context.Context) (bool, error) {
+		calls++
+		time.Sleep(time.Millisecond)
+		return calls == 3, nil
+	}, time.Millisecond, time.Millisecond, 0, Observe(&stats))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := stats.Snapshot()
+	if s.Execs != 3 || s.Waits != 3 {
+		t.Errorf("got %d execs and %d waits, wanted 3 of each", s.Execs, s.Waits)
+	}
+	if s.ExecTime < 3*time.Millisecond || s.WaitTime < 3*time.Millisecond {
+		t.Errorf("got exec time %v and wait time %v, wanted at least 3ms each", s.ExecTime, s.WaitTime)
+	}
+}
+
+func TestUntilImmediate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := Until(ctx, func(context.Context) (bool, error) {
+		return true, nil
+	}, time.Hour, time.Hour, 0.5, Immediate())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("took %v, wanted condition to be checked immediately", elapsed)
+	}
+}
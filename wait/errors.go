@@ -0,0 +1,107 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTimeout is matched, using errors.Is, by the errors returned by loops such as Until and
+	// Retry when they stop because their context's deadline passed. Such errors also match
+	// context.DeadlineExceeded.
+	ErrTimeout = errors.New("wait: timed out")
+
+	// ErrBudgetExhausted is wrapped by the error returned by Retry when the next attempt would
+	// exceed the time allowed by the Budget option.
+	ErrBudgetExhausted = errors.New("wait: retry budget exhausted")
+
+	// ErrMaxAttempts is wrapped by the error returned by Retry when it has made the number of
+	// attempts allowed by the MaxAttempts option.
+	ErrMaxAttempts = errors.New("wait: maximum attempts reached")
+)
+
+// PermanentError wraps an error returned by the function called by Retry to indicate that the
+// operation cannot succeed, so that Retry returns immediately instead of trying again.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps err in a *PermanentError. It returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterError wraps an error returned by the function called by Retry to indicate that the
+// operation should not be attempted again until After has elapsed, as when a server asks clients
+// to slow down. Retry waits for After in place of the delay given by its backoff policy.
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+// RetryAfter wraps err in a *RetryAfterError requesting a delay of d before the next attempt. It
+// returns nil if err is nil.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryAfterError{Err: err, After: d}
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// deadlineError marks an error caused by a passed deadline so that it matches ErrTimeout without
+// changing its message.
+type deadlineError struct {
+	err error
+}
+
+func (e *deadlineError) Error() string        { return e.err.Error() }
+func (e *deadlineError) Unwrap() error        { return e.err }
+func (e *deadlineError) Is(target error) bool { return target == ErrTimeout }
+
+// timedOut returns err marked as matching ErrTimeout if it was caused by a passed deadline.
+func timedOut(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &deadlineError{err: err}
+	}
+	return err
+}
+
+// MaxAttempts causes Retry to stop after n attempts have failed, returning an error wrapping
+// ErrMaxAttempts and the error from the last attempt. Zero means no limit.
+func MaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// Budget causes Retry to stop when waiting for the next attempt would take the total time spent
+// retrying beyond d, returning an error wrapping ErrBudgetExhausted and the error from the last
+// attempt. Unlike a context deadline, the budget stops Retry early rather than waiting for it to
+// pass. Zero means no limit.
+func Budget(d time.Duration) Option {
+	return func(o *options) {
+		o.budget = d
+	}
+}
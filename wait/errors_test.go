@@ -0,0 +1,61 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryErrors(t *testing.T) {
+	errFail := errors.New("fail")
+	policy := FixedBackoff{Interval: time.Millisecond}
+	fail := func(context.Context) error { return errFail }
+
+	t.Run("permanent", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), policy, func(context.Context) error {
+			calls++
+			return Permanent(errFail)
+		})
+		var pe *PermanentError
+		if !errors.As(err, &pe) || !errors.Is(err, errFail) {
+			t.Errorf("got error %v, wanted a *PermanentError wrapping %v", err, errFail)
+		}
+		if calls != 1 {
+			t.Errorf("got %d calls, wanted 1", calls)
+		}
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		err := Retry(context.Background(), policy, fail, MaxAttempts(3))
+		if !errors.Is(err, ErrMaxAttempts) || !errors.Is(err, errFail) {
+			t.Errorf("got error %v, wanted it to wrap %v and %v", err, ErrMaxAttempts, errFail)
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		err := Retry(context.Background(), FixedBackoff{Interval: time.Hour}, fail, Budget(time.Minute))
+		if !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, errFail) {
+			t.Errorf("got error %v, wanted it to wrap %v and %v", err, ErrBudgetExhausted, errFail)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := Retry(ctx, policy, fail)
+		if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errFail) {
+			t.Errorf("got error %v, wanted it to wrap %v, %v and %v", err, ErrTimeout, context.DeadlineExceeded, errFail)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := Until(ctx, func(context.Context) (bool, error) { return false, nil }, time.Millisecond, time.Millisecond, 0)
+		if errors.Is(err, ErrTimeout) {
+			t.Errorf("got error %v, wanted cancellation not to match %v", err, ErrTimeout)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrGroup runs a collection of tasks concurrently and waits for them to complete. It is similar
+// to errgroup.Group but converts panics in tasks into errors and collects the errors returned by
+// all tasks rather than just the first. The first task to return an error cancels the group's
+// context, signalling the remaining tasks to stop.
+//
+// An ErrGroup must be created with NewErrGroup.
+type ErrGroup struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewErrGroup returns a new ErrGroup with a context derived from ctx. The derived context is
+// cancelled when the first task returns a non-nil error or when Wait returns, whichever occurs
+// first.
+func NewErrGroup(ctx context.Context) *ErrGroup {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &ErrGroup{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Context returns the context passed to each task in the group.
+func (g *ErrGroup) Context() context.Context {
+	return g.ctx
+}
+
+// SetLimit limits the number of tasks that may be running concurrently to n. Once the limit
+// is reached, calls to Go block until a running task completes. A negative value indicates
+// no limit. SetLimit must not be called while any tasks are running.
+func (g *ErrGroup) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("wait: modify limit while %d tasks are still running", len(g.sem)))
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go calls fn in a new goroutine, passing it the group's context. If fn returns a non-nil error
+// or panics then the group's context is cancelled. A panic is converted into a *PanicError.
+func (g *ErrGroup) Go(fn func(context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		if err := safeCall(g.ctx, fn); err != nil {
+			g.record(err)
+		}
+	}()
+}
+
+// GoUntil runs Until in a new goroutine using the group's context. See the documentation for
+// Until for the meaning of the arguments.
+func (g *ErrGroup) GoUntil(condition func(context.Context) (bool, error), delay time.Duration, interval time.Duration, j float64, opts ...Option) {
+	g.Go(func(ctx context.Context) error {
+		return Until(ctx, condition, delay, interval, j, opts...)
+	})
+}
+
+// GoForever runs Forever in a new goroutine using the group's context. See the documentation for
+// Forever for the meaning of the arguments.
+func (g *ErrGroup) GoForever(fn func(context.Context) error, delay time.Duration, interval time.Duration, j float64, opts ...Option) {
+	g.Go(func(ctx context.Context) error {
+		return Forever(ctx, fn, delay, interval, j, opts...)
+	})
+}
+
+// Wait blocks until all tasks started with Go have returned and then returns the errors they
+// reported, joined using errors.Join. Errors caused solely by the group cancelling its own context
+// are omitted. Wait returns nil if no task reported an error.
+func (g *ErrGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
+
+func (g *ErrGroup) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+func (g *ErrGroup) record(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Once the group has been cancelled by an earlier failure, other tasks typically
+	// report the cancellation, which is just noise.
+	if len(g.errs) > 0 && errors.Is(err, context.Canceled) && errors.Is(context.Cause(g.ctx), errGroupFailed) {
+		return
+	}
+	g.errs = append(g.errs, err)
+	g.cancel(errGroupFailed)
+}
+
+var errGroupFailed = errors.New("wait: task in group failed")
+
+// PanicError is returned in place of a panic that was recovered from a function called by this
+// package.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the stack trace of the goroutine at the time of the panic
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// safeCall calls fn, converting any panic into a *PanicError.
+func safeCall(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,70 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestErrGroupCollectsErrors(t *testing.T) {
+	g := NewErrGroup(context.Background())
+
+	errA := errors.New("a")
+	g.Go(func(context.Context) error { return errA })
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := g.Wait()
+	if !errors.Is(err, errA) {
+		t.Fatalf("got error %v, wanted %v", err, errA)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("error included group cancellation: %v", err)
+	}
+}
+
+func TestErrGroupPanic(t *testing.T) {
+	g := NewErrGroup(context.Background())
+	g.Go(func(context.Context) error { panic("boom") })
+
+	err := g.Wait()
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got error %v, wanted a PanicError", err)
+	}
+	if pe.Value != "boom" {
+		t.Errorf("got panic value %v, wanted %q", pe.Value, "boom")
+	}
+}
+
+func TestErrGroupLimit(t *testing.T) {
+	g := NewErrGroup(context.Background())
+	g.SetLimit(2)
+
+	var running, peak atomic.Int32
+	for i := 0; i < 10; i++ {
+		g.Go(func(context.Context) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("got %d concurrent tasks, wanted at most 2", p)
+	}
+}
@@ -0,0 +1,48 @@
+package wait
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Attempt records the outcome of a single call made by a loop to its condition or function.
+type Attempt struct {
+	Start    time.Time     // when the attempt started
+	Duration time.Duration // how long the attempt took
+	Err      error         // the error returned by the attempt, if any
+}
+
+// TimeoutError is returned, usually wrapped, when a loop that was given the History option stops
+// because its context was cancelled or its deadline passed. It holds the most recent attempts made
+// by the loop.
+type TimeoutError struct {
+	Err      error     // the error describing why the loop stopped
+	Attempts []Attempt // the most recent attempts, oldest first
+}
+
+func (e *TimeoutError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	if len(e.Attempts) == 0 {
+		b.WriteString(" (no attempts made)")
+		return b.String()
+	}
+	fmt.Fprintf(&b, " (last %d attempts:", len(e.Attempts))
+	for _, a := range e.Attempts {
+		fmt.Fprintf(&b, " [%s took %v: ", a.Start.Format("15:04:05.000"), a.Duration.Round(time.Microsecond))
+		if a.Err != nil {
+			b.WriteString(a.Err.Error())
+		} else {
+			b.WriteString("not done")
+		}
+		b.WriteString("]")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// Unwrap returns the error describing why the loop stopped.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
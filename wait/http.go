@@ -0,0 +1,126 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDrainBytes limits how much of the body of a retried response is read so that its connection
+// may be reused.
+const maxDrainBytes = 4 << 10
+
+// HTTPStatusError is returned by RetryHTTP when the final attempt received a response with a
+// retryable status code.
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string // the status line of the response, such as "503 Service Unavailable"
+}
+
+func (e *HTTPStatusError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode)
+	}
+	return "unexpected http status: " + status
+}
+
+// RetryableStatus reports whether a request that received a response with the given status code
+// may succeed if tried again. Request timeouts, rate limiting and temporary server failures are
+// retryable. Other client errors are permanent, as are successful and redirect responses.
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooEarly,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or
+// an HTTP date, returning the delay it requests relative to now. It reports false if the value
+// cannot be parsed. A date in the past requests no delay.
+func ParseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return capDuration(float64(secs)*float64(time.Second), 0), true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// RetryHTTP calls do using Retry until it returns a response with a status code that is not
+// retryable, as reported by RetryableStatus, and returns that response. The caller is responsible
+// for closing its body and for interpreting its status code, as with http.Client.Do.
+//
+// The bodies of responses with retryable status codes are closed. When such a response is a
+// 429 Too Many Requests or 503 Service Unavailable with a Retry-After header, the delay requested
+// by the server is used in place of the delay given by policy. Errors returned by do are retried
+// unless they wrap a *PermanentError. If no attempt succeeds, RetryHTTP returns the error from
+// Retry, which wraps an *HTTPStatusError if the last attempt received a retryable status code.
+//
+// For example:
+//
+//	resp, err := wait.RetryHTTP(ctx, policy, func(ctx context.Context) (*http.Response, error) {
+//		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//		if err != nil {
+//			return nil, wait.Permanent(err)
+//		}
+//		return http.DefaultClient.Do(req)
+//	}, wait.MaxAttempts(5))
+func RetryHTTP(ctx context.Context, policy BackoffPolicy, do func(context.Context) (*http.Response, error), opts ...Option) (*http.Response, error) {
+	clock := newOptions(opts).clock
+
+	var resp *http.Response
+	err := Retry(ctx, policy, func(ctx context.Context) error {
+		r, err := do(ctx)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return Permanent(fmt.Errorf("wait: http operation returned no response and no error"))
+		}
+		if !RetryableStatus(r.StatusCode) {
+			resp = r
+			return nil
+		}
+
+		if r.Body != nil {
+			_, _ = io.CopyN(io.Discard, r.Body, maxDrainBytes)
+			_ = r.Body.Close()
+		}
+		err = &HTTPStatusError{StatusCode: r.StatusCode, Status: r.Status}
+		if r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusServiceUnavailable {
+			if d, ok := ParseRetryAfter(r.Header.Get("Retry-After"), clock.Now()); ok {
+				return RetryAfter(err, d)
+			}
+		}
+		return err
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
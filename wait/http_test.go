@@ -0,0 +1,132 @@
+package wait_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/pontium/wait"
+)
+
+// response returns a response with the given status code and Retry-After header, if not empty.
+func response(code int, retryAfter string) *http.Response {
+	resp := &http.Response{
+		StatusCode: code,
+		Status:     http.StatusText(code),
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("body")),
+	}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	return resp
+}
+
+func TestRetryHTTP(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := wait.FixedBackoff{Interval: time.Second}
+
+	testCases := []struct {
+		name      string
+		responses []*http.Response
+		want      []time.Duration
+		wantCode  int
+	}{
+		{
+			name:      "retry after seconds",
+			responses: []*http.Response{response(503, "5"), response(200, "")},
+			want:      []time.Duration{0, 5 * time.Second},
+			wantCode:  200,
+		},
+		{
+			name:      "retry after date",
+			responses: []*http.Response{response(429, start.Add(10*time.Second).Format(http.TimeFormat)), response(200, "")},
+			want:      []time.Duration{0, 10 * time.Second},
+			wantCode:  200,
+		},
+		{
+			name:      "retry after ignored",
+			responses: []*http.Response{response(500, "5"), response(502, ""), response(204, "")},
+			want:      []time.Duration{0, time.Second, 2 * time.Second},
+			wantCode:  204,
+		},
+		{
+			name:      "permanent",
+			responses: []*http.Response{response(404, "5"), response(200, "")},
+			want:      []time.Duration{0},
+			wantCode:  404,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var resp *http.Response
+			got, err := simulate(t, 0, func(ctx context.Context, call func(), clock wait.Clock) error {
+				n := 0
+				var err error
+				resp, err = wait.RetryHTTP(ctx, policy, func(context.Context) (*http.Response, error) {
+					call()
+					n++
+					return tc.responses[n-1], nil
+				}, wait.WithClock(clock))
+				return err
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode != tc.wantCode {
+				t.Errorf("got status %d, wanted %d", resp.StatusCode, tc.wantCode)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got calls at %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryHTTPExhausted(t *testing.T) {
+	_, err := simulate(t, 0, func(ctx context.Context, call func(), clock wait.Clock) error {
+		resp, err := wait.RetryHTTP(ctx, wait.FixedBackoff{Interval: time.Second}, func(context.Context) (*http.Response, error) {
+			call()
+			return response(503, ""), nil
+		}, wait.WithClock(clock), wait.MaxAttempts(3))
+		if resp != nil {
+			t.Errorf("got a response, wanted none")
+		}
+		return err
+	})
+
+	var se *wait.HTTPStatusError
+	if !errors.As(err, &se) || se.StatusCode != 503 || !errors.Is(err, wait.ErrMaxAttempts) {
+		t.Errorf("got error %v, wanted an *HTTPStatusError with status 503 wrapping %v", err, wait.ErrMaxAttempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{in: "120", want: 2 * time.Minute, ok: true},
+		{in: " 0 ", want: 0, ok: true},
+		{in: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second, ok: true},
+		{in: now.Add(-time.Hour).Format(http.TimeFormat), want: 0, ok: true},
+		{in: "", ok: false},
+		{in: "-1", ok: false},
+		{in: "soon", ok: false},
+	}
+
+	for _, tc := range testCases {
+		got, ok := wait.ParseRetryAfter(tc.in, now)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%q: got %v, %v, wanted %v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
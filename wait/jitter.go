@@ -1,7 +1,6 @@
 package wait
 
 import (
-	prand "math/rand"
 	"time"
 )
 
@@ -12,5 +11,5 @@ func JitterDuration(d time.Duration, j float64) time.Duration {
 	if j < 0 || j >= 1.0 {
 		return d
 	}
-	return d + time.Duration(float64(d)*prand.Float64()*j)
+	return d + time.Duration(float64(d)*randFloat64()*j)
 }
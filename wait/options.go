@@ -0,0 +1,155 @@
+package wait
+
+import (
+	"context"
+	"time"
+)
+
+// An Option configures the behaviour of a function in this package that accepts options.
+type Option func(*options)
+
+type options struct {
+	finalAttempt bool
+	immediate    bool
+	recover      bool
+	name         string
+	observer     Observer
+	history      int       // maximum number of attempts to record
+	attempts     []Attempt // most recent attempts, oldest first
+	clock        Clock
+	maxAttempts  int           // maximum number of attempts made by Retry
+	budget       time.Duration // maximum time spent by Retry
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{clock: realClock{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// FinalAttempt causes Retry to make one last attempt immediately when the context's deadline
+// would pass before the next attempt is due, instead of waiting past the deadline and returning
+// without trying again. It has no effect when the context has no deadline.
+func FinalAttempt() Option {
+	return func(o *options) {
+		o.finalAttempt = true
+	}
+}
+
+// Immediate causes Until and Forever to make their first call to the condition or function
+// synchronously, before any waiting. If that call does not complete the loop, the loop continues
+// as usual by waiting for the initial delay, including any jitter, before calling again.
+func Immediate() Option {
+	return func(o *options) {
+		o.immediate = true
+	}
+}
+
+// RecoverPanics causes a loop such as Until or Retry to convert a panic in its condition or function
+// into a returned *PanicError, which captures the stack at the time of the panic, instead of
+// crashing the program. This is useful when the condition calls code of unknown quality.
+func RecoverPanics() Option {
+	return func(o *options) {
+		o.recover = true
+	}
+}
+
+// Operation names the operation being performed by a loop such as Until or Retry. The name
+// prefixes the error returned when the loop stops because its context was cancelled, so that logs
+// explain which wait loop stopped.
+func Operation(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// Observe causes a loop such as Until or Retry to report the time it spends executing its
+// condition or function and the time it spends waiting between calls to obs. This can be used to
+// tune polling intervals based on real data. Stats is an implementation of Observer that
+// accumulates totals.
+func Observe(obs Observer) Option {
+	return func(o *options) {
+		o.observer = obs
+	}
+}
+
+// History causes a loop such as Until or Retry to record the outcome of its n most recent
+// attempts. When the loop stops because its context was cancelled or its deadline passed, the
+// returned error wraps a *TimeoutError holding the recorded attempts, which can be retrieved
+// using errors.As and are included in the error's message. This helps to diagnose waits that
+// never succeed.
+func History(n int) Option {
+	return func(o *options) {
+		o.history = n
+	}
+}
+
+// wait waits for interval adjusted by jitter j, as WithJitter but using the loop's clock, reporting
+// the time spent to any observer.
+func (o *options) wait(ctx context.Context, interval time.Duration, j float64) error {
+	if interval <= 0 {
+		return nil
+	}
+	start := o.clock.Now()
+	err := sleep(ctx, o.clock, JitterDuration(interval, j))
+	if o.observer != nil {
+		o.observer.ObserveWait(o.clock.Now().Sub(start))
+	}
+	return err
+}
+
+// check calls condition, recording the attempt and recovering any panic if requested.
+func (o *options) check(ctx context.Context, condition func(context.Context) (bool, error)) (done bool, err error) {
+	start := o.clock.Now()
+	if o.recover {
+		err = safeCall(ctx, func(ctx context.Context) error {
+			var cerr error
+			done, cerr = condition(ctx)
+			return cerr
+		})
+	} else {
+		done, err = condition(ctx)
+	}
+	o.attempted(start, err)
+	return done, err
+}
+
+// call calls fn, recording the attempt and recovering any panic if requested.
+func (o *options) call(ctx context.Context, fn func(context.Context) error) error {
+	start := o.clock.Now()
+	var err error
+	if o.recover {
+		err = safeCall(ctx, fn)
+	} else {
+		err = fn(ctx)
+	}
+	o.attempted(start, err)
+	return err
+}
+
+// attempted reports the time since start as time spent executing to any observer and records
+// the outcome of the attempt in the history, if one is being kept.
+func (o *options) attempted(start time.Time, err error) {
+	d := o.clock.Now().Sub(start)
+	if o.observer != nil {
+		o.observer.ObserveExec(d)
+	}
+	if o.history > 0 {
+		if len(o.attempts) == o.history {
+			copy(o.attempts, o.attempts[1:])
+			o.attempts = o.attempts[:len(o.attempts)-1]
+		}
+		o.attempts = append(o.attempts, Attempt{Start: start, Duration: d, Err: err})
+	}
+}
+
+// withHistory wraps err in a *TimeoutError holding the recorded attempts, if a history is being
+// kept.
+func (o *options) withHistory(err error) error {
+	if o.history <= 0 {
+		return err
+	}
+	return &TimeoutError{Err: err, Attempts: append([]Attempt(nil), o.attempts...)}
+}
@@ -0,0 +1,222 @@
+package wait
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseBackoffPolicy parses a textual description of a backoff policy, as might be found in a
+// configuration file or environment variable. The following forms are recognised:
+//
+//	fixed(interval, jitter=j)
+//	exp(initial, multiplier, max=d, jitter=j)
+//	linear(initial, step, max=d, jitter=j)
+//	poly(initial, exponent, max=d, jitter=j)
+//
+// Durations are written in the form accepted by time.ParseDuration. Named arguments are optional
+// and may appear in any order after the positional arguments. The multiplier of an exponential
+// policy is optional and defaults to 2, the step of a linear policy defaults to its initial delay
+// and the exponent of a polynomial policy defaults to 2. For example:
+//
+//	fixed(5s)
+//	exp(100ms, 2.0, max=30s, jitter=0.2)
+//	linear(1s, 500ms, max=10s)
+func ParseBackoffPolicy(s string) (BackoffPolicy, error) {
+	name, args, err := splitPolicy(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var pos []string
+	named := map[string]string{}
+	for _, arg := range args {
+		if k, v, ok := strings.Cut(arg, "="); ok {
+			k = strings.TrimSpace(k)
+			if _, exists := named[k]; exists {
+				return nil, fmt.Errorf("backoff policy %q: duplicate argument %q", s, k)
+			}
+			named[k] = strings.TrimSpace(v)
+			continue
+		}
+		if len(named) > 0 {
+			return nil, fmt.Errorf("backoff policy %q: positional argument %q follows named argument", s, arg)
+		}
+		pos = append(pos, arg)
+	}
+
+	p := policyParser{src: s, named: named}
+	switch name {
+	case "fixed":
+		if len(pos) != 1 {
+			return nil, fmt.Errorf("backoff policy %q: fixed takes 1 positional argument, got %d", s, len(pos))
+		}
+		b := FixedBackoff{
+			Interval: p.duration("interval", pos[0]),
+			Jitter:   p.namedFloat("jitter"),
+		}
+		return b, p.done()
+	case "exp", "exponential":
+		if len(pos) < 1 || len(pos) > 2 {
+			return nil, fmt.Errorf("backoff policy %q: exp takes 1 or 2 positional arguments, got %d", s, len(pos))
+		}
+		b := ExponentialBackoff{
+			Initial:    p.duration("initial", pos[0]),
+			Multiplier: 2,
+			Max:        p.namedDuration("max"),
+			Jitter:     p.namedFloat("jitter"),
+		}
+		if len(pos) > 1 {
+			b.Multiplier = p.float("multiplier", pos[1])
+		}
+		return b, p.done()
+	case "linear":
+		if len(pos) < 1 || len(pos) > 2 {
+			return nil, fmt.Errorf("backoff policy %q: linear takes 1 or 2 positional arguments, got %d", s, len(pos))
+		}
+		b := LinearBackoff{
+			Initial: p.duration("initial", pos[0]),
+			Max:     p.namedDuration("max"),
+			Jitter:  p.namedFloat("jitter"),
+		}
+		b.Step = b.Initial
+		if len(pos) > 1 {
+			b.Step = p.duration("step", pos[1])
+		}
+		return b, p.done()
+	case "poly", "polynomial":
+		if len(pos) < 1 || len(pos) > 2 {
+			return nil, fmt.Errorf("backoff policy %q: poly takes 1 or 2 positional arguments, got %d", s, len(pos))
+		}
+		b := PolynomialBackoff{
+			Initial:  p.duration("initial", pos[0]),
+			Exponent: 2,
+			Max:      p.namedDuration("max"),
+			Jitter:   p.namedFloat("jitter"),
+		}
+		if len(pos) > 1 {
+			b.Exponent = p.float("exponent", pos[1])
+		}
+		return b, p.done()
+	default:
+		return nil, fmt.Errorf("backoff policy %q: unknown policy %q", s, name)
+	}
+}
+
+// splitPolicy splits a policy of the form name(arg, arg, ...) into its name and arguments.
+func splitPolicy(s string) (string, []string, error) {
+	s = strings.TrimSpace(s)
+	open := strings.IndexByte(s, '(')
+	if open == -1 || !strings.HasSuffix(s, ")") {
+		return "", nil, fmt.Errorf("backoff policy %q: expected the form name(args)", s)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(s[:open]))
+	inner := strings.TrimSpace(s[open+1 : len(s)-1])
+	if inner == "" {
+		return name, nil, nil
+	}
+
+	args := strings.Split(inner, ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+		if args[i] == "" {
+			return "", nil, fmt.Errorf("backoff policy %q: empty argument", s)
+		}
+	}
+	return name, args, nil
+}
+
+// policyParser converts policy arguments, retaining the first error encountered.
+type policyParser struct {
+	src   string
+	named map[string]string
+	err   error
+}
+
+func (p *policyParser) duration(name, v string) time.Duration {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		p.fail(name, v, err)
+	}
+	return d
+}
+
+func (p *policyParser) float(name, v string) float64 {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		p.fail(name, v, err)
+	}
+	return f
+}
+
+func (p *policyParser) namedDuration(name string) time.Duration {
+	v, ok := p.named[name]
+	if !ok {
+		return 0
+	}
+	delete(p.named, name)
+	return p.duration(name, v)
+}
+
+func (p *policyParser) namedFloat(name string) float64 {
+	v, ok := p.named[name]
+	if !ok {
+		return 0
+	}
+	delete(p.named, name)
+	return p.float(name, v)
+}
+
+func (p *policyParser) fail(name, v string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("backoff policy %q: invalid %s %q: %w", p.src, name, v, err)
+	}
+}
+
+// done reports the first error encountered or any named arguments that were not consumed.
+func (p *policyParser) done() error {
+	if p.err != nil {
+		return p.err
+	}
+	for k := range p.named {
+		return fmt.Errorf("backoff policy %q: unknown argument %q", p.src, k)
+	}
+	return nil
+}
+
+// BackoffPolicyValue holds a BackoffPolicy and implements flag.Value, encoding.TextMarshaler and
+// encoding.TextUnmarshaler so that a policy can be read from command line flags, environment
+// variables or configuration files using the syntax accepted by ParseBackoffPolicy.
+type BackoffPolicyValue struct {
+	Policy BackoffPolicy
+}
+
+// Set parses s and replaces the held policy.
+func (v *BackoffPolicyValue) Set(s string) error {
+	p, err := ParseBackoffPolicy(s)
+	if err != nil {
+		return err
+	}
+	v.Policy = p
+	return nil
+}
+
+// String returns the held policy in the form accepted by ParseBackoffPolicy.
+func (v *BackoffPolicyValue) String() string {
+	if v == nil || v.Policy == nil {
+		return ""
+	}
+	return fmt.Sprint(v.Policy)
+}
+
+// UnmarshalText parses text and replaces the held policy.
+func (v *BackoffPolicyValue) UnmarshalText(text []byte) error {
+	return v.Set(string(text))
+}
+
+// MarshalText returns the held policy in the form accepted by ParseBackoffPolicy.
+func (v *BackoffPolicyValue) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
@@ -0,0 +1,110 @@
+package wait
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBackoffPolicy(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want BackoffPolicy
+	}{
+		{in: "fixed(5s)", want: FixedBackoff{Interval: 5 * time.Second}},
+		{in: "fixed(5s, jitter=0.1)", want: FixedBackoff{Interval: 5 * time.Second, Jitter: 0.1}},
+		{in: "exp(100ms)", want: ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 2}},
+		{
+			in:   "exp(100ms, 2.0, max=30s, jitter=0.2)",
+			want: ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 2, Max: 30 * time.Second, Jitter: 0.2},
+		},
+		{
+			in:   " Exponential( 1s , 1.5 , jitter = 0.5 ) ",
+			want: ExponentialBackoff{Initial: time.Second, Multiplier: 1.5, Jitter: 0.5},
+		},
+		{in: "linear(1s)", want: LinearBackoff{Initial: time.Second, Step: time.Second}},
+		{
+			in:   "linear(1s, 500ms, max=10s, jitter=0.1)",
+			want: LinearBackoff{Initial: time.Second, Step: 500 * time.Millisecond, Max: 10 * time.Second, Jitter: 0.1},
+		},
+		{in: "poly(100ms)", want: PolynomialBackoff{Initial: 100 * time.Millisecond, Exponent: 2}},
+		{
+			in:   "polynomial(100ms, 1.5, max=5s)",
+			want: PolynomialBackoff{Initial: 100 * time.Millisecond, Exponent: 1.5, Max: 5 * time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseBackoffPolicy(tc.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %#v, wanted %#v", got, tc.want)
+			}
+
+			// Policies should round trip through their string form
+			again, err := ParseBackoffPolicy(got.(interface{ String() string }).String())
+			if err != nil {
+				t.Fatalf("unexpected error parsing string form: %v", err)
+			}
+			if again != tc.want {
+				t.Errorf("round trip got %#v, wanted %#v", again, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseBackoffPolicyErrors(t *testing.T) {
+	testCases := []string{
+		"",
+		"fixed",
+		"fixed()",
+		"fixed(5)",
+		"fixed(5s, 6s)",
+		"fixed(5s, max=10s)",
+		"exp(100ms, x)",
+		"exp(jitter=0.1, 100ms)",
+		"exp(100ms, max=1s, max=2s)",
+		"linear(1s, 2s, 3s)",
+		"linear(1s, 2)",
+		"poly(1s, x)",
+		"cubic(1s)",
+	}
+
+	for _, tc := range testCases {
+		if _, err := ParseBackoffPolicy(tc); err == nil {
+			t.Errorf("%q: expected an error", tc)
+		}
+	}
+}
+
+func TestExponentialBackoffDelay(t *testing.T) {
+	b := ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 2, Max: time.Second}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w*time.Millisecond {
+			t.Errorf("attempt %d: got %v, wanted %v", i+1, got, w*time.Millisecond)
+		}
+	}
+}
+
+func TestLinearBackoffDelay(t *testing.T) {
+	b := LinearBackoff{Initial: 100 * time.Millisecond, Step: 250 * time.Millisecond, Max: time.Second}
+	want := []time.Duration{100, 350, 600, 850, 1000, 1000}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w*time.Millisecond {
+			t.Errorf("attempt %d: got %v, wanted %v", i+1, got, w*time.Millisecond)
+		}
+	}
+}
+
+func TestPolynomialBackoffDelay(t *testing.T) {
+	b := PolynomialBackoff{Initial: 100 * time.Millisecond, Exponent: 2, Max: 2 * time.Second}
+	want := []time.Duration{100, 400, 900, 1600, 2000, 2000}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w*time.Millisecond {
+			t.Errorf("attempt %d: got %v, wanted %v", i+1, got, w*time.Millisecond)
+		}
+	}
+}
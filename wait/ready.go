@@ -0,0 +1,60 @@
+package wait
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	// readinessInterval is the interval between checks made by readiness functions such as ForTCP.
+	readinessInterval = 250 * time.Millisecond
+
+	// readinessJitter is the jitter applied to readinessInterval.
+	readinessJitter = 0.1
+
+	// readinessAttemptTimeout limits the duration of each check made by a readiness function.
+	readinessAttemptTimeout = 2 * time.Second
+)
+
+// ForTCP waits until a TCP connection can be established with addr or until the context is cancelled.
+// It returns nil once a connection has been made, otherwise the cancelled context's error.
+func ForTCP(ctx context.Context, addr string, opts ...Option) error {
+	d := net.Dialer{Timeout: readinessAttemptTimeout}
+	return Until(ctx, func(ctx context.Context) (bool, error) {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	}, 0, readinessInterval, readinessJitter, opts...)
+}
+
+// ForDNS waits until name resolves to at least one address or until the context is cancelled.
+// It returns nil once name has been resolved, otherwise the cancelled context's error.
+func ForDNS(ctx context.Context, name string, opts ...Option) error {
+	var r net.Resolver
+	return Until(ctx, func(ctx context.Context) (bool, error) {
+		ctx, cancel := context.WithTimeout(ctx, readinessAttemptTimeout)
+		defer cancel()
+		addrs, err := r.LookupHost(ctx, name)
+		return err == nil && len(addrs) > 0, nil
+	}, 0, readinessInterval, readinessJitter, opts...)
+}
+
+// ForPortFree waits until the local TCP address addr is free to listen on, such as after the
+// process that held it has exited, or until the context is cancelled. It returns nil once the
+// address is free, otherwise the cancelled context's error. The address is released before
+// ForPortFree returns so another process may claim it at any time.
+func ForPortFree(ctx context.Context, addr string, opts ...Option) error {
+	var lc net.ListenConfig
+	return Until(ctx, func(ctx context.Context) (bool, error) {
+		ln, err := lc.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return false, nil
+		}
+		ln.Close()
+		return true, nil
+	}, 0, readinessInterval, readinessJitter, opts...)
+}
@@ -0,0 +1,37 @@
+package wait
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestForPortFree(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := ForPortFree(ctx, addr); err == nil {
+		t.Errorf("expected an error while the port is in use")
+	}
+
+	ln.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ForPortFree(ctx, addr); err != nil {
+		t.Errorf("unexpected error after the port was released: %v", err)
+	}
+}
+
+func TestForDNS(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ForDNS(ctx, "localhost"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Retry repeatedly calls fn until it returns nil or until the context is cancelled, waiting between
+// attempts for the delay given by policy. It returns nil if an attempt succeeds. If the context is
+// cancelled it returns the context's error wrapped together with the error from the last attempt.
+// The context's error is reported as described for Until. The FinalAttempt option may be used to
+// modify how Retry behaves when the context's deadline is near. When the RecoverPanics option is
+// given, a panic in fn is returned immediately rather than retried. An error wrapping a
+// *PermanentError is also returned immediately, while an error wrapping a *RetryAfterError with a
+// positive delay replaces the delay given by policy before the next attempt. The MaxAttempts and
+// Budget options limit the number of attempts and the time spent retrying.
+func Retry(ctx context.Context, policy BackoffPolicy, fn func(context.Context) error, opts ...Option) error {
+	o := newOptions(opts)
+	start := o.clock.Now()
+	for attempt := 1; ; attempt++ {
+		err := o.call(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		if o.recover && isPanic(err) {
+			return err
+		}
+		var pe *PermanentError
+		if errors.As(err, &pe) {
+			return err
+		}
+		if o.maxAttempts > 0 && attempt >= o.maxAttempts {
+			return o.named(fmt.Errorf("%w (%d): last error: %w", ErrMaxAttempts, attempt, err))
+		}
+
+		delay := policy.Delay(attempt)
+		var ra *RetryAfterError
+		if errors.As(err, &ra) && ra.After > 0 {
+			delay = ra.After
+		}
+		if o.budget > 0 && o.clock.Now().Sub(start)+delay > o.budget {
+			return o.named(fmt.Errorf("%w: last error: %w", ErrBudgetExhausted, err))
+		}
+		if o.finalAttempt {
+			if deadline, ok := ctx.Deadline(); ok && deadline.Sub(o.clock.Now()) <= delay && ctx.Err() == nil {
+				err := o.call(ctx, fn)
+				if err != nil {
+					return o.withHistory(timedOut(fmt.Errorf("%w: final attempt: %w", context.DeadlineExceeded, err)))
+				}
+				return nil
+			}
+		}
+
+		if werr := o.wait(ctx, delay, 0); werr != nil {
+			return fmt.Errorf("%w: last error: %w", o.ctxError(ctx, werr), err)
+		}
+	}
+}
+
+// isPanic reports whether err is a panic recovered by this package.
+func isPanic(err error) bool {
+	var pe *PanicError
+	return errors.As(err, &pe)
+}
+
+// UntilBackoff repeatedly calls condition until it returns true, an error or until the context is
+// cancelled. It is like Until but waits between calls for the delay given by policy rather than
+// a fixed interval. It returns any error returned from condition or the cancelled context, which
+// is reported as described for Until.
+func UntilBackoff(ctx context.Context, condition func(context.Context) (bool, error), policy BackoffPolicy, opts ...Option) error {
+	o := newOptions(opts)
+	for attempt := 1; ; attempt++ {
+		done, err := o.check(ctx, condition)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		if err := o.wait(ctx, policy.Delay(attempt), 0); err != nil {
+			return o.ctxError(ctx, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryFinalAttempt(t *testing.T) {
+	errFail := errors.New("fail")
+	policy := FixedBackoff{Interval: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	attempts := 0
+	err := Retry(ctx, policy, func(context.Context) error {
+		attempts++
+		if attempts < 2 {
+			return errFail
+		}
+		return nil
+	}, FinalAttempt())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("got %d attempts, wanted 2", attempts)
+	}
+
+	attempts = 0
+	err = Retry(ctx, policy, func(context.Context) error {
+		attempts++
+		return errFail
+	}, FinalAttempt())
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errFail) {
+		t.Errorf("got error %v, wanted deadline exceeded wrapping the final attempt's error", err)
+	}
+	if attempts != 2 {
+		t.Errorf("got %d attempts, wanted 2", attempts)
+	}
+}
+
+func TestRetryHistory(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	err := Retry(ctx, FixedBackoff{Interval: 5 * time.Millisecond}, func(context.Context) error {
+		calls++
+		return fmt.Errorf("attempt %d failed", calls)
+	}, History(2))
+
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("got error %v, wanted a *TimeoutError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted it to wrap %v", err, context.DeadlineExceeded)
+	}
+	if len(te.Attempts) != 2 {
+		t.Fatalf("got %d attempts, wanted 2", len(te.Attempts))
+	}
+	want := fmt.Sprintf("attempt %d failed", calls)
+	if got := te.Attempts[1].Err.Error(); got != want {
+		t.Errorf("got last attempt error %q, wanted %q", got, want)
+	}
+}
+
+func TestRetryRecoverPanics(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), FixedBackoff{Interval: time.Millisecond}, func(context.Context) error {
+		calls++
+		panic("boom")
+	}, RecoverPanics())
+
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got error %v, wanted a *PanicError", err)
+	}
+	if pe.Value != "boom" {
+		t.Errorf("got panic value %v, wanted %q", pe.Value, "boom")
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, wanted panic to stop retrying", calls)
+	}
+}
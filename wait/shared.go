@@ -0,0 +1,138 @@
+package wait
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sharedBackoffShards is the number of independently locked partitions of a SharedBackoff's keys.
+const sharedBackoffShards = 32
+
+// SharedBackoff tracks backoff state for many independent keys, such as the hosts that a connection
+// pool dials, using a single BackoffPolicy. State for a key is created on first use and discarded
+// once the key has not been used for the idle expiry period, so memory use is bounded by the number
+// of recently active keys. Keys are partitioned across independently locked shards to limit lock
+// contention.
+//
+// A SharedBackoff is safe for concurrent use.
+type SharedBackoff struct {
+	policy  BackoffPolicy
+	idleTTL time.Duration
+	shards  [sharedBackoffShards]backoffShard
+}
+
+type backoffShard struct {
+	mu        sync.Mutex
+	keys      map[string]*KeyBackoff
+	lastSweep time.Time
+}
+
+// NewSharedBackoff returns a SharedBackoff that computes delays using policy and discards the state
+// of keys that have not been used for idleTTL. If idleTTL is zero or negative, state is never
+// discarded.
+func NewSharedBackoff(policy BackoffPolicy, idleTTL time.Duration) *SharedBackoff {
+	return &SharedBackoff{
+		policy:  policy,
+		idleTTL: idleTTL,
+	}
+}
+
+// ForKey returns the backoff state for key, creating it if necessary. Callers may retain the
+// returned value but should call ForKey again after a period of inactivity longer than the idle
+// expiry period, since the retained state may have been discarded.
+func (s *SharedBackoff) ForKey(key string) *KeyBackoff {
+	now := time.Now()
+	sh := &s.shards[shardIndex(key)]
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if s.idleTTL > 0 && now.Sub(sh.lastSweep) > s.idleTTL {
+		sh.sweep(now.Add(-s.idleTTL))
+		sh.lastSweep = now
+	}
+
+	k, ok := sh.keys[key]
+	if !ok {
+		if sh.keys == nil {
+			sh.keys = make(map[string]*KeyBackoff)
+		}
+		k = &KeyBackoff{policy: s.policy}
+		sh.keys[key] = k
+	}
+	k.lastUsed.Store(now.UnixNano())
+	return k
+}
+
+// Len returns the number of keys for which state is currently held.
+func (s *SharedBackoff) Len() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		n += len(sh.keys)
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// sweep discards keys last used before cutoff. The caller must hold sh.mu.
+func (sh *backoffShard) sweep(cutoff time.Time) {
+	c := cutoff.UnixNano()
+	for key, k := range sh.keys {
+		if k.lastUsed.Load() < c {
+			delete(sh.keys, key)
+		}
+	}
+}
+
+// shardIndex returns the shard for key using the FNV-1a hash.
+func shardIndex(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % sharedBackoffShards)
+}
+
+// KeyBackoff is the backoff state for a single key of a SharedBackoff. It counts consecutive
+// failures and uses the SharedBackoff's policy to compute the delay before the next attempt.
+//
+// A KeyBackoff is safe for concurrent use.
+type KeyBackoff struct {
+	policy   BackoffPolicy
+	failures atomic.Int64
+	lastUsed atomic.Int64 // unix nanoseconds
+}
+
+// Failure records a failed attempt and returns the delay to wait before the next attempt.
+func (k *KeyBackoff) Failure() time.Duration {
+	n := k.failures.Add(1)
+	k.touch()
+	return k.policy.Delay(int(n))
+}
+
+// Success records a successful attempt, resetting the count of consecutive failures.
+func (k *KeyBackoff) Success() {
+	k.failures.Store(0)
+	k.touch()
+}
+
+// Failures returns the number of consecutive failures recorded since the last success.
+func (k *KeyBackoff) Failures() int {
+	return int(k.failures.Load())
+}
+
+// Wait records a failed attempt and waits for the resulting delay or until the context is
+// cancelled, in which case it returns the cancellation error. The WithClock and Observe options
+// apply to the wait.
+func (k *KeyBackoff) Wait(ctx context.Context, opts ...Option) error {
+	return newOptions(opts).wait(ctx, k.Failure(), 0)
+}
+
+func (k *KeyBackoff) touch() {
+	k.lastUsed.Store(time.Now().UnixNano())
+}
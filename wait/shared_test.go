@@ -0,0 +1,32 @@
+package wait
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSharedBackoff(t *testing.T) {
+	s := NewSharedBackoff(ExponentialBackoff{Initial: time.Second, Multiplier: 2}, time.Millisecond)
+
+	a := s.ForKey("a")
+	if d := a.Failure(); d != time.Second {
+		t.Errorf("got first delay %v, wanted %v", d, time.Second)
+	}
+	if d := s.ForKey("a").Failure(); d != 2*time.Second {
+		t.Errorf("got second delay %v, wanted %v", d, 2*time.Second)
+	}
+	if d := s.ForKey("b").Failure(); d != time.Second {
+		t.Errorf("got first delay for other key %v, wanted %v", d, time.Second)
+	}
+
+	a.Success()
+	if n := a.Failures(); n != 0 {
+		t.Errorf("got %d failures after success, wanted 0", n)
+	}
+
+	// Idle keys are discarded once the expiry period has passed
+	time.Sleep(5 * time.Millisecond)
+	if n := s.ForKey("b").Failures(); n != 0 {
+		t.Errorf("got %d failures for idle key, wanted its state to be discarded", n)
+	}
+}
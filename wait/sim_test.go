@@ -0,0 +1,203 @@
+package wait_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iand/pontium/test"
+	"github.com/iand/pontium/wait"
+)
+
+var errSim = errors.New("simulated failure")
+
+// simulate runs loop under a virtual clock that advances automatically and a fixed jitter source,
+// returning the virtual times, relative to the start, at which the loop called its condition.
+func simulate(t *testing.T, jitter float64, loop func(ctx context.Context, call func(), clock wait.Clock) error) ([]time.Duration, error) {
+	t.Helper()
+	defer wait.SetJitterSource(func() float64 { return jitter })()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := test.NewAutoClock(start)
+
+	var calls []time.Duration
+	err := loop(test.CtxShort(t), func() {
+		calls = append(calls, clock.Now().Sub(start))
+	}, clock)
+	return calls, err
+}
+
+func TestSimulateUntil(t *testing.T) {
+	testCases := []struct {
+		name     string
+		delay    time.Duration
+		interval time.Duration
+		jitter   float64 // jitter fraction passed to Until
+		source   float64 // value returned by the jitter source
+		calls    int     // number of calls before the condition is satisfied
+		opts     []wait.Option
+		want     []time.Duration
+	}{
+		{
+			name:     "no delay",
+			interval: time.Second,
+			calls:    3,
+			want:     []time.Duration{0, time.Second, 2 * time.Second},
+		},
+		{
+			name:     "delay",
+			delay:    5 * time.Second,
+			interval: time.Second,
+			calls:    2,
+			want:     []time.Duration{5 * time.Second, 6 * time.Second},
+		},
+		{
+			name:     "jitter",
+			delay:    2 * time.Second,
+			interval: time.Second,
+			jitter:   0.5,
+			source:   0.5,
+			calls:    2,
+			want:     []time.Duration{2500 * time.Millisecond, 3750 * time.Millisecond},
+		},
+		{
+			name:     "immediate",
+			delay:    5 * time.Second,
+			interval: time.Second,
+			calls:    3,
+			opts:     []wait.Option{wait.Immediate()},
+			want:     []time.Duration{0, 5 * time.Second, 6 * time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := simulate(t, tc.source, func(ctx context.Context, call func(), clock wait.Clock) error {
+				n := 0
+				return wait.Until(ctx, func(context.Context) (bool, error) {
+					call()
+					n++
+					return n == tc.calls, nil
+				}, tc.delay, tc.interval, tc.jitter, append(tc.opts, wait.WithClock(clock))...)
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got calls at %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSimulateRetry(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy wait.BackoffPolicy
+		source float64
+		calls  int
+		want   []time.Duration
+	}{
+		{
+			name:   "fixed",
+			policy: wait.FixedBackoff{Interval: time.Second},
+			calls:  3,
+			want:   []time.Duration{0, time.Second, 2 * time.Second},
+		},
+		{
+			name:   "exponential",
+			policy: wait.ExponentialBackoff{Initial: time.Second, Multiplier: 2, Max: 3 * time.Second},
+			calls:  4,
+			want:   []time.Duration{0, time.Second, 3 * time.Second, 6 * time.Second},
+		},
+		{
+			name:   "exponential jitter",
+			policy: wait.ExponentialBackoff{Initial: time.Second, Multiplier: 2, Jitter: 0.5},
+			source: 0.5,
+			calls:  3,
+			want:   []time.Duration{0, 1250 * time.Millisecond, 3750 * time.Millisecond},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := simulate(t, tc.source, func(ctx context.Context, call func(), clock wait.Clock) error {
+				n := 0
+				return wait.Retry(ctx, tc.policy, func(context.Context) error {
+					call()
+					n++
+					if n == tc.calls {
+						return nil
+					}
+					return errSim
+				}, wait.WithClock(clock))
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got calls at %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSimulateUntilBackoff(t *testing.T) {
+	got, err := simulate(t, 0, func(ctx context.Context, call func(), clock wait.Clock) error {
+		n := 0
+		return wait.UntilBackoff(ctx, func(context.Context) (bool, error) {
+			call()
+			n++
+			return n == 4, nil
+		}, wait.ExponentialBackoff{Initial: time.Second, Multiplier: 2}, wait.WithClock(clock))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []time.Duration{0, time.Second, 3 * time.Second, 7 * time.Second}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls at %v, wanted %v", got, want)
+	}
+}
+
+func TestSimulateAdaptive(t *testing.T) {
+	got, err := simulate(t, 0, func(ctx context.Context, call func(), clock wait.Clock) error {
+		n := 0
+		return wait.Adaptive(ctx, func(context.Context) (bool, error) {
+			call()
+			n++
+			if n == 3 {
+				return false, errSim
+			}
+			return false, nil
+		}, time.Second, 11*time.Second, 0, wait.WithClock(clock))
+	})
+	if !errors.Is(err, errSim) {
+		t.Fatalf("got error %v, wanted %v", err, errSim)
+	}
+	// Each unchanged observation moves the interval a fifth of the way towards the maximum
+	if want := []time.Duration{0, 3 * time.Second, 7600 * time.Millisecond}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls at %v, wanted %v", got, want)
+	}
+}
+
+func TestSimulateKeyBackoff(t *testing.T) {
+	got, err := simulate(t, 0, func(ctx context.Context, call func(), clock wait.Clock) error {
+		k := wait.NewSharedBackoff(wait.ExponentialBackoff{Initial: time.Second, Multiplier: 2}, time.Minute).ForKey("k")
+		for i := 0; i < 3; i++ {
+			call()
+			if err := k.Wait(ctx, wait.WithClock(clock)); err != nil {
+				return err
+			}
+		}
+		call()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []time.Duration{0, time.Second, 3 * time.Second, 7 * time.Second}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls at %v, wanted %v", got, want)
+	}
+}
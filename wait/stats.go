@@ -0,0 +1,66 @@
+package wait
+
+import (
+	"sync"
+	"time"
+)
+
+// An Observer is notified of the time spent by a loop executing and waiting. See the Observe option.
+type Observer interface {
+	// ObserveExec is called with the time taken by each call of a loop's condition or function.
+	ObserveExec(d time.Duration)
+
+	// ObserveWait is called with the time spent in each wait between calls.
+	ObserveWait(d time.Duration)
+}
+
+// Stats is an Observer that accumulates the number of calls and waits made by one or more loops
+// and the time spent in each. The zero value is ready for use. A Stats is safe for concurrent use.
+type Stats struct {
+	mu sync.Mutex
+	s  StatsSnapshot
+}
+
+var _ Observer = (*Stats)(nil)
+
+// StatsSnapshot holds the values accumulated by a Stats at a point in time.
+type StatsSnapshot struct {
+	Execs       int           // number of calls made to conditions or functions
+	ExecTime    time.Duration // total time spent in calls
+	MaxExecTime time.Duration // longest time spent in a single call
+	Waits       int           // number of waits between calls
+	WaitTime    time.Duration // total time spent waiting
+}
+
+// ObserveExec implements Observer.
+func (s *Stats) ObserveExec(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.s.Execs++
+	s.s.ExecTime += d
+	if d > s.s.MaxExecTime {
+		s.s.MaxExecTime = d
+	}
+}
+
+// ObserveWait implements Observer.
+func (s *Stats) ObserveWait(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.s.Waits++
+	s.s.WaitTime += d
+}
+
+// Snapshot returns the values accumulated so far.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.s
+}
+
+// Reset clears the values accumulated so far.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.s = StatsSnapshot{}
+}
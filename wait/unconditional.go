@@ -14,15 +14,15 @@ func WithJitter(ctx context.Context, interval time.Duration, jitter float64) err
 		return nil
 	}
 
-	interval = JitterDuration(interval, jitter)
-
-	t := time.NewTimer(interval)
-	defer t.Stop()
+	return sleep(ctx, realClock{}, JitterDuration(interval, jitter))
+}
 
-	select {
-	case <-t.C:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+// SleepJitter pauses the current goroutine for d plus a random fraction of d up to j, as computed by
+// JitterDuration. It is intended for code that cannot be cancelled, such as initialization and
+// tests, and should not be used where a context is available; use WithJitter instead.
+func SleepJitter(d time.Duration, j float64) {
+	if d <= 0 {
+		return
 	}
+	time.Sleep(JitterDuration(d, j))
 }
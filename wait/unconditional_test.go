@@ -0,0 +1,16 @@
+package wait
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSleepJitter(t *testing.T) {
+	defer SetJitterSource(func() float64 { return 0.5 })()
+
+	start := time.Now()
+	SleepJitter(20*time.Millisecond, 0.5)
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("slept for %v, wanted at least 25ms", elapsed)
+	}
+}
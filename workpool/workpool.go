@@ -0,0 +1,278 @@
+// Package workpool runs jobs on a fixed number of workers, dividing the workers between classes of
+// job according to their priority.
+package workpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// ErrQueueFull is returned by Submit when the queue of the job's class is full.
+	ErrQueueFull = errors.New("queue full")
+
+	// ErrStopped is returned by Submit once the pool has stopped running.
+	ErrStopped = errors.New("pool stopped")
+)
+
+// A Job is a unit of work run by a pool. The context is cancelled when the pool is stopped.
+type Job func(context.Context) error
+
+// Class describes a priority class of jobs.
+type Class struct {
+	// Name identifies the class when submitting jobs and in metrics.
+	Name string
+
+	// Share is the relative share of the pool's workers reserved for jobs in the class. A class
+	// with twice the share of another is reserved twice as many workers, rounding down. Workers
+	// that are not reserved, and those reserved for a class that is using fewer than its
+	// reservation, are not given to other classes; they are kept free so that a job of the class
+	// can start as soon as it is submitted. Share must be positive.
+	Share int
+
+	// MaxQueue limits the number of jobs of the class waiting for a worker. Zero means no limit.
+	MaxQueue int
+}
+
+// Pool runs jobs on a fixed number of workers. Each job belongs to a class, and each class is
+// reserved a number of workers according to its share, so that latency sensitive jobs are not
+// starved by batch work even when batch jobs are submitted first. Workers that are not reserved
+// are given to any class, and when jobs of several classes are waiting, free workers are given to
+// the class that is furthest below its share of the workers. Classes listed earlier are preferred
+// when classes are equally far below their shares.
+//
+// A Pool is a prometheus.Collector exporting the number of waiting and running jobs, the number
+// of completed jobs and the time jobs waited for a worker, each by class.
+//
+// A Pool must be created with New. Jobs may be submitted before the pool is run.
+type Pool struct {
+	workers int
+	wake    chan struct{}
+
+	mu      sync.Mutex
+	classes []*class
+	byName  map[string]*class
+	busy    int // number of workers running jobs
+	stopped bool
+
+	queued    *prometheus.GaugeVec
+	running   *prometheus.GaugeVec
+	completed *prometheus.CounterVec
+	wait      *prometheus.HistogramVec
+}
+
+type class struct {
+	Class
+	reserved int // number of workers kept for jobs of the class
+	queue    []queuedJob
+	running  int
+}
+
+type queuedJob struct {
+	job      Job
+	enqueued time.Time
+}
+
+var _ prometheus.Collector = (*Pool)(nil)
+
+// New returns a Pool with the given number of workers, running jobs of the given classes.
+func New(workers int, classes ...Class) (*Pool, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("number of workers must be positive")
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("at least one class is required")
+	}
+	p := &Pool{
+		workers: workers,
+		wake:    make(chan struct{}, workers),
+		byName:  make(map[string]*class, len(classes)),
+		queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "workpool_queued_jobs",
+			Help: "Number of jobs waiting for a worker.",
+		}, []string{"class"}),
+		running: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "workpool_running_jobs",
+			Help: "Number of jobs being run.",
+		}, []string{"class"}),
+		completed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "workpool_completed_jobs_total",
+			Help: "Number of jobs that have been run, by whether they returned an error.",
+		}, []string{"class", "result"}),
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "workpool_queue_wait_seconds",
+			Help:    "Time jobs waited for a worker.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"class"}),
+	}
+	for _, c := range classes {
+		if c.Share <= 0 {
+			return nil, fmt.Errorf("class %q: share must be positive", c.Name)
+		}
+		if _, exists := p.byName[c.Name]; exists {
+			return nil, fmt.Errorf("class %q: duplicate name", c.Name)
+		}
+		cl := &class{Class: c}
+		p.classes = append(p.classes, cl)
+		p.byName[c.Name] = cl
+	}
+	total := 0
+	for _, c := range p.classes {
+		total += c.Share
+	}
+	for _, c := range p.classes {
+		c.reserved = workers * c.Share / total
+	}
+	return p, nil
+}
+
+// Submit queues job to be run by the pool as a member of the named class. It returns
+// ErrQueueFull if the class's queue is full and ErrStopped if the pool has stopped.
+func (p *Pool) Submit(className string, job Job) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return ErrStopped
+	}
+	c, ok := p.byName[className]
+	if !ok {
+		return fmt.Errorf("unknown class %q", className)
+	}
+	if c.MaxQueue > 0 && len(c.queue) >= c.MaxQueue {
+		return ErrQueueFull
+	}
+	c.queue = append(c.queue, queuedJob{job: job, enqueued: time.Now()})
+	p.queued.WithLabelValues(c.Name).Inc()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+		// Enough workers have already been woken
+	}
+	return nil
+}
+
+// Run runs the pool's workers until the context is cancelled. It then waits for running jobs to
+// return, discards any jobs still waiting and returns the context's error. Jobs that return an
+// error are logged using slog.
+func (p *Pool) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+
+	p.mu.Lock()
+	p.stopped = true
+	for _, c := range p.classes {
+		c.queue = nil
+		p.queued.WithLabelValues(c.Name).Set(0)
+	}
+	p.mu.Unlock()
+	return ctx.Err()
+}
+
+// work runs jobs until the context is cancelled.
+func (p *Pool) work(ctx context.Context) {
+	for {
+		c, qj, ok := p.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.wake:
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			p.done(c)
+			return
+		}
+
+		p.wait.WithLabelValues(c.Name).Observe(time.Since(qj.enqueued).Seconds())
+		result := "ok"
+		if err := qj.job(ctx); err != nil {
+			result = "error"
+			slog.ErrorContext(ctx, "job failed", "class", c.Name, "error", err)
+		}
+		p.completed.WithLabelValues(c.Name, result).Inc()
+		p.done(c)
+	}
+}
+
+// next removes the next job to run from the queues, choosing the class that is furthest below its
+// share of the workers among those that may be given a worker, and counts it as running.
+func (p *Pool) next() (*class, queuedJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// unused is the number of free workers reserved for classes using fewer than their reservation
+	unused := 0
+	for _, c := range p.classes {
+		unused += max(c.reserved-c.running, 0)
+	}
+	free := p.workers - p.busy
+
+	var best *class
+	for _, c := range p.classes {
+		if len(c.queue) == 0 {
+			continue
+		}
+		// A class may take a worker reserved for itself or one that is not reserved
+		if c.running >= c.reserved && free-1 < unused {
+			continue
+		}
+		// Compare running/share without division
+		if best == nil || c.running*best.Share < best.running*c.Share {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil, queuedJob{}, false
+	}
+
+	qj := best.queue[0]
+	best.queue[0] = queuedJob{}
+	best.queue = best.queue[1:]
+	best.running++
+	p.busy++
+	p.queued.WithLabelValues(best.Name).Dec()
+	p.running.WithLabelValues(best.Name).Inc()
+	return best, qj, true
+}
+
+// done records that a job of class c has finished running.
+func (p *Pool) done(c *class) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c.running--
+	p.busy--
+	p.running.WithLabelValues(c.Name).Dec()
+}
+
+// Describe implements prometheus.Collector.
+func (p *Pool) Describe(ch chan<- *prometheus.Desc) {
+	p.queued.Describe(ch)
+	p.running.Describe(ch)
+	p.completed.Describe(ch)
+	p.wait.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (p *Pool) Collect(ch chan<- prometheus.Metric) {
+	p.queued.Collect(ch)
+	p.running.Collect(ch)
+	p.completed.Collect(ch)
+	p.wait.Collect(ch)
+}
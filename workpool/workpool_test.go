@@ -0,0 +1,107 @@
+package workpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/iand/pontium/test"
+)
+
+func TestPoolShares(t *testing.T) {
+	p, err := New(4,
+		Class{Name: "interactive", Share: 3},
+		Class{Name: "batch", Share: 1},
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// Queue more jobs of both classes than there are workers, then record which classes the
+	// workers are given while all jobs are blocked.
+	release := make(chan struct{})
+	var mu sync.Mutex
+	started := map[string]int{}
+	for i := 0; i < 8; i++ {
+		for _, name := range []string{"batch", "interactive"} {
+			name := name
+			if err := p.Submit(name, func(context.Context) error {
+				mu.Lock()
+				started[name]++
+				mu.Unlock()
+				<-release
+				return nil
+			}); err != nil {
+				t.Fatalf("Submit: %v", err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	test.EventuallyEqual(t, 4.0, func() any {
+		return testutil.ToFloat64(p.running.WithLabelValues("interactive")) + testutil.ToFloat64(p.running.WithLabelValues("batch"))
+	}, time.Second)
+	mu.Lock()
+	if started["interactive"] != 3 || started["batch"] != 1 {
+		t.Errorf("got %v jobs started, wanted 3 interactive and 1 batch", started)
+	}
+	mu.Unlock()
+
+	close(release)
+	test.EventuallyEqual(t, 8.0, func() any {
+		return testutil.ToFloat64(p.completed.WithLabelValues("batch", "ok"))
+	}, time.Second)
+}
+
+func TestPoolReserve(t *testing.T) {
+	p, err := New(2,
+		Class{Name: "interactive", Share: 1},
+		Class{Name: "batch", Share: 1},
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	blocked := func(context.Context) error { <-release; return nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	// Batch jobs submitted first may only use the worker reserved for their class
+	for i := 0; i < 3; i++ {
+		if err := p.Submit("batch", blocked); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	test.EventuallyEqual(t, 1.0, func() any { return testutil.ToFloat64(p.running.WithLabelValues("batch")) }, time.Second)
+	time.Sleep(20 * time.Millisecond)
+	if got := testutil.ToFloat64(p.running.WithLabelValues("batch")); got != 1 {
+		t.Fatalf("got %v batch jobs running, wanted 1", got)
+	}
+
+	// An interactive job starts straight away on the worker kept for it
+	if err := p.Submit("interactive", blocked); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	test.EventuallyEqual(t, 1.0, func() any { return testutil.ToFloat64(p.running.WithLabelValues("interactive")) }, time.Second)
+}
+
+func TestPoolQueueFull(t *testing.T) {
+	p, _ := New(1, Class{Name: "batch", Share: 1, MaxQueue: 1})
+	noop := func(context.Context) error { return nil }
+	if err := p.Submit("batch", noop); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := p.Submit("batch", noop); err != ErrQueueFull {
+		t.Errorf("got error %v, wanted ErrQueueFull", err)
+	}
+}